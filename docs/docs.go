@@ -15,42 +15,40 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/messages": {
-            "get": {
-                "description": "Get messages with cursor-based pagination",
+        "/admin/benchmark/ingest": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Create N synthetic messages for a tenant as fast as possible and report throughput and latency percentiles (admin only). The messages are real and tagged with metadata source \"benchmark\".",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Get messages with pagination",
+                "summary": "Benchmark message ingestion",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Tenant ID",
-                        "name": "tenant_id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Cursor for pagination",
-                        "name": "cursor",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Limit (default 20, max 100)",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Benchmark parameters",
+                        "name": "benchmark",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BenchmarkIngestRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/services.PaginatedMessages"
+                            "$ref": "#/definitions/models.BenchmarkResult"
                         }
                     },
                     "400": {
@@ -59,6 +57,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -68,34 +78,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/{id}": {
-            "get": {
-                "description": "Get a specific message by its ID",
+        "/admin/maintenance": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "ANALYZE (or VACUUM ANALYZE when maintenance.vacuum is set) the message partitions with enough churn (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
-                ],
-                "summary": "Get a message by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Message ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Run partition maintenance",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Message"
+                            "$ref": "#/definitions/models.MaintenanceResult"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -107,34 +113,82 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a message by its ID",
+            }
+        },
+        "/admin/messages": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Query messages from every tenant partition with keyset pagination (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Delete a message",
+                "summary": "List messages across all tenants",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Message ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only messages created at or after this time (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages created before this time (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message status (pending, processed, failed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages whose metadata source matches",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor for pagination",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)",
+                        "name": "X-Response-Envelope",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
+                            "$ref": "#/definitions/services.PaginatedMessages"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -144,50 +198,55 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/messages/{tenant_id}": {
-            "post": {
-                "description": "Create a new message for a tenant",
-                "consumes": [
-                    "application/json"
+        "/admin/messages/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Look a message up by ID across every tenant partition, without knowing its tenant (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Create a message",
+                "summary": "Find a message in any tenant",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Tenant ID",
-                        "name": "tenant_id",
+                        "description": "Message ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Message data",
-                        "name": "message",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreateMessageRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.Message"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -201,30 +260,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/stats/tenants/{id}/messages": {
+        "/admin/orphaned-queues": {
             "get": {
-                "description": "Get message statistics for a tenant",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List the tenant queues, lane queues and dead letter queues on the broker whose tenant no longer exists, e.g. after a failed delete. Needs rabbitmq.management_url (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "stats"
-                ],
-                "summary": "Get message statistics",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Tenant ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "List orphaned queues",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.MessageStats"
+                            "$ref": "#/definitions/models.OrphanedQueues"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
@@ -232,28 +293,42 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/tenants": {
-            "get": {
-                "description": "Get a list of all tenants",
+        "/admin/orphaned-queues/cleanup": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Delete the queues GET /admin/orphaned-queues lists, with any messages still in them, and list those deleted (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
+                    "admin"
                 ],
-                "summary": "List all tenants",
+                "summary": "Delete orphaned queues",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Tenant"
-                            }
+                            "$ref": "#/definitions/models.OrphanedQueues"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
@@ -261,47 +336,46 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/admin/rabbitmq/reconnect": {
             "post": {
-                "description": "Create a new tenant with automatic consumer setup",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Re-dial the broker and recreate every tenant consumer without restarting the process (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
-                ],
-                "summary": "Create a new tenant",
-                "parameters": [
-                    {
-                        "description": "Tenant data",
-                        "name": "tenant",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreateTenantRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Reconnect to RabbitMQ",
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Tenant"
+                            "$ref": "#/definitions/models.ReconnectResult"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -309,60 +383,135 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenants/{id}": {
+        "/admin/reconcile": {
             "get": {
-                "description": "Get a specific tenant by its ID",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Show the result of the latest reconciliation of live worker pools against the stored worker config, scheduled by reconcile.interval or run through POST /admin/reconcile (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
-                ],
-                "summary": "Get a tenant by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Tenant ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Get the last worker pool reconciliation",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Tenant"
+                            "$ref": "#/definitions/models.ReconcileResult"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Delete a tenant and stop its consumer",
-                "produces": [
-                    "application/json"
-                ],
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Resize every worker pool on this instance whose live worker count differs from the tenant's or lane's stored worker count, and list the corrections (admin only)",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "tenants"
+                    "admin"
                 ],
-                "summary": "Delete a tenant",
+                "summary": "Reconcile worker pools",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReconcileResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retired-partitions": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List the partitions of deleted tenants kept detached until deletion.partition_grace_period has passed, oldest first (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List retired partitions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RetiredPartitions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retired-partitions/{name}": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Drop the partition of a deleted tenant, with its messages, without waiting for deletion.partition_grace_period to pass (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Drop a retired partition",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Tenant ID",
-                        "name": "id",
+                        "description": "Retired partition name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -374,6 +523,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -389,9 +544,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenants/{id}/config/concurrency": {
-            "put": {
-                "description": "Update the number of workers for a tenant",
+        "/admin/retired-partitions/{name}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Recreate a deleted tenant under its old ID, with the given name and the default config, and attach its retired partition again, messages and all (admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -399,24 +559,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
+                    "admin"
                 ],
-                "summary": "Update tenant concurrency",
+                "summary": "Restore a deleted tenant from its retired partition",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Tenant ID",
-                        "name": "id",
+                        "description": "Retired partition name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Concurrency config",
-                        "name": "config",
+                        "description": "Tenant name",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateConcurrencyRequest"
+                            "$ref": "#/definitions/models.RestoreRetiredPartitionRequest"
                         }
                     }
                 ],
@@ -424,7 +584,90 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
+                            "$ref": "#/definitions/models.Tenant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages": {
+            "get": {
+                "description": "Get messages with cursor-based pagination",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get messages with pagination",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor for pagination (an RFC3339 timestamp, normally the previous page's next_cursor)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages whose metadata source matches",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)",
+                        "name": "X-Response-Envelope",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.PaginatedMessages"
                         }
                     },
                     "400": {
@@ -433,6 +676,86 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{id}": {
+            "get": {
+                "description": "Get a specific message by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get a message by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a message by its ID. Passing the owning tenant saves looking it up from the message.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Delete a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID of the message",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -447,21 +770,2831 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CreateMessageRequest": {
+        },
+        "/messages/{id}/raw": {
+            "get": {
+                "description": "Get the payload of a message as it was received, before the tenant's transforms were applied. Only kept for tenants with keep_raw_payload set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get a message's raw payload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RawPayload"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{id}/reprocess": {
+            "post": {
+                "description": "Send one processed or failed message through processing again, for targeted debugging. The message keeps its ID; it is reset to pending and republished to its tenant's queue.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Reprocess a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{tenant_id}": {
+            "post": {
+                "description": "Create a new message for a tenant. The message ID is generated unless the request supplies one, which must be a UUID not yet used by the tenant.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Create a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "tenant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message data",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateMessageRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Correlation ID used to trace the message; generated when omitted",
+                        "name": "X-Correlation-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        },
+                        "headers": {
+                            "X-Correlation-ID": {
+                                "type": "string",
+                                "description": "Correlation ID of the created message"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/tenants/{id}/histogram": {
+            "get": {
+                "description": "Get a tenant's message counts per minute, hour or day between two times. Buckets are UTC-aligned and empty buckets are included with a zero count.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get message histogram",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket size: minute, hour or day (default hour)",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the range (RFC3339, default 24 hours before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the range, exclusive (RFC3339, default the end of the current bucket)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, to get a 304 if it is unchanged",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Histogram"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused (api.stats_max_age)"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Unchanged since the ETag in If-None-Match"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/tenants/{id}/messages": {
+            "get": {
+                "description": "Get message statistics for a tenant. The exact mode scans the tenant's messages; the approximate mode takes the 24h and 1h counts from per-minute counters and the total from the planner's estimate, for dashboards polling often, and leaves out failed_permanently and retrying. The mode used is returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get message statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "exact or approximate (default exact)",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, to get a 304 if it is unchanged",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MessageStats"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused (api.stats_max_age)"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Unchanged since the ETag in If-None-Match"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/tenants/{id}/throughput": {
+            "get": {
+                "description": "Get a tenant's message counts bucketed by interval over a trailing window. Buckets are UTC-aligned and empty buckets are included with a zero count.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get message throughput",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket size: minute, hour or day (default hour)",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Trailing window as a duration, e.g. 24h or 90m (default 24h)",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, to get a 304 if it is unchanged",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Throughput"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused (api.stats_max_age)"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Unchanged since the ETag in If-None-Match"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants": {
+            "get": {
+                "description": "Get a list of all tenants",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List all tenants",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)",
+                        "name": "X-Response-Envelope",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tenant"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new tenant with automatic consumer setup",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Create a new tenant",
+                "parameters": [
+                    {
+                        "description": "Tenant data",
+                        "name": "tenant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTenantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tenant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}": {
+            "get": {
+                "description": "Get a specific tenant by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tenant"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a tenant and stop its consumer. With drain=true the tenant first stops accepting messages and is deleted once its pending messages are processed (or the timeout passes); 202 is returned while that is still in progress. With soft=true the tenant's messages and queues are kept and it can be restored until deletion.grace_period has passed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Keep the tenant's data so it can be restored",
+                        "name": "soft",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Process pending messages before deleting",
+                        "name": "drain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Longest time to wait for the backlog when draining (Go duration, default 5m)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.DrainStatus"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the draining tenant"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/concurrency": {
+            "put": {
+                "description": "Update the number of workers for a tenant, up to the configured max_workers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant concurrency",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Concurrency config",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateConcurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/effective": {
+            "get": {
+                "description": "Return every setting that applies to the tenant, with its source: \"tenant\" for settings set for the tenant, \"default\" for tenant settings left at their default, \"instance\" for instance-wide configuration, and \"schedule\" for a worker count set by the worker schedule window in force.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Export effective tenant config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EffectiveConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/empty-payload": {
+            "put": {
+                "description": "Let the tenant send messages without a payload, e.g. heartbeats. A missing or null payload is then stored as {} instead of being rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update whether a tenant accepts empty payloads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Empty payload setting",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateEmptyPayloadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/failure-policy": {
+            "put": {
+                "description": "Choose what happens to messages whose processing fails: dlq, retry, drop or retry_then_dlq",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant failure policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Failure policy",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateFailurePolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/history": {
+            "get": {
+                "description": "List past configurations of a tenant, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get tenant config history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)",
+                        "name": "X-Response-Envelope",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TenantConfigHistory"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/lanes/{lane}": {
+            "put": {
+                "description": "Add a named lane (e.g. realtime, batch) with its own queue and worker pool, or change the worker count of an existing one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Create or resize a tenant lane",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lane name (lowercase letters, digits, _ and -)",
+                        "name": "lane",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lane config",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateLaneRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop a lane's consumer and worker pool. Its queue is deleted once empty.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Remove a tenant lane",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lane name",
+                        "name": "lane",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/max-concurrency": {
+            "put": {
+                "description": "Cap how many of the tenant's messages are processed at once, independently of its worker count. 0 removes the cap.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant processing limit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Processing limit",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateMaxConcurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/payload-schema": {
+            "put": {
+                "description": "Set the schema the tenant's payloads are checked against before they are processed, or remove it with a null schema. Payloads that do not match, including messages published straight to the queue, are dead-lettered unprocessed with the violation as the reason. The schema is a subset of JSON Schema: type, properties, required, items and enum.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant payload schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payload schema",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdatePayloadSchemaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/pool": {
+            "put": {
+                "description": "Move a tenant between the shared worker pool and a dedicated pool",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant pool mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Pool mode",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdatePoolModeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/sample-rate": {
+            "put": {
+                "description": "Process only a share of the tenant's messages, from 0 to 1. The rest are acknowledged without processing and marked sampled_out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant sample rate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sample rate",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSampleRateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/transforms": {
+            "put": {
+                "description": "Set the transforms applied, in order, to the tenant's payloads before they are stored, and whether the payload as received is kept as well",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant payload transforms",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transforms",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateTransformsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/validate": {
+            "post": {
+                "description": "Check a tenant config change without applying it. Each field takes the value of its PUT /tenants/{id}/config endpoint and is checked as that endpoint would, e.g. that a payload schema compiles, transforms are registered and worker counts are within max_workers. Fields left out are not checked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Validate tenant config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Config change",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidateConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConfigValidation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/weight": {
+            "put": {
+                "description": "Set the tenant's share of processing relative to other tenants when the fair scheduler is enabled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant scheduling weight",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scheduling weight",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateWeightRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/worker-schedule": {
+            "put": {
+                "description": "Replace the tenant's worker schedule: daily windows, in UTC, each setting the worker count of its dedicated pool, up to the configured max_workers. Outside the windows the tenant runs its configured worker count; an empty schedule removes it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update tenant worker schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Worker schedule",
+                        "name": "schedule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateWorkerScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumer/restart": {
+            "post": {
+                "description": "Stop and recreate the tenant's consumers and worker pools from its stored config, without affecting other tenants. Messages being handled finish first; queued ones are redelivered.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Restart tenant consumer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConsumerStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumers": {
+            "get": {
+                "description": "Show whether a tenant's queues are being consumed and the state of the worker pools behind them, lanes included",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get tenant consumer status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConsumerStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/diagnostics": {
+            "get": {
+                "description": "Check that the tenant's queue exists with a consumer attached, that its partition exists, and that a message can be written and read back (the write is rolled back). Failed checks come with a remediation hint.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Diagnose tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TenantDiagnostics"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/export/config": {
+            "get": {
+                "description": "Return every setting that applies to the tenant, with its source: \"tenant\" for settings set for the tenant, \"default\" for tenant settings left at their default, \"instance\" for instance-wide configuration, and \"schedule\" for a worker count set by the worker schedule window in force.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Export effective tenant config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EffectiveConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/lag": {
+            "get": {
+                "description": "Combine the tenant's queue depth, the age of its oldest pending message and its worker utilization into a status of \"healthy\", \"degraded\" or \"overloaded\", using the lag thresholds. Reasons lists each threshold reached.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get tenant lag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TenantLag"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/messages": {
+            "delete": {
+                "description": "Delete the tenant's messages whose payload matches every filter. A filter is path=value or path!=value, where path is a dot-separated list of object keys (letters, digits, _ and -) and the value is compared as text, e.g. env=test or meta.source!=import. Payloads without the path match neither operator.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a tenant's messages by payload filter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Payload filter, repeat for several",
+                        "name": "filter",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PurgeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/messages/move": {
+            "post": {
+                "description": "Move the tenant's messages whose payload matches every filter to the target tenant, e.g. when merging customers; no filters moves them all. Filters use the syntax of DELETE /tenants/{id}/messages. The move is a single transaction. Messages keep their IDs, status and attempts; pending messages are still queued for the source, so they are skipped and counted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Move a tenant's messages to another tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target tenant and filters",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MoveMessagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MoveResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/messages/search/text": {
+            "get": {
+                "description": "Find the tenant's messages whose payload mentions the words of q, most relevant first (ts_rank) and newest first among equally relevant ones. q uses web search syntax: quoted phrases, or, and -word to exclude. Payload keys are searched as well as values.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Search a tenant's messages by text",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search text",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Most results to return (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MessageSearchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/pool/stats": {
+            "get": {
+                "description": "Show the live state of the tenant's worker pools on this instance, lanes included: configured workers, worker goroutines alive, busy workers, job queue length and capacity, and jobs processed since start",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get worker pool stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TenantPoolStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/queue/info": {
+            "get": {
+                "description": "Show how the tenant's queue is actually declared on the broker: its arguments, durability, message and consumer counts, and bindings, to spot drift from what the code declares. Needs rabbitmq.management_url.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get queue info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "One of the tenant's lanes instead of its main queue",
+                        "name": "lane",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueueInfo"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/reprocess": {
+            "post": {
+                "description": "Send the tenant's processed messages created within [from, to) through processing again. Messages keep their IDs; they are reset to pending and republished, and the new result replaces the old one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Reprocess a tenant's messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the window, inclusive (RFC3339)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the window, exclusive (RFC3339)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReprocessResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/restore": {
+            "post": {
+                "description": "Undo DELETE /tenants/{id}?soft=true before the tenant is purged, and start consuming it again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Restore a soft-deleted tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tenant"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.BenchmarkIngestRequest": {
+            "type": "object",
+            "required": [
+                "count",
+                "tenant_id"
+            ],
+            "properties": {
+                "concurrency": {
+                    "type": "integer",
+                    "maximum": 50,
+                    "minimum": 1
+                },
+                "count": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BenchmarkResult": {
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "number"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "latency_ms": {
+                    "$ref": "#/definitions/models.LatencyPercentiles"
+                },
+                "messages": {
+                    "type": "integer"
+                },
+                "messages_per_second": {
+                    "type": "number"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConfigError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConfigValidation": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ConfigError"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ConsumerStatus": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "description": "Delivered and Redelivered count deliveries to the tenant's consumers,\nlanes included, since the service started. RedeliveryRate is the share\nof deliveries that were redeliveries; a high rate means messages keep\nfailing or missing their visibility deadline.",
+                    "type": "integer"
+                },
+                "idle": {
+                    "description": "Idle reports whether the tenant's consumers are stopped for having\nhad no messages for consumer.idle_timeout; they start again when one\nis published",
+                    "type": "boolean"
+                },
+                "lanes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LaneStatus"
+                    }
+                },
+                "last_restart": {
+                    "type": "string"
+                },
+                "max_concurrency": {
+                    "description": "MaxConcurrency caps the tenant's concurrent handler runs across all of\nits pools; 0 means it is bounded only by the worker count",
+                    "type": "integer"
+                },
+                "owner": {
+                    "description": "Owner is the instance consuming the tenant when cluster.enabled is\nset, empty while no instance owns it",
+                    "type": "string"
+                },
+                "pool": {
+                    "description": "Pool is \"dedicated\" or \"shared\". Workers, QueuedJobs and Processed\ndescribe that pool, so for the shared pool they cover every tenant on it.",
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "queued_jobs": {
+                    "type": "integer"
+                },
+                "redelivered": {
+                    "type": "integer"
+                },
+                "redelivery_rate": {
+                    "type": "number"
+                },
+                "restarts": {
+                    "description": "Restarts counts the times the tenant's consumer was recreated after\nthe broker connection was lost, and LastRestart is the latest; both\nare since the service started",
+                    "type": "integer"
+                },
+                "running": {
+                    "description": "Running reports whether the tenant's main queue is being consumed",
+                    "type": "boolean"
+                },
+                "sample_rate": {
+                    "description": "SampleRate is the share of the tenant's messages that are processed;\nthe rest are acked without processing",
+                    "type": "number"
+                },
+                "single_active_consumer": {
+                    "description": "SingleActiveConsumer reports whether the tenant's queues let only one\nconsumer across all instances receive at a time",
+                    "type": "boolean"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "transient": {
+                    "description": "Transient reports whether the tenant's queues are non-durable",
+                    "type": "boolean"
+                },
+                "weight": {
+                    "description": "Weight is the tenant's scheduler weight, omitted when the scheduler\nis disabled",
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CreateMessageRequest": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt backfills a message with its original time, e.g. when\nimporting historical data. The server time is used when it is\nomitted.",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the message expires: from then on it is left out of\nreads, and the retention job deletes it. It must be in the future;\nmessages without it are kept.",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID is an optional client-supplied message ID, which must be a UUID and\nunique within the tenant. The server generates one when it is empty.",
+                    "type": "string"
+                },
+                "lane": {
+                    "description": "Lane routes the message to one of the tenant's lanes. The main queue\nis used when it is empty.",
+                    "type": "string"
+                },
+                "metadata": {
+                    "description": "Metadata describes the message rather than its content, e.g. the\nproducing \"source\". It is stored alongside the payload.",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "payload": {
+                    "description": "Payload is required unless the tenant has allow_empty_payload set, in\nwhich case a missing or null payload is stored as {}",
+                    "type": "object"
+                }
+            }
+        },
+        "models.CreateTenantRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "single_active_consumer": {
+                    "description": "SingleActiveConsumer declares the tenant's queues so that only one\nconsumer across all instances receives messages at a time. It is fixed\nonce the tenant exists.",
+                    "type": "boolean"
+                },
+                "transient": {
+                    "description": "Transient declares the tenant's queues non-durable and publishes its\nmessages without persistence, for throwaway data such as telemetry:\nqueued messages are lost when the broker restarts. It is fixed once\nthe tenant exists.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.DiagnosticCheck": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "passed": {
+                    "type": "boolean"
+                },
+                "remediation": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DrainStatus": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "status_url": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EffectiveConfig": {
+            "type": "object",
+            "properties": {
+                "settings": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.EffectiveSetting"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EffectiveSetting": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "type": "string"
+                },
+                "value": {}
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "Fields lists, for a request body that failed validation, each field\nat fault",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Histogram": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TimeBucket"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LaneStatus": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "queued_jobs": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LatencyPercentiles": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "p50": {
+                    "type": "number"
+                },
+                "p90": {
+                    "type": "number"
+                },
+                "p99": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.MaintenanceResult": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "number"
+                },
+                "partitions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "vacuum": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.Message": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lane": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MessageSearchHit": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lane": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "rank": {
+                    "description": "Rank is the message's ts_rank for the query; higher is more relevant",
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MessageSearchResult": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MessageSearchHit"
+                    }
+                },
+                "query": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MessageStats": {
+            "type": "object",
+            "properties": {
+                "failed_permanently": {
+                    "description": "FailedPermanently counts messages that used up consumer.max_attempts.\nIt takes a scan, so approximate stats leave it out.",
+                    "type": "integer"
+                },
+                "messages_1h": {
+                    "type": "integer"
+                },
+                "messages_24h": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "Mode is how TotalMessages, Messages24h and Messages1h were counted",
+                    "type": "string"
+                },
+                "retrying": {
+                    "description": "Retrying counts messages that failed at least once and will be\nretried. Like FailedPermanently, only exact stats have it.",
+                    "type": "integer"
+                },
+                "total_messages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MoveMessagesRequest": {
+            "type": "object",
+            "required": [
+                "target_tenant_id"
+            ],
+            "properties": {
+                "filters": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "target_tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MoveResult": {
+            "type": "object",
+            "properties": {
+                "moved": {
+                    "type": "integer"
+                },
+                "skipped_pending": {
+                    "type": "integer"
+                },
+                "source_tenant_id": {
+                    "type": "string"
+                },
+                "target_tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrphanedQueue": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrphanedQueues": {
+            "type": "object",
+            "properties": {
+                "queues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrphanedQueue"
+                    }
+                }
+            }
+        },
+        "models.PayloadSchema": {
+            "type": "object",
+            "properties": {
+                "enum": {
+                    "type": "array",
+                    "items": {}
+                },
+                "items": {
+                    "$ref": "#/definitions/models.PayloadSchema"
+                },
+                "properties": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.PayloadSchema"
+                    }
+                },
+                "required": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PoolCorrection": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "integer"
+                },
+                "lane": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PoolStats": {
+            "type": "object",
+            "properties": {
+                "busy": {
+                    "type": "integer"
+                },
+                "lane": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "queue_capacity": {
+                    "type": "integer"
+                },
+                "queued_jobs": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PurgeResult": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QueueBinding": {
+            "type": "object",
+            "properties": {
+                "arguments": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "exchange": {
+                    "type": "string"
+                },
+                "routing_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QueueInfo": {
+            "type": "object",
+            "properties": {
+                "arguments": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "auto_delete": {
+                    "type": "boolean"
+                },
+                "bindings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QueueBinding"
+                    }
+                },
+                "consumers": {
+                    "type": "integer"
+                },
+                "durable": {
+                    "type": "boolean"
+                },
+                "exclusive": {
+                    "type": "boolean"
+                },
+                "lane": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "integer"
+                },
+                "messages_ready": {
+                    "type": "integer"
+                },
+                "messages_unacknowledged": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RawPayload": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReconcileResult": {
+            "type": "object",
+            "properties": {
+                "checked": {
+                    "type": "integer"
+                },
+                "corrections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PoolCorrection"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "ran_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReconnectResult": {
             "type": "object",
-            "required": [
-                "payload"
-            ],
             "properties": {
-                "payload": {
-                    "type": "object"
+                "consumers_restarted": {
+                    "type": "integer"
+                },
+                "discarded_jobs": {
+                    "description": "DiscardedJobs counts buffered jobs dropped because their deliveries\nbelonged to the old connection; the broker redelivers them",
+                    "type": "integer"
+                },
+                "failures": {
+                    "description": "Failures maps tenant IDs to the error that kept their consumer down",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "models.CreateTenantRequest": {
+        "models.ReprocessResult": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "reprocessed": {
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RestoreRetiredPartitionRequest": {
             "type": "object",
             "required": [
                 "name"
@@ -472,18 +3605,44 @@ const docTemplate = `{
                 }
             }
         },
-        "models.ErrorResponse": {
+        "models.RetiredPartition": {
             "type": "object",
             "properties": {
-                "error": {
+                "drops_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "retired_at": {
                     "type": "string"
                 },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RetiredPartitions": {
+            "type": "object",
+            "properties": {
+                "partitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RetiredPartition"
+                    }
+                }
+            }
+        },
+        "models.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
                 "message": {
                     "type": "string"
                 }
             }
         },
-        "models.Message": {
+        "models.Tenant": {
             "type": "object",
             "properties": {
                 "created_at": {
@@ -492,50 +3651,123 @@ const docTemplate = `{
                 "id": {
                     "type": "string"
                 },
-                "payload": {
-                    "type": "object"
+                "name": {
+                    "type": "string"
                 },
-                "tenant_id": {
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
                     "type": "string"
                 }
             }
         },
-        "models.MessageStats": {
+        "models.TenantConfigHistory": {
             "type": "object",
             "properties": {
-                "messages_1h": {
-                    "type": "integer"
+                "changed_at": {
+                    "type": "string"
                 },
-                "messages_24h": {
+                "config": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "id": {
                     "type": "integer"
                 },
-                "total_messages": {
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TenantDiagnostics": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DiagnosticCheck"
+                    }
+                },
+                "healthy": {
+                    "description": "Healthy is set when every check passed",
+                    "type": "boolean"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TenantLag": {
+            "type": "object",
+            "properties": {
+                "oldest_pending_age_seconds": {
+                    "description": "OldestPendingAgeSeconds is the age of the oldest message not yet\nprocessed, 0 when none is pending",
+                    "type": "number"
+                },
+                "queue_depth": {
+                    "description": "QueueDepth counts the messages waiting in the tenant's queues, lanes\nincluded, and in its dedicated worker pools",
                     "type": "integer"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "worker_utilization": {
+                    "description": "WorkerUtilization is the share of the workers serving the tenant that\nare busy with a message, from 0 to 1. For tenants on the shared pool it\ncovers every tenant on it.",
+                    "type": "number"
                 }
             }
         },
-        "models.SuccessResponse": {
+        "models.TenantPoolStats": {
             "type": "object",
             "properties": {
-                "data": {},
-                "message": {
+                "pools": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PoolStats"
+                    }
+                },
+                "tenant_id": {
                     "type": "string"
                 }
             }
         },
-        "models.Tenant": {
+        "models.Throughput": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TimeBucket"
+                    }
+                },
+                "interval": {
                     "type": "string"
                 },
-                "id": {
+                "tenant_id": {
                     "type": "string"
                 },
-                "name": {
+                "window": {
                     "type": "string"
+                }
+            }
+        },
+        "models.TimeBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
                 },
-                "updated_at": {
+                "start": {
                     "type": "string"
                 }
             }
@@ -547,12 +3779,202 @@ const docTemplate = `{
             ],
             "properties": {
                 "workers": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "models.UpdateEmptyPayloadRequest": {
+            "type": "object",
+            "required": [
+                "allow_empty_payload"
+            ],
+            "properties": {
+                "allow_empty_payload": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UpdateFailurePolicyRequest": {
+            "type": "object",
+            "required": [
+                "policy"
+            ],
+            "properties": {
+                "policy": {
+                    "type": "string",
+                    "enum": [
+                        "dlq",
+                        "retry",
+                        "drop",
+                        "retry_then_dlq"
+                    ]
+                }
+            }
+        },
+        "models.UpdateLaneRequest": {
+            "type": "object",
+            "required": [
+                "workers"
+            ],
+            "properties": {
+                "workers": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "models.UpdateMaxConcurrencyRequest": {
+            "type": "object",
+            "properties": {
+                "max_concurrency": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0
+                }
+            }
+        },
+        "models.UpdatePayloadSchemaRequest": {
+            "type": "object",
+            "properties": {
+                "schema": {
+                    "$ref": "#/definitions/models.PayloadSchema"
+                }
+            }
+        },
+        "models.UpdatePoolModeRequest": {
+            "type": "object",
+            "required": [
+                "dedicated"
+            ],
+            "properties": {
+                "dedicated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UpdateSampleRateRequest": {
+            "type": "object",
+            "required": [
+                "sample_rate"
+            ],
+            "properties": {
+                "sample_rate": {
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0
+                }
+            }
+        },
+        "models.UpdateTransformsRequest": {
+            "type": "object",
+            "properties": {
+                "keep_raw_payload": {
+                    "type": "boolean"
+                },
+                "transforms": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.UpdateWeightRequest": {
+            "type": "object",
+            "required": [
+                "weight"
+            ],
+            "properties": {
+                "weight": {
                     "type": "integer",
                     "maximum": 100,
                     "minimum": 1
                 }
             }
         },
+        "models.UpdateWorkerScheduleRequest": {
+            "type": "object",
+            "required": [
+                "schedule"
+            ],
+            "properties": {
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WorkerWindow"
+                    }
+                }
+            }
+        },
+        "models.ValidateConfigRequest": {
+            "type": "object",
+            "properties": {
+                "failure_policy": {
+                    "type": "string"
+                },
+                "lanes": {
+                    "description": "Lanes maps lane names to their worker counts",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "max_concurrency": {
+                    "type": "integer"
+                },
+                "payload_schema": {
+                    "$ref": "#/definitions/models.PayloadSchema"
+                },
+                "sample_rate": {
+                    "type": "number"
+                },
+                "transforms": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "weight": {
+                    "type": "integer"
+                },
+                "worker_schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WorkerWindow"
+                    }
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WorkerWindow": {
+            "type": "object",
+            "required": [
+                "end",
+                "start",
+                "workers"
+            ],
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
         "services.PaginatedMessages": {
             "type": "object",
             "properties": {
@@ -567,6 +3989,13 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -1,27 +1,65 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"jatis/internal/config"
+	"jatis/internal/messaging"
 	"jatis/internal/metrics"
 	"jatis/internal/models"
 	"jatis/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRoutes(router *gin.Engine, tenantManager *services.TenantManager, messageService *services.MessageService) {
+const (
+	// correlationIDHeader carries the ID used to trace a message from the
+	// API through to the worker that processes it.
+	correlationIDHeader    = "X-Correlation-ID"
+	maxCorrelationIDLength = 255
+
+	// envelopeHeader asks for list responses with ("true") or without
+	// ("false") the {data, meta} envelope, overriding api.envelope_lists.
+	envelopeHeader = "X-Response-Envelope"
+	envelopeKey    = "envelope"
+)
+
+func SetupRoutes(router *gin.Engine, cfg *config.Config, tenantManager *services.TenantManager, messageService *services.MessageService) {
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
 	router.Use(metrics.PrometheusMiddleware())
+	router.Use(envelopeMiddleware(cfg.API.EnvelopeLists))
+	router.Use(decompressMiddleware(cfg.API.MaxDecompressedBytes))
+	router.Use(timeoutMiddleware(cfg.API.RequestTimeout, cfg.API.RouteTimeouts))
 
-	// Swagger documentation
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Swagger documentation, unless disabled for production
+	if cfg.API.Swagger {
+		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
 
 	// Metrics endpoint
 	router.GET("/metrics", metrics.MetricsHandler())
@@ -36,7 +74,33 @@ func SetupRoutes(router *gin.Engine, tenantManager *services.TenantManager, mess
 			tenants.GET("", listTenants(tenantManager))
 			tenants.GET("/:id", getTenant(tenantManager))
 			tenants.DELETE("/:id", deleteTenant(tenantManager))
+			tenants.POST("/:id/restore", restoreTenant(tenantManager))
 			tenants.PUT("/:id/config/concurrency", updateConcurrency(tenantManager))
+			tenants.PUT("/:id/config/worker-schedule", updateWorkerSchedule(tenantManager))
+			tenants.PUT("/:id/config/pool", updatePoolMode(tenantManager))
+			tenants.PUT("/:id/config/failure-policy", updateFailurePolicy(tenantManager))
+			tenants.PUT("/:id/config/weight", updateWeight(tenantManager))
+			tenants.PUT("/:id/config/max-concurrency", updateMaxConcurrency(tenantManager))
+			tenants.PUT("/:id/config/sample-rate", updateSampleRate(tenantManager))
+			tenants.PUT("/:id/config/transforms", updateTransforms(tenantManager))
+			tenants.PUT("/:id/config/empty-payload", updateEmptyPayload(tenantManager))
+			tenants.PUT("/:id/config/payload-schema", updatePayloadSchema(tenantManager))
+			tenants.PUT("/:id/config/lanes/:lane", updateLane(tenantManager))
+			tenants.DELETE("/:id/config/lanes/:lane", removeLane(tenantManager))
+			tenants.GET("/:id/config/history", getConfigHistory(tenantManager))
+			tenants.GET("/:id/consumers", getConsumerStatus(tenantManager))
+			tenants.POST("/:id/consumer/restart", restartConsumer(tenantManager))
+			tenants.GET("/:id/diagnostics", getDiagnostics(tenantManager))
+			tenants.GET("/:id/queue/info", getQueueInfo(tenantManager))
+			tenants.GET("/:id/pool/stats", getPoolStats(tenantManager))
+			tenants.GET("/:id/lag", getLag(tenantManager))
+			tenants.GET("/:id/export/config", getEffectiveConfig(tenantManager))
+			tenants.GET("/:id/config/effective", getEffectiveConfig(tenantManager))
+			tenants.POST("/:id/config/validate", validateConfig(tenantManager))
+			tenants.POST("/:id/reprocess", reprocessMessages(messageService))
+			tenants.DELETE("/:id/messages", purgeMessages(messageService))
+			tenants.POST("/:id/messages/move", moveMessages(messageService))
+			tenants.GET("/:id/messages/search/text", searchMessagesText(messageService))
 		}
 
 		// Message routes
@@ -44,14 +108,41 @@ func SetupRoutes(router *gin.Engine, tenantManager *services.TenantManager, mess
 		{
 			messages.GET("", getMessages(messageService))
 			messages.POST("/:tenant_id", createMessage(messageService))
+			// gin (1.9) panics if two routes of a method put differently
+			// named wildcards in the same segment, so this route has to
+			// reuse POST /:tenant_id's name even though the segment holds
+			// a message ID; reprocessMessage reads it as such
+			messages.POST("/:tenant_id/reprocess", reprocessMessage(messageService))
 			messages.GET("/:id", getMessage(messageService))
+			messages.GET("/:id/raw", getRawPayload(messageService))
 			messages.DELETE("/:id", deleteMessage(messageService))
 		}
 
 		// Stats routes
 		stats := api.Group("/stats")
+		stats.Use(cacheMiddleware(cfg.API.StatsMaxAge))
 		{
 			stats.GET("/tenants/:id/messages", getMessageStats(messageService))
+			stats.GET("/tenants/:id/throughput", getThroughput(messageService))
+			stats.GET("/tenants/:id/histogram", getHistogram(messageService))
+		}
+
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(adminAuthMiddleware(cfg.Admin.Token))
+		{
+			admin.GET("/messages", listAllMessages(messageService))
+			admin.GET("/messages/:id", findMessage(messageService))
+			admin.POST("/rabbitmq/reconnect", reconnectRabbitMQ(tenantManager))
+			admin.POST("/benchmark/ingest", benchmarkIngest(messageService))
+			admin.POST("/maintenance", runMaintenance(tenantManager))
+			admin.GET("/reconcile", getLastReconcile(tenantManager))
+			admin.POST("/reconcile", runReconcile(tenantManager))
+			admin.GET("/orphaned-queues", listOrphanedQueues(tenantManager))
+			admin.POST("/orphaned-queues/cleanup", cleanupOrphanedQueues(tenantManager))
+			admin.GET("/retired-partitions", listRetiredPartitions(tenantManager))
+			admin.POST("/retired-partitions/:name/restore", restoreRetiredPartition(tenantManager))
+			admin.DELETE("/retired-partitions/:name", dropRetiredPartition(tenantManager))
 		}
 	}
 
@@ -59,6 +150,9 @@ func SetupRoutes(router *gin.Engine, tenantManager *services.TenantManager, mess
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
+
+	// Readiness check, served from the cached dependency checks
+	router.GET("/readyz", readiness(tenantManager))
 }
 
 // @Summary Create a new tenant
@@ -69,21 +163,27 @@ func SetupRoutes(router *gin.Engine, tenantManager *services.TenantManager, mess
 // @Param tenant body models.CreateTenantRequest true "Tenant data"
 // @Success 201 {object} models.Tenant
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /tenants [post]
 func createTenant(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req models.CreateTenantRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error:   "Invalid request",
-				Message: err.Error(),
-			})
+			c.JSON(http.StatusBadRequest, invalidRequest(req, err))
 			return
 		}
 
-		tenant, err := tm.CreateTenant(req.Name)
+		tenant, err := tm.CreateTenantFromRequest(&req)
 		if err != nil {
+			if errors.Is(err, services.ErrTooManyCreates) {
+				c.Header("Retry-After", "1")
+				c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+					Error:   "Too many tenant creations in progress",
+					Message: err.Error(),
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 				Error:   "Failed to create tenant",
 				Message: err.Error(),
@@ -99,6 +199,7 @@ func createTenant(tm *services.TenantManager) gin.HandlerFunc {
 // @Description Get a list of all tenants
 // @Tags tenants
 // @Produce json
+// @Param X-Response-Envelope header bool false "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)"
 // @Success 200 {array} models.Tenant
 // @Failure 500 {object} models.ErrorResponse
 // @Router /tenants [get]
@@ -113,7 +214,11 @@ func listTenants(tm *services.TenantManager) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, tenants)
+		data := tenants
+		if data == nil {
+			data = []*models.Tenant{}
+		}
+		writeList(c, tenants, data, len(tenants), nil)
 	}
 }
 
@@ -150,11 +255,17 @@ func getTenant(tm *services.TenantManager) gin.HandlerFunc {
 }
 
 // @Summary Delete a tenant
-// @Description Delete a tenant and stop its consumer
+// @Description Delete a tenant and stop its consumer. With drain=true the tenant first stops accepting messages and is deleted once its pending messages are processed (or the timeout passes); 202 is returned while that is still in progress. With soft=true the tenant's messages and queues are kept and it can be restored until deletion.grace_period has passed.
 // @Tags tenants
 // @Produce json
 // @Param id path string true "Tenant ID"
+// @Param soft query bool false "Keep the tenant's data so it can be restored"
+// @Param drain query bool false "Process pending messages before deleting"
+// @Param timeout query string false "Longest time to wait for the backlog when draining (Go duration, default 5m)"
 // @Success 200 {object} models.SuccessResponse
+// @Success 202 {object} models.DrainStatus
+// @Header 202 {string} Location "URL of the draining tenant"
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /tenants/{id} [delete]
@@ -162,6 +273,15 @@ func deleteTenant(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tenantID := c.Param("id")
 
+		if soft, _ := strconv.ParseBool(c.Query("soft")); soft {
+			softDeleteTenant(c, tm, tenantID)
+			return
+		}
+		if drain, _ := strconv.ParseBool(c.Query("drain")); drain {
+			drainTenant(c, tm, tenantID)
+			return
+		}
+
 		err := tm.DeleteTenant(tenantID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -177,8 +297,120 @@ func deleteTenant(tm *services.TenantManager) gin.HandlerFunc {
 	}
 }
 
+func softDeleteTenant(c *gin.Context, tm *services.TenantManager, tenantID string) {
+	if err := tm.SoftDeleteTenant(tenantID); err != nil {
+		if err.Error() == "tenant not found" {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Tenant not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to delete tenant",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Tenant deleted; it can be restored with POST /api/v1/tenants/" + tenantID + "/restore until it is purged",
+	})
+}
+
+// @Summary Restore a soft-deleted tenant
+// @Description Undo DELETE /tenants/{id}?soft=true before the tenant is purged, and start consuming it again
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.Tenant
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/restore [post]
+func restoreTenant(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := tm.RestoreTenant(c.Param("id"))
+		if err != nil {
+			if errors.Is(err, services.ErrTenantNotDeleted) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Tenant is not deleted",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to restore tenant",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, tenant)
+	}
+}
+
+// drainSyncWait is how long a drained delete may take before the request
+// returns 202 and the drain carries on in the background.
+const drainSyncWait = 2 * time.Second
+
+func drainTenant(c *gin.Context, tm *services.TenantManager, tenantID string) {
+	timeout := services.DefaultDrainTimeout
+	if raw := c.Query("timeout"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "timeout must be a positive duration such as 30s or 5m",
+			})
+			return
+		}
+		timeout = d
+	}
+
+	done, err := tm.DrainTenant(tenantID, timeout)
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Tenant not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to drain tenant",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	select {
+	case <-done:
+		// A drain cut short by shutdown leaves the tenant in place
+		if _, err := tm.GetTenant(tenantID); err != nil {
+			c.JSON(http.StatusOK, models.SuccessResponse{
+				Message: "Tenant drained and deleted successfully",
+			})
+			return
+		}
+	case <-time.After(drainSyncWait):
+	}
+
+	statusURL := "/api/v1/tenants/" + tenantID
+	c.Header("Location", statusURL)
+	c.JSON(http.StatusAccepted, models.DrainStatus{
+		TenantID:  tenantID,
+		Status:    models.TenantStatusDraining,
+		StatusURL: statusURL,
+	})
+}
+
 // @Summary Update tenant concurrency
-// @Description Update the number of workers for a tenant
+// @Description Update the number of workers for a tenant, up to the configured max_workers
 // @Tags tenants
 // @Accept json
 // @Produce json
@@ -195,15 +427,19 @@ func updateConcurrency(tm *services.TenantManager) gin.HandlerFunc {
 
 		var req models.UpdateConcurrencyRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error:   "Invalid request",
-				Message: err.Error(),
-			})
+			c.JSON(http.StatusBadRequest, invalidRequest(req, err))
 			return
 		}
 
 		err := tm.UpdateConcurrency(tenantID, req.Workers)
 		if err != nil {
+			if errors.Is(err, services.ErrTooManyWorkers) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
 			if err.Error() == "tenant not found" {
 				c.JSON(http.StatusNotFound, models.ErrorResponse{
 					Error: "Tenant not found",
@@ -223,69 +459,76 @@ func updateConcurrency(tm *services.TenantManager) gin.HandlerFunc {
 	}
 }
 
-// @Summary Get messages with pagination
-// @Description Get messages with cursor-based pagination
-// @Tags messages
+// @Summary Update tenant worker schedule
+// @Description Replace the tenant's worker schedule: daily windows, in UTC, each setting the worker count of its dedicated pool, up to the configured max_workers. Outside the windows the tenant runs its configured worker count; an empty schedule removes it.
+// @Tags tenants
+// @Accept json
 // @Produce json
-// @Param tenant_id query string true "Tenant ID"
-// @Param cursor query string false "Cursor for pagination"
-// @Param limit query int false "Limit (default 20, max 100)"
-// @Success 200 {object} services.PaginatedMessages
+// @Param id path string true "Tenant ID"
+// @Param schedule body models.UpdateWorkerScheduleRequest true "Worker schedule"
+// @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /messages [get]
-func getMessages(ms *services.MessageService) gin.HandlerFunc {
+// @Router /tenants/{id}/config/worker-schedule [put]
+func updateWorkerSchedule(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tenantID := c.Query("tenant_id")
-		if tenantID == "" {
+		tenantID := c.Param("id")
+
+		var req models.UpdateWorkerScheduleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "tenant_id query parameter is required",
+				Error:   "Invalid request",
+				Message: err.Error(),
 			})
 			return
 		}
 
-		cursor := c.Query("cursor")
-		var cursorPtr *string
-		if cursor != "" {
-			cursorPtr = &cursor
-		}
-
-		limit := 20 // default
-		if limitStr := c.Query("limit"); limitStr != "" {
-			if l, err := strconv.Atoi(limitStr); err == nil {
-				limit = l
-			}
-		}
-
-		messages, err := ms.GetMessages(tenantID, cursorPtr, limit)
+		err := tm.UpdateWorkerSchedule(tenantID, req.Schedule)
 		if err != nil {
+			if errors.Is(err, services.ErrInvalidWorkerSchedule) || errors.Is(err, services.ErrTooManyWorkers) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to get messages",
+				Error:   "Failed to update worker schedule",
 				Message: err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusOK, messages)
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Worker schedule updated successfully",
+		})
 	}
 }
 
-// @Summary Create a message
-// @Description Create a new message for a tenant
-// @Tags messages
+// @Summary Update tenant pool mode
+// @Description Move a tenant between the shared worker pool and a dedicated pool
+// @Tags tenants
 // @Accept json
 // @Produce json
-// @Param tenant_id path string true "Tenant ID"
-// @Param message body models.CreateMessageRequest true "Message data"
-// @Success 201 {object} models.Message
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdatePoolModeRequest true "Pool mode"
+// @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /messages/{tenant_id} [post]
-func createMessage(ms *services.MessageService) gin.HandlerFunc {
+// @Router /tenants/{id}/config/pool [put]
+func updatePoolMode(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tenantID := c.Param("tenant_id")
+		tenantID := c.Param("id")
 
-		var req models.CreateMessageRequest
+		var req models.UpdatePoolModeRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error:   "Invalid request",
@@ -294,115 +537,2428 @@ func createMessage(ms *services.MessageService) gin.HandlerFunc {
 			return
 		}
 
-		message, err := ms.CreateMessage(tenantID, req.Payload)
+		err := tm.UpdatePoolMode(tenantID, *req.Dedicated)
 		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to create message",
+				Error:   "Failed to update pool mode",
 				Message: err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusCreated, message)
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Pool mode updated successfully",
+		})
 	}
 }
 
-// @Summary Get a message by ID
-// @Description Get a specific message by its ID
-// @Tags messages
+// @Summary Update tenant failure policy
+// @Description Choose what happens to messages whose processing fails: dlq, retry, drop or retry_then_dlq
+// @Tags tenants
+// @Accept json
 // @Produce json
-// @Param id path string true "Message ID"
-// @Success 200 {object} models.Message
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdateFailurePolicyRequest true "Failure policy"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /messages/{id} [get]
-func getMessage(ms *services.MessageService) gin.HandlerFunc {
+// @Router /tenants/{id}/config/failure-policy [put]
+func updateFailurePolicy(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		messageID := c.Param("id")
+		tenantID := c.Param("id")
 
-		message, err := ms.GetMessage(messageID)
+		var req models.UpdateFailurePolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdateFailurePolicy(tenantID, req.Policy)
 		if err != nil {
-			if err.Error() == "message not found" {
+			if err.Error() == "tenant not found" {
 				c.JSON(http.StatusNotFound, models.ErrorResponse{
-					Error: "Message not found",
+					Error: "Tenant not found",
 				})
 				return
 			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to get message",
+				Error:   "Failed to update failure policy",
 				Message: err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusOK, message)
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Failure policy updated successfully",
+		})
 	}
 }
 
-// @Summary Delete a message
-// @Description Delete a message by its ID
-// @Tags messages
+// @Summary Update tenant scheduling weight
+// @Description Set the tenant's share of processing relative to other tenants when the fair scheduler is enabled
+// @Tags tenants
+// @Accept json
 // @Produce json
-// @Param id path string true "Message ID"
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdateWeightRequest true "Scheduling weight"
 // @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /messages/{id} [delete]
-func deleteMessage(ms *services.MessageService) gin.HandlerFunc {
+// @Router /tenants/{id}/config/weight [put]
+func updateWeight(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		messageID := c.Param("id")
+		tenantID := c.Param("id")
+
+		var req models.UpdateWeightRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
 
-		err := ms.DeleteMessage(messageID)
+		err := tm.UpdateWeight(tenantID, req.Weight)
 		if err != nil {
-			if err.Error() == "message not found" {
+			if err.Error() == "tenant not found" {
 				c.JSON(http.StatusNotFound, models.ErrorResponse{
-					Error: "Message not found",
+					Error: "Tenant not found",
 				})
 				return
 			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to delete message",
+				Error:   "Failed to update weight",
 				Message: err.Error(),
 			})
 			return
 		}
 
 		c.JSON(http.StatusOK, models.SuccessResponse{
-			Message: "Message deleted successfully",
+			Message: "Weight updated successfully",
 		})
 	}
 }
 
-// @Summary Get message statistics
-// @Description Get message statistics for a tenant
-// @Tags stats
+// @Summary Update tenant processing limit
+// @Description Cap how many of the tenant's messages are processed at once, independently of its worker count. 0 removes the cap.
+// @Tags tenants
+// @Accept json
 // @Produce json
 // @Param id path string true "Tenant ID"
-// @Success 200 {object} models.MessageStats
+// @Param config body models.UpdateMaxConcurrencyRequest true "Processing limit"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /stats/tenants/{id}/messages [get]
-func getMessageStats(ms *services.MessageService) gin.HandlerFunc {
+// @Router /tenants/{id}/config/max-concurrency [put]
+func updateMaxConcurrency(tm *services.TenantManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tenantID := c.Param("id")
 
-		stats, err := ms.GetMessageStats(tenantID)
+		var req models.UpdateMaxConcurrencyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdateMaxConcurrency(tenantID, req.MaxConcurrency)
 		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Error:   "Failed to get message stats",
+				Error:   "Failed to update max concurrency",
 				Message: err.Error(),
 			})
 			return
 		}
 
-		c.JSON(http.StatusOK, stats)
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Max concurrency updated successfully",
+		})
+	}
+}
+
+// @Summary Update tenant sample rate
+// @Description Process only a share of the tenant's messages, from 0 to 1. The rest are acknowledged without processing and marked sampled_out.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdateSampleRateRequest true "Sample rate"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/sample-rate [put]
+func updateSampleRate(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.UpdateSampleRateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdateSampleRate(tenantID, *req.SampleRate)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to update sample rate",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Sample rate updated successfully",
+		})
+	}
+}
+
+// @Summary Update whether a tenant accepts empty payloads
+// @Description Let the tenant send messages without a payload, e.g. heartbeats. A missing or null payload is then stored as {} instead of being rejected.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdateEmptyPayloadRequest true "Empty payload setting"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/empty-payload [put]
+func updateEmptyPayload(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.UpdateEmptyPayloadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdateEmptyPayload(tenantID, *req.AllowEmptyPayload)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to update empty payload setting",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Empty payload setting updated successfully",
+		})
+	}
+}
+
+// @Summary Update tenant payload schema
+// @Description Set the schema the tenant's payloads are checked against before they are processed, or remove it with a null schema. Payloads that do not match, including messages published straight to the queue, are dead-lettered unprocessed with the violation as the reason. The schema is a subset of JSON Schema: type, properties, required, items and enum.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdatePayloadSchemaRequest true "Payload schema"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/payload-schema [put]
+func updatePayloadSchema(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.UpdatePayloadSchemaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdatePayloadSchema(tenantID, req.Schema)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidSchema) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to update payload schema",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Payload schema updated successfully",
+		})
+	}
+}
+
+// @Summary Update tenant payload transforms
+// @Description Set the transforms applied, in order, to the tenant's payloads before they are stored, and whether the payload as received is kept as well
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param config body models.UpdateTransformsRequest true "Transforms"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/transforms [put]
+func updateTransforms(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.UpdateTransformsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdateTransforms(tenantID, req.Transforms, req.KeepRawPayload)
+		if err != nil {
+			if errors.Is(err, services.ErrUnknownTransform) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: fmt.Sprintf("%v (available: %s)", err, strings.Join(services.Transformers(), ", ")),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to update transforms",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Transforms updated successfully",
+		})
+	}
+}
+
+// @Summary Create or resize a tenant lane
+// @Description Add a named lane (e.g. realtime, batch) with its own queue and worker pool, or change the worker count of an existing one
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param lane path string true "Lane name (lowercase letters, digits, _ and -)"
+// @Param config body models.UpdateLaneRequest true "Lane config"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/lanes/{lane} [put]
+func updateLane(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.UpdateLaneRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		err := tm.UpdateLane(tenantID, c.Param("lane"), req.Workers)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidLane) || errors.Is(err, services.ErrTooManyWorkers) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to update lane",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Lane updated successfully",
+		})
+	}
+}
+
+// @Summary Remove a tenant lane
+// @Description Stop a lane's consumer and worker pool. Its queue is deleted once empty.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param lane path string true "Lane name"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/lanes/{lane} [delete]
+func removeLane(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		err := tm.RemoveLane(tenantID, c.Param("lane"))
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			if errors.Is(err, services.ErrUnknownLane) {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Lane not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to remove lane",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Lane removed successfully",
+		})
+	}
+}
+
+// @Summary Get tenant config history
+// @Description List past configurations of a tenant, newest first
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param X-Response-Envelope header bool false "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)"
+// @Success 200 {array} models.TenantConfigHistory
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/history [get]
+func getConfigHistory(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		history, err := tm.GetConfigHistory(tenantID)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get config history",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		writeList(c, history, history, len(history), nil)
+	}
+}
+
+// @Summary Get tenant consumer status
+// @Description Show whether a tenant's queues are being consumed and the state of the worker pools behind them, lanes included
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.ConsumerStatus
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/consumers [get]
+func getConsumerStatus(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		status, err := tm.GetConsumerStatus(tenantID)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get consumer status",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// @Summary Restart tenant consumer
+// @Description Stop and recreate the tenant's consumers and worker pools from its stored config, without affecting other tenants. Messages being handled finish first; queued ones are redelivered.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.ConsumerStatus
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/consumer/restart [post]
+func restartConsumer(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		status, err := tm.RestartConsumer(tenantID)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			if errors.Is(err, services.ErrNotOwner) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Tenant is consumed by another instance",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to restart consumer",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// @Summary Get tenant lag
+// @Description Combine the tenant's queue depth, the age of its oldest pending message and its worker utilization into a status of "healthy", "degraded" or "overloaded", using the lag thresholds. Reasons lists each threshold reached.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.TenantLag
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/lag [get]
+func getLag(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		lag, err := tm.Lag(tenantID)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get tenant lag",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, lag)
+	}
+}
+
+// @Summary Export effective tenant config
+// @Description Return every setting that applies to the tenant, with its source: "tenant" for settings set for the tenant, "default" for tenant settings left at their default, "instance" for instance-wide configuration, and "schedule" for a worker count set by the worker schedule window in force.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.EffectiveConfig
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/export/config [get]
+// @Router /tenants/{id}/config/effective [get]
+func getEffectiveConfig(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		config, err := tm.EffectiveConfig(tenantID)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get effective config",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, config)
+	}
+}
+
+// @Summary Validate tenant config
+// @Description Check a tenant config change without applying it. Each field takes the value of its PUT /tenants/{id}/config endpoint and is checked as that endpoint would, e.g. that a payload schema compiles, transforms are registered and worker counts are within max_workers. Fields left out are not checked.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param config body models.ValidateConfigRequest true "Config change"
+// @Success 200 {object} models.ConfigValidation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/config/validate [post]
+func validateConfig(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.ValidateConfigRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		errs, err := tm.ValidateConfig(tenantID, req)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to validate config",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		errs = append(bindingErrors(req), errs...)
+		c.JSON(http.StatusOK, models.ConfigValidation{
+			TenantID: tenantID,
+			Valid:    len(errs) == 0,
+			Errors:   errs,
+		})
+	}
+}
+
+// bindingErrors checks each field of a config change against the binding
+// rules of its update request.
+func bindingErrors(req models.ValidateConfigRequest) []models.ConfigError {
+	type update struct {
+		field   string
+		request interface{}
+	}
+	var updates []update
+	if req.Workers != nil {
+		updates = append(updates, update{"workers", models.UpdateConcurrencyRequest{Workers: *req.Workers}})
+	}
+	if req.WorkerSchedule != nil {
+		updates = append(updates, update{"worker_schedule", models.UpdateWorkerScheduleRequest{Schedule: req.WorkerSchedule}})
+	}
+	if req.Weight != nil {
+		updates = append(updates, update{"weight", models.UpdateWeightRequest{Weight: *req.Weight}})
+	}
+	if req.MaxConcurrency != nil {
+		updates = append(updates, update{"max_concurrency", models.UpdateMaxConcurrencyRequest{MaxConcurrency: *req.MaxConcurrency}})
+	}
+	if req.SampleRate != nil {
+		updates = append(updates, update{"sample_rate", models.UpdateSampleRateRequest{SampleRate: req.SampleRate}})
+	}
+	if req.FailurePolicy != nil {
+		updates = append(updates, update{"failure_policy", models.UpdateFailurePolicyRequest{Policy: *req.FailurePolicy}})
+	}
+	for name, workers := range req.Lanes {
+		updates = append(updates, update{"lanes." + name, models.UpdateLaneRequest{Workers: workers}})
+	}
+
+	errs := []models.ConfigError{}
+	for _, u := range updates {
+		if err := binding.Validator.ValidateStruct(u.request); err != nil {
+			errs = append(errs, models.ConfigError{Field: u.field, Error: err.Error()})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// @Summary Diagnose tenant
+// @Description Check that the tenant's queue exists with a consumer attached, that its partition exists, and that a message can be written and read back (the write is rolled back). Failed checks come with a remediation hint.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.TenantDiagnostics
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/diagnostics [get]
+func getDiagnostics(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		report, err := tm.Diagnose(tenantID)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to diagnose tenant",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// @Summary Get queue info
+// @Description Show how the tenant's queue is actually declared on the broker: its arguments, durability, message and consumer counts, and bindings, to spot drift from what the code declares. Needs rabbitmq.management_url.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param lane query string false "One of the tenant's lanes instead of its main queue"
+// @Success 200 {object} models.QueueInfo
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /tenants/{id}/queue/info [get]
+func getQueueInfo(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, err := tm.QueueInfo(c.Request.Context(), c.Param("id"), c.Query("lane"))
+		if err != nil {
+			switch {
+			case err.Error() == "tenant not found":
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+			case errors.Is(err, messaging.ErrQueueNotFound):
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error:   "Queue not found",
+					Message: err.Error(),
+				})
+			case errors.Is(err, messaging.ErrManagementDisabled):
+				c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+					Error:   "Failed to get queue info",
+					Message: err.Error(),
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+					Error:   "Failed to get queue info",
+					Message: err.Error(),
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, info)
+	}
+}
+
+// @Summary Get worker pool stats
+// @Description Show the live state of the tenant's worker pools on this instance, lanes included: configured workers, worker goroutines alive, busy workers, job queue length and capacity, and jobs processed since start
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.TenantPoolStats
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/pool/stats [get]
+func getPoolStats(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := tm.PoolStats(c.Param("id"))
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get pool stats",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
+// @Summary Get messages with pagination
+// @Description Get messages with cursor-based pagination
+// @Tags messages
+// @Produce json
+// @Param tenant_id query string true "Tenant ID"
+// @Param cursor query string false "Cursor for pagination (an RFC3339 timestamp, normally the previous page's next_cursor)"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param source query string false "Only messages whose metadata source matches"
+// @Param X-Response-Envelope header bool false "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)"
+// @Success 200 {object} services.PaginatedMessages
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /messages [get]
+func getMessages(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Query("tenant_id")
+		if tenantID == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "tenant_id query parameter is required",
+			})
+			return
+		}
+
+		cursor := c.Query("cursor")
+		var cursorPtr *string
+		if cursor != "" {
+			cursorPtr = &cursor
+		}
+
+		limit := 20 // default
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+
+		messages, err := ms.GetMessages(tenantID, cursorPtr, limit, c.Query("source"))
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCursor) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get messages",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		writeList(c, messages, messages.Data, len(messages.Data), messages.NextCursor)
+	}
+}
+
+// @Summary Create a message
+// @Description Create a new message for a tenant. The message ID is generated unless the request supplies one, which must be a UUID not yet used by the tenant.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param tenant_id path string true "Tenant ID"
+// @Param message body models.CreateMessageRequest true "Message data"
+// @Param X-Correlation-ID header string false "Correlation ID used to trace the message; generated when omitted"
+// @Success 201 {object} models.Message
+// @Header 201 {string} X-Correlation-ID "Correlation ID of the created message"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /messages/{tenant_id} [post]
+func createMessage(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("tenant_id")
+
+		var req models.CreateMessageRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, invalidRequest(req, err))
+			return
+		}
+
+		req.CorrelationID = c.GetHeader(correlationIDHeader)
+		if len(req.CorrelationID) > maxCorrelationIDLength {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: fmt.Sprintf("%s must be at most %d characters", correlationIDHeader, maxCorrelationIDLength),
+			})
+			return
+		}
+
+		message, err := ms.CreateMessage(tenantID, &req)
+		if err != nil {
+			if errors.Is(err, services.ErrUnknownLane) || errors.Is(err, services.ErrTransformFailed) ||
+				errors.Is(err, services.ErrInvalidMessageID) || errors.Is(err, services.ErrEmptyPayload) ||
+				errors.Is(err, services.ErrInvalidCreatedAt) || errors.Is(err, services.ErrInvalidExpiresAt) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if errors.Is(err, services.ErrDuplicateMessage) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Message already exists",
+					Message: err.Error(),
+				})
+				return
+			}
+			if errors.Is(err, services.ErrTenantDraining) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Tenant is being deleted",
+					Message: err.Error(),
+				})
+				return
+			}
+			if errors.Is(err, services.ErrTooManyMessageCreates) {
+				c.Header("Retry-After", "1")
+				c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+					Error:   "Too many message creations in progress",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to create message",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.Header(correlationIDHeader, message.CorrelationID)
+		c.JSON(http.StatusCreated, message)
+	}
+}
+
+// @Summary Get a message by ID
+// @Description Get a specific message by its ID
+// @Tags messages
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.Message
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /messages/{id} [get]
+func getMessage(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		messageID := c.Param("id")
+
+		message, err := ms.GetMessage(messageID)
+		if err != nil {
+			if err.Error() == "message not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Message not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get message",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, message)
+	}
+}
+
+// @Summary Get a message's raw payload
+// @Description Get the payload of a message as it was received, before the tenant's transforms were applied. Only kept for tenants with keep_raw_payload set.
+// @Tags messages
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.RawPayload
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /messages/{id}/raw [get]
+func getRawPayload(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		messageID := c.Param("id")
+
+		raw, err := ms.GetRawPayload(messageID)
+		if err != nil {
+			if err.Error() == "message not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Message not found",
+				})
+				return
+			}
+			if errors.Is(err, services.ErrRawPayloadNotKept) {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error:   "Raw payload not available",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get raw payload",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, raw)
+	}
+}
+
+// @Summary Delete a message
+// @Description Delete a message by its ID. Passing the owning tenant saves looking it up from the message.
+// @Tags messages
+// @Produce json
+// @Param id path string true "Message ID"
+// @Param tenant_id query string false "Tenant ID of the message"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /messages/{id} [delete]
+func deleteMessage(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		messageID := c.Param("id")
+
+		err := ms.DeleteMessage(c.Query("tenant_id"), messageID)
+		if err != nil {
+			if err.Error() == "message not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Message not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to delete message",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Message: "Message deleted successfully",
+		})
+	}
+}
+
+// @Summary Get message statistics
+// @Description Get message statistics for a tenant. The exact mode scans the tenant's messages; the approximate mode takes the 24h and 1h counts from per-minute counters and the total from the planner's estimate, for dashboards polling often, and leaves out failed_permanently and retrying. The mode used is returned.
+// @Tags stats
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param mode query string false "exact or approximate (default exact)"
+// @Param If-None-Match header string false "ETag of a previous response, to get a 304 if it is unchanged"
+// @Success 200 {object} models.MessageStats
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused (api.stats_max_age)"
+// @Success 304 "Unchanged since the ETag in If-None-Match"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /stats/tenants/{id}/messages [get]
+func getMessageStats(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var stats *models.MessageStats
+		var err error
+		switch mode := c.DefaultQuery("mode", models.StatsModeExact); mode {
+		case models.StatsModeExact:
+			stats, err = ms.GetMessageStats(c.Request.Context(), tenantID)
+		case models.StatsModeApproximate:
+			stats, err = ms.GetApproximateMessageStats(c.Request.Context(), tenantID)
+		default:
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: fmt.Sprintf("mode must be %s or %s, not %q", models.StatsModeExact, models.StatsModeApproximate, mode),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get message stats",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
+// @Summary Get message throughput
+// @Description Get a tenant's message counts bucketed by interval over a trailing window. Buckets are UTC-aligned and empty buckets are included with a zero count.
+// @Tags stats
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param interval query string false "Bucket size: minute, hour or day (default hour)"
+// @Param window query string false "Trailing window as a duration, e.g. 24h or 90m (default 24h)"
+// @Param If-None-Match header string false "ETag of a previous response, to get a 304 if it is unchanged"
+// @Success 200 {object} models.Throughput
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused (api.stats_max_age)"
+// @Success 304 "Unchanged since the ETag in If-None-Match"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /stats/tenants/{id}/throughput [get]
+func getThroughput(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+		interval := c.DefaultQuery("interval", "hour")
+
+		window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		throughput, err := ms.GetThroughput(c.Request.Context(), tenantID, interval, window)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidRange) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get throughput",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, throughput)
+	}
+}
+
+// @Summary Get message histogram
+// @Description Get a tenant's message counts per minute, hour or day between two times. Buckets are UTC-aligned and empty buckets are included with a zero count.
+// @Tags stats
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param bucket query string false "Bucket size: minute, hour or day (default hour)"
+// @Param from query string false "Start of the range (RFC3339, default 24 hours before to)"
+// @Param to query string false "End of the range, exclusive (RFC3339, default the end of the current bucket)"
+// @Param If-None-Match header string false "ETag of a previous response, to get a 304 if it is unchanged"
+// @Success 200 {object} models.Histogram
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused (api.stats_max_age)"
+// @Success 304 "Unchanged since the ETag in If-None-Match"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /stats/tenants/{id}/histogram [get]
+func getHistogram(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+		bucket := c.DefaultQuery("bucket", "hour")
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "from must be an RFC3339 timestamp",
+			})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "to must be an RFC3339 timestamp",
+			})
+			return
+		}
+		if to == nil {
+			// The end of the current bucket rather than now, so the
+			// response, and with it the ETag, only changes with the counts
+			end := services.BucketEnd(bucket, time.Now())
+			to = &end
+		}
+		if from == nil {
+			start := to.Add(-24 * time.Hour)
+			from = &start
+		}
+
+		histogram, err := ms.GetHistogram(c.Request.Context(), tenantID, bucket, *from, *to)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidRange) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get histogram",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, histogram)
+	}
+}
+
+// @Summary Delete a tenant's messages by payload filter
+// @Description Delete the tenant's messages whose payload matches every filter. A filter is path=value or path!=value, where path is a dot-separated list of object keys (letters, digits, _ and -) and the value is compared as text, e.g. env=test or meta.source!=import. Payloads without the path match neither operator.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param filter query []string true "Payload filter, repeat for several" collectionFormat(multi)
+// @Success 200 {object} models.PurgeResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/messages [delete]
+func purgeMessages(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var filters []services.PayloadFilter
+		for _, expr := range c.QueryArray("filter") {
+			filter, err := services.ParsePayloadFilter(expr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			filters = append(filters, filter)
+		}
+
+		result, err := ms.DeleteMessagesByFilter(tenantID, filters)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidFilter) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to delete messages",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Move a tenant's messages to another tenant
+// @Description Move the tenant's messages whose payload matches every filter to the target tenant, e.g. when merging customers; no filters moves them all. Filters use the syntax of DELETE /tenants/{id}/messages. The move is a single transaction. Messages keep their IDs, status and attempts; pending messages are still queued for the source, so they are skipped and counted.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Param id path string true "Source tenant ID"
+// @Param move body models.MoveMessagesRequest true "Target tenant and filters"
+// @Success 200 {object} models.MoveResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/messages/move [post]
+func moveMessages(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		var req models.MoveMessagesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		var filters []services.PayloadFilter
+		for _, expr := range req.Filters {
+			filter, err := services.ParsePayloadFilter(expr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			filters = append(filters, filter)
+		}
+
+		result, err := ms.MoveMessages(tenantID, req.TargetTenantID, filters)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidMove) || errors.Is(err, services.ErrInvalidFilter) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if errors.Is(err, services.ErrTargetTenantNotFound) {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Target tenant not found",
+				})
+				return
+			}
+			if errors.Is(err, services.ErrDuplicateMessage) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Message already exists",
+					Message: err.Error(),
+				})
+				return
+			}
+			if errors.Is(err, services.ErrTenantDraining) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Tenant is being deleted",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to move messages",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Search a tenant's messages by text
+// @Description Find the tenant's messages whose payload mentions the words of q, most relevant first (ts_rank) and newest first among equally relevant ones. q uses web search syntax: quoted phrases, or, and -word to exclude. Payload keys are searched as well as values.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param q query string true "Search text"
+// @Param limit query int false "Most results to return (default 20, max 100)"
+// @Success 200 {object} models.MessageSearchResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /tenants/{id}/messages/search/text [get]
+func searchMessagesText(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		limit := 20 // default
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				limit = l
+			}
+		}
+
+		result, err := ms.SearchMessages(c.Request.Context(), tenantID, c.Query("q"), limit)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidSearch) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to search messages",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		writeList(c, result, result.Data, len(result.Data), nil)
+	}
+}
+
+// @Summary Reprocess a tenant's messages
+// @Description Send the tenant's processed messages created within [from, to) through processing again. Messages keep their IDs; they are reset to pending and republished, and the new result replaces the old one.
+// @Tags tenants
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param from query string true "Start of the window, inclusive (RFC3339)"
+// @Param to query string true "End of the window, exclusive (RFC3339)"
+// @Success 200 {object} models.ReprocessResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tenants/{id}/reprocess [post]
+func reprocessMessages(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil || from == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "from must be an RFC3339 timestamp",
+			})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil || to == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "to must be an RFC3339 timestamp",
+			})
+			return
+		}
+
+		result, err := ms.ReprocessMessages(tenantID, *from, *to)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidRange) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to reprocess messages",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Reprocess a message
+// @Description Send one processed or failed message through processing again, for targeted debugging. The message keeps its ID; it is reset to pending and republished to its tenant's queue.
+// @Tags messages
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.Message
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /messages/{id}/reprocess [post]
+func reprocessMessage(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Registered as /:tenant_id/reprocess (see SetupRoutes), but the
+		// segment is the message ID
+		messageID := c.Param("tenant_id")
+		message, err := ms.ReprocessMessage(messageID)
+		if err != nil {
+			if err.Error() == "message not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Message not found",
+				})
+				return
+			}
+			if errors.Is(err, services.ErrMessagePending) {
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Message is already pending",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to reprocess message",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, message)
+	}
+}
+
+// @Summary List messages across all tenants
+// @Description Query messages from every tenant partition with keyset pagination (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param from query string false "Only messages created at or after this time (RFC3339)"
+// @Param to query string false "Only messages created before this time (RFC3339)"
+// @Param status query string false "Message status (pending, processed, failed)"
+// @Param source query string false "Only messages whose metadata source matches"
+// @Param cursor query string false "Cursor for pagination"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param X-Response-Envelope header bool false "Wrap the list in a {data, meta} envelope (defaults to api.envelope_lists)"
+// @Success 200 {object} services.PaginatedMessages
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/messages [get]
+func listAllMessages(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := services.MessageFilter{
+			Status: c.Query("status"),
+			Source: c.Query("source"),
+			Cursor: c.Query("cursor"),
+			Limit:  20, // default
+		}
+
+		var err error
+		if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid from parameter",
+				Message: err.Error(),
+			})
+			return
+		}
+		if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid to parameter",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil {
+				filter.Limit = l
+			}
+		}
+
+		messages, err := ms.ListAllMessages(c.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidStatus) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid request",
+					Message: err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to list messages",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		writeList(c, messages, messages.Data, len(messages.Data), messages.NextCursor)
+	}
+}
+
+// @Summary Find a message in any tenant
+// @Description Look a message up by ID across every tenant partition, without knowing its tenant (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.Message
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/messages/{id} [get]
+func findMessage(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		message, err := ms.GetMessage(c.Param("id"))
+		if err != nil {
+			if err.Error() == "message not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Message not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to get message",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, message)
+	}
+}
+
+// parseTimeQuery parses an optional RFC3339 query parameter.
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+// @Summary Reconnect to RabbitMQ
+// @Description Re-dial the broker and recreate every tenant consumer without restarting the process (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.ReconnectResult
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/rabbitmq/reconnect [post]
+func reconnectRabbitMQ(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := tm.ReconnectRabbitMQ()
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+				Error:   "Failed to reconnect to RabbitMQ",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Benchmark message ingestion
+// @Description Create N synthetic messages for a tenant as fast as possible and report throughput and latency percentiles (admin only). The messages are real and tagged with metadata source "benchmark".
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param benchmark body models.BenchmarkIngestRequest true "Benchmark parameters"
+// @Success 200 {object} models.BenchmarkResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/benchmark/ingest [post]
+func benchmarkIngest(ms *services.MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.BenchmarkIngestRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		result, err := ms.BenchmarkIngest(req.TenantID, req.Count, req.Concurrency)
+		if err != nil {
+			if err.Error() == "tenant not found" {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Tenant not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Benchmark failed",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Run partition maintenance
+// @Description ANALYZE (or VACUUM ANALYZE when maintenance.vacuum is set) the message partitions with enough churn (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.MaintenanceResult
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/maintenance [post]
+func runMaintenance(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := tm.RunMaintenance()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Maintenance failed",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Get the last worker pool reconciliation
+// @Description Show the result of the latest reconciliation of live worker pools against the stored worker config, scheduled by reconcile.interval or run through POST /admin/reconcile (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.ReconcileResult
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/reconcile [get]
+func getLastReconcile(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := tm.LastReconcile()
+		if result == nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "No reconciliation has run yet",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary Reconcile worker pools
+// @Description Resize every worker pool on this instance whose live worker count differs from the tenant's or lane's stored worker count, and list the corrections (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.ReconcileResult
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/reconcile [post]
+func runReconcile(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := tm.Reconcile()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Reconciliation failed",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary List orphaned queues
+// @Description List the tenant queues, lane queues and dead letter queues on the broker whose tenant no longer exists, e.g. after a failed delete. Needs rabbitmq.management_url (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.OrphanedQueues
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/orphaned-queues [get]
+func listOrphanedQueues(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		queues, err := tm.OrphanedQueues(c.Request.Context())
+		if err != nil {
+			orphanedQueuesError(c, "Failed to list orphaned queues", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.OrphanedQueues{Queues: queues})
+	}
+}
+
+// @Summary Delete orphaned queues
+// @Description Delete the queues GET /admin/orphaned-queues lists, with any messages still in them, and list those deleted (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.OrphanedQueues
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/orphaned-queues/cleanup [post]
+func cleanupOrphanedQueues(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		queues, err := tm.CleanupOrphanedQueues(c.Request.Context())
+		if err != nil {
+			orphanedQueuesError(c, "Failed to clean up orphaned queues", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.OrphanedQueues{Queues: queues})
+	}
+}
+
+// @Summary List retired partitions
+// @Description List the partitions of deleted tenants kept detached until deletion.partition_grace_period has passed, oldest first (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.RetiredPartitions
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/retired-partitions [get]
+func listRetiredPartitions(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		partitions, err := tm.RetiredPartitions()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to list retired partitions",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.RetiredPartitions{Partitions: partitions})
+	}
+}
+
+// @Summary Restore a deleted tenant from its retired partition
+// @Description Recreate a deleted tenant under its old ID, with the given name and the default config, and attach its retired partition again, messages and all (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "Retired partition name"
+// @Param request body models.RestoreRetiredPartitionRequest true "Tenant name"
+// @Success 200 {object} models.Tenant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/retired-partitions/{name}/restore [post]
+func restoreRetiredPartition(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RestoreRetiredPartitionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		tenant, err := tm.RestoreRetiredPartition(c.Param("name"), req.Name)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrRetiredPartitionNotFound):
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Retired partition not found",
+				})
+			case errors.Is(err, services.ErrTenantExists):
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error:   "Tenant already exists",
+					Message: err.Error(),
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+					Error:   "Failed to restore tenant",
+					Message: err.Error(),
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, tenant)
+	}
+}
+
+// @Summary Drop a retired partition
+// @Description Drop the partition of a deleted tenant, with its messages, without waiting for deletion.partition_grace_period to pass (admin only)
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "Retired partition name"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/retired-partitions/{name} [delete]
+func dropRetiredPartition(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := tm.DropRetiredPartition(c.Param("name")); err != nil {
+			if errors.Is(err, services.ErrRetiredPartitionNotFound) {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{
+					Error: "Retired partition not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to drop retired partition",
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse{Message: "Retired partition dropped"})
+	}
+}
+
+// orphanedQueuesError responds 503 when queues cannot be listed without the
+// management API, and 500 otherwise.
+func orphanedQueuesError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, messaging.ErrManagementDisabled) {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, models.ErrorResponse{
+		Error:   message,
+		Message: err.Error(),
+	})
+}
+
+// readiness reports whether Postgres and RabbitMQ are reachable, with 503
+// when either is not. The checks run every health.cache_ttl rather than on
+// each probe.
+func readiness(tm *services.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := tm.Readiness()
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
+
+// adminAuthMiddleware requires a matching "Authorization: Bearer <token>"
+// header. All admin routes are refused when no token is configured.
+func adminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error: "Admin API is disabled",
+			})
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error: "Unauthorized",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// envelopeMiddleware records whether list responses to the request are
+// enveloped: as the X-Response-Envelope header says, or else if enabled.
+func envelopeMiddleware(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		envelope := enabled
+		if value := c.GetHeader(envelopeHeader); value != "" {
+			var err error
+			if envelope, err = strconv.ParseBool(value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "Invalid " + envelopeHeader + " header",
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+		c.Set(envelopeKey, envelope)
+		c.Next()
+	}
+}
+
+// decompressMiddleware decodes request bodies sent with a gzip or deflate
+// Content-Encoding, so handlers read them as if they had been sent plain.
+// Bodies that decompress to more than limit bytes are rejected with 413, and
+// other encodings with 415.
+func decompressMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			c.Next()
+			return
+		}
+
+		var reader io.Reader
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(c.Request.Body)
+		case "deflate":
+			reader, err = newDeflateReader(c.Request.Body)
+		default:
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+				Error:   "Unsupported Content-Encoding",
+				Message: fmt.Sprintf("%q is not supported; use gzip or deflate", encoding),
+			})
+			return
+		}
+
+		var body []byte
+		if err == nil {
+			if limit > 0 {
+				reader = io.LimitReader(reader, limit+1)
+			}
+			body, err = io.ReadAll(reader)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: fmt.Sprintf("failed to decode %s body: %v", encoding, err),
+			})
+			return
+		}
+		if limit > 0 && int64(len(body)) > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+				Error:   "Request body too large",
+				Message: fmt.Sprintf("decompressed body exceeds %d bytes", limit),
+			})
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Next()
+	}
+}
+
+// timedRoutes are the routes api.request_timeout applies to: reads whose
+// handlers pass the request's context on to their queries, so a timed out
+// request stops its work instead of leaving it running. Mutating and
+// streaming routes are left out, as a 503 would not undo what they did.
+var timedRoutes = map[string]bool{
+	"/api/v1/tenants/:id/queue/info":           true,
+	"/api/v1/tenants/:id/messages/search/text": true,
+	"/api/v1/stats/tenants/:id/messages":       true,
+	"/api/v1/stats/tenants/:id/throughput":     true,
+	"/api/v1/stats/tenants/:id/histogram":      true,
+	"/api/v1/admin/messages":                   true,
+	"/api/v1/admin/orphaned-queues":            true,
+}
+
+// timeoutMiddleware runs each request under a context with a deadline: the
+// route's entry in overrides if it has one, or else timeout for the
+// timedRoutes. Other routes run untimed. Handlers pass the context on to
+// their database queries, so those are cancelled at the deadline. A response
+// written after the deadline is replaced with a 503.
+func timeoutMiddleware(timeout time.Duration, overrides map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := timeout
+		if !timedRoutes[c.FullPath()] {
+			timeout = 0
+		}
+		if override, exists := overrides[c.FullPath()]; exists {
+			timeout = override
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx, timeout: timeout}
+		c.Writer = writer
+
+		c.Next()
+
+		// A handler that gave up without responding
+		if !writer.Written() {
+			writer.expired()
+		}
+	}
+}
+
+// timeoutWriter drops what a handler writes once the request's deadline has
+// passed, and responds with a 503 in its place.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timeout  time.Duration
+	timedOut bool
+}
+
+// expired reports whether the deadline has passed, writing the 503 the first
+// time it finds it has.
+func (w *timeoutWriter) expired() bool {
+	if w.timedOut {
+		return true
+	}
+	if w.ResponseWriter.Written() || !errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	w.timedOut = true
+
+	body, _ := json.Marshal(models.ErrorResponse{
+		Error:   "Request timed out",
+		Message: fmt.Sprintf("the request did not complete within %s", w.timeout),
+	})
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	w.ResponseWriter.Write(body)
+	return true
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if !w.expired() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.expired() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// cacheMiddleware lets clients cache successful responses: each gets an ETag
+// of its body and a Cache-Control max-age of maxAge, and a request whose
+// If-None-Match holds the ETag of the response it would get is answered with
+// 304 and no body. The handler still runs, so revalidating saves the
+// transfer, not the work; maxAge is what spares the handler.
+func cacheMiddleware(maxAge time.Duration) gin.HandlerFunc {
+	cacheControl := fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+	if maxAge <= 0 {
+		cacheControl = "no-cache"
+	}
+	return func(c *gin.Context) {
+		writer := &cacheWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		if writer.status != http.StatusOK {
+			writer.flush()
+			return
+		}
+		sum := sha256.Sum256(writer.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		header := writer.ResponseWriter.Header()
+		header.Set("ETag", etag)
+		header.Set("Cache-Control", cacheControl)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			writer.ResponseWriter.WriteHeader(http.StatusNotModified)
+			writer.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		writer.flush()
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. Weak
+// validators match their strong form, as RFC 9110 has it for GET.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheWriter holds back a handler's response, so cacheMiddleware can tag it
+// or replace it with a 304 before anything is sent.
+type cacheWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// flush sends the response held back.
+func (w *cacheWriter) flush() {
+	if w.status == 0 {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *cacheWriter) WriteHeaderNow() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.Write(data)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.WriteString(s)
+}
+
+func (w *cacheWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *cacheWriter) Size() int {
+	if w.status == 0 {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *cacheWriter) Written() bool {
+	return w.status != 0
+}
+
+// newDeflateReader reads a deflate-encoded body. HTTP's deflate is zlib
+// wrapped, but some clients send raw deflate, so the zlib header is checked
+// before choosing.
+func newDeflateReader(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// writeList responds with a list: bare, the way the endpoint has always
+// returned it, or with the items in data wrapped in a models.ListResponse
+// when the request is enveloped.
+func writeList(c *gin.Context, bare, data interface{}, count int, nextCursor *string) {
+	if !c.GetBool(envelopeKey) {
+		c.JSON(http.StatusOK, bare)
+		return
+	}
+	c.JSON(http.StatusOK, models.ListResponse{
+		Data: data,
+		Meta: models.ListMeta{Count: count, NextCursor: nextCursor},
+	})
+}
+
+// invalidRequest is the 400 response for a request body that req could not
+// be bound from. Validation failures and values of the wrong JSON type are
+// also listed per field, under the field's JSON path, so clients can show
+// each next to the form field at fault.
+func invalidRequest(req interface{}, err error) models.ErrorResponse {
+	resp := models.ErrorResponse{Error: "Invalid request", Message: err.Error()}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		resp.Fields = []models.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be a JSON %s, not %s", jsonKind(typeErr.Type), typeErr.Value),
+		}}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		for _, fe := range validationErrs {
+			resp.Fields = append(resp.Fields, models.FieldError{
+				Field:   jsonFieldPath(reflect.TypeOf(req), fe),
+				Rule:    fe.Tag(),
+				Message: fieldErrorMessage(fe),
+			})
+		}
+	}
+	return resp
+}
+
+// jsonFieldPath turns a failed field's Go path, such as
+// "CreateBatchRequest.Items[2].Payload", into its path in the JSON body,
+// "items[2].payload", by looking up each field's json tag in t.
+func jsonFieldPath(t reflect.Type, fe validator.FieldError) string {
+	segments := strings.Split(fe.StructNamespace(), ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return fe.Field()
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return fe.Field()
+		}
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			name = tag
+		}
+		path = append(path, name+index)
+
+		t = field.Type
+		// Each [i] or [key] steps into an element
+		for i := strings.Count(index, "["); i > 0; i-- {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			}
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+// fieldErrorMessage describes a failed binding rule in words.
+func fieldErrorMessage(fe validator.FieldError) string {
+	bound := func(qualifier string) string {
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", qualifier, fe.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("must have %s %s entries", qualifier, fe.Param())
+		}
+		return fmt.Sprintf("must be %s %s", qualifier, fe.Param())
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return bound("at least")
+	case "max", "lte":
+		return bound("at most")
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed the %s=%s rule", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+// jsonKind names the JSON type a Go value decodes from.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
 	}
+	return "object"
 }
 
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Correlation-ID, X-Response-Envelope, Content-Encoding")
+		c.Header("Access-Control-Expose-Headers", "X-Correlation-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,37 @@
+// Package backoff computes delays between retries.
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff is exponential backoff with full jitter: the delay before retry n
+// is drawn uniformly from zero to Base doubled n times, capped at Cap.
+// Spreading retries over the whole range keeps clients that failed together,
+// such as every tenant after a broker blip, from retrying in lockstep.
+type Backoff struct {
+	Base time.Duration
+	// Cap bounds the delay. A cap below Base means Base.
+	Cap time.Duration
+}
+
+// Delay returns how long to wait before retry attempt, counted from zero.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	ceiling := b.Base
+	for i := 0; i < attempt && ceiling < b.Cap; i++ {
+		ceiling *= 2
+	}
+	if b.Cap >= b.Base {
+		ceiling = min(ceiling, b.Cap)
+	}
+	return rand.N(ceiling + 1)
+}
+
+// Sleep waits for the delay before retry attempt.
+func (b Backoff) Sleep(attempt int) {
+	time.Sleep(b.Delay(attempt))
+}
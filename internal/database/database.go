@@ -2,10 +2,17 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"jatis/internal/backoff"
+
+	"github.com/lib/pq"
 )
 
 func NewConnection(databaseURL string) (*sql.DB, error) {
@@ -21,8 +28,58 @@ func NewConnection(databaseURL string) (*sql.DB, error) {
 	return db, nil
 }
 
-func RunMigrations(db *sql.DB) error {
-	migrations := []string{
+// ErrSchemaMismatch is returned when the database schema is older than this
+// build expects.
+var ErrSchemaMismatch = errors.New("database schema is out of date")
+
+// RunMigrations brings the schema up to date and records its version.
+// Transient failures are retried as Migrate describes.
+func RunMigrations(db *sql.DB, b backoff.Backoff, maxRetries int) error {
+	steps := append(migrations(),
+		`CREATE TABLE IF NOT EXISTS schema_version (
+			id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+			version INTEGER NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		// A build behind the schema, e.g. during a rolling deploy, leaves
+		// the version alone
+		fmt.Sprintf(`INSERT INTO schema_version (version) VALUES (%d)
+			ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version), applied_at = NOW();`, SchemaVersion()),
+	)
+	return Migrate(db, steps, b, maxRetries)
+}
+
+// SchemaVersion is the schema version this build expects: the number of
+// migration steps it knows.
+func SchemaVersion() int {
+	return len(migrations())
+}
+
+// CheckSchemaVersion returns ErrSchemaMismatch if the schema recorded by
+// the last migration run is older than SchemaVersion, or was never
+// recorded. A newer schema is accepted, as migrations only add to it.
+func CheckSchemaVersion(db *sql.DB) error {
+	var recorded bool
+	if err := db.QueryRow(`SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&recorded); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	var version int
+	if recorded {
+		err := db.QueryRow(`SELECT version FROM schema_version`).Scan(&version)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+	}
+	if want := SchemaVersion(); version < want {
+		return fmt.Errorf("%w: schema is at version %d, this build needs %d; run the migrations or set database.auto_migrate", ErrSchemaMismatch, version, want)
+	}
+	return nil
+}
+
+// migrations lists the schema changes in the order they are applied. Each
+// step must be safe to run again, and new steps go at the end.
+func migrations() []string {
+	return []string{
 		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`,
 
 		`CREATE TABLE IF NOT EXISTS tenants (
@@ -45,26 +102,200 @@ func RunMigrations(db *sql.DB) error {
 			workers INTEGER NOT NULL DEFAULT 3,
 			updated_at TIMESTAMPTZ DEFAULT NOW()
 		);`,
+
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending';`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS dedicated_pool BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS metadata JSONB;`,
+
+		`CREATE INDEX IF NOT EXISTS idx_messages_metadata_source ON messages (tenant_id, (metadata->>'source'));`,
+
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255);`,
+
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS failure_policy VARCHAR(20) NOT NULL DEFAULT 'retry_then_dlq';`,
+
+		`CREATE TABLE IF NOT EXISTS tenant_config_history (
+			id BIGSERIAL PRIMARY KEY,
+			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+			config JSONB NOT NULL,
+			changed_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_tenant_config_history_tenant ON tenant_config_history (tenant_id, changed_at);`,
+
+		`CREATE TABLE IF NOT EXISTS tenant_lanes (
+			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+			name VARCHAR(32) NOT NULL,
+			workers INTEGER NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (tenant_id, name)
+		);`,
+
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS lane VARCHAR(32);`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS single_active_consumer BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS max_concurrency INTEGER NOT NULL DEFAULT 0;`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS transforms JSONB NOT NULL DEFAULT '[]';`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS keep_raw_payload BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+		// The payload as received, kept only for tenants with keep_raw_payload
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS raw_payload JSONB;`,
+
+		// The instance consuming each tenant when cluster.enabled is set
+		`CREATE TABLE IF NOT EXISTS tenant_owners (
+			tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+			instance_id VARCHAR(255) NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_tenant_owners_instance ON tenant_owners (instance_id);`,
+
+		`ALTER TABLE tenants ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';`,
+
+		// Serves created_at ordering and the (created_at, id) keyset cursor.
+		// Defined on the parent, so Postgres builds it on every existing
+		// partition and on each partition created afterwards.
+		`CREATE INDEX IF NOT EXISTS idx_messages_created_at_id ON messages (created_at, id);`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS sample_rate DOUBLE PRECISION NOT NULL DEFAULT 1;`,
+
+		// Full text search over the payload's keys and values, as text
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (to_tsvector('simple', payload::text)) STORED;`,
+
+		`CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON messages USING GIN (search_vector);`,
+
+		// Set while a tenant is soft-deleted; it is purged once
+		// deletion.grace_period has passed
+		`ALTER TABLE tenants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS allow_empty_payload BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+		// The tenant_configs columns set explicitly rather than left at
+		// their default
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS overridden TEXT[] NOT NULL DEFAULT '{}';`,
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS worker_schedule JSONB NOT NULL DEFAULT '[]';`,
+
+		// Messages created per tenant and minute, counted as they are
+		// created, for approximate stats that do not scan the partition
+		`CREATE TABLE IF NOT EXISTS message_counts (
+			tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+			bucket TIMESTAMPTZ NOT NULL,
+			count BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (tenant_id, bucket)
+		);`,
+
+		// When a message expires: it is hidden from reads from then on and
+		// deleted by the next retention run
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages (expires_at) WHERE expires_at IS NOT NULL;`,
+
+		// Checked by the consumers before a payload is processed; NULL
+		// accepts any payload
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS payload_schema JSONB;`,
+
+		// Transient tenants' queues are non-durable and their messages are
+		// published without persistence
+		`ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS transient BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+		// The blob store key of a payload offloaded there, whose payload is
+		// then NULL
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS payload_ref TEXT;`,
 	}
+}
+
+// Migrate runs the migration steps in order in a single transaction, so a
+// run that fails leaves the schema as it was. A step failing with a
+// transient error is rolled back to the savepoint before it and retried, up
+// to maxRetries times; a run that loses its connection, or cannot get one
+// while the database is still starting, is retried from the start. Instances
+// starting together take turns.
+func Migrate(db *sql.DB, steps []string, b backoff.Backoff, maxRetries int) error {
+	return Retry(b, maxRetries, func() error {
+		return withAdvisoryLock(db, "migrations", func(tx *sql.Tx) error {
+			for i, step := range steps {
+				if err := migrateStep(tx, step, b, maxRetries); err != nil {
+					return fmt.Errorf("failed to execute migration %d: %w", i, err)
+				}
+			}
+			return nil
+		})
+	})
+}
 
-	for _, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("failed to execute migration: %w", err)
+// migrateStep runs one migration step in tx, retrying it from a savepoint
+// while it fails with a transient error.
+func migrateStep(tx *sql.Tx, step string, b backoff.Backoff, maxRetries int) error {
+	if _, err := tx.Exec(`SAVEPOINT migration_step`); err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		_, err := tx.Exec(step)
+		if err == nil {
+			_, err = tx.Exec(`RELEASE SAVEPOINT migration_step`)
+			return err
 		}
+		if !IsRetryable(err) || attempt >= maxRetries {
+			return err
+		}
+		// A lost connection takes the transaction with it, leaving the
+		// retry to Migrate
+		if _, rollbackErr := tx.Exec(`ROLLBACK TO SAVEPOINT migration_step`); rollbackErr != nil {
+			return err
+		}
+		b.Sleep(attempt)
 	}
+}
 
-	return nil
+// PartitionName returns the name of the messages partition holding a
+// tenant's rows.
+func PartitionName(tenantID string) string {
+	return "messages_" + strings.ReplaceAll(tenantID, "-", "_")
 }
 
+// ErrPartitionConflict is returned when the partition name of a tenant is
+// already taken by a table that is not that tenant's partition.
+var ErrPartitionConflict = errors.New("partition name already in use")
+
+// CreateTenantPartition creates a tenant's messages partition. Indexes
+// defined on messages, such as idx_messages_created_at_id, are created on
+// the new partition along with it.
+//
+// Creation holds an advisory lock on the partition name, so concurrent
+// creates of the same partition run one after the other instead of racing
+// on the catalog, and a name already taken by another tenant is reported
+// instead of being silently shared.
 func CreateTenantPartition(db *sql.DB, tenantID string) error {
-	safeTenantID := strings.ReplaceAll(tenantID, "-", "_")
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS messages_%s 
-		PARTITION OF messages 
-		FOR VALUES IN ('%s');
-	`, safeTenantID, tenantID)
-
-	_, err := db.Exec(query)
+	name := PartitionName(tenantID)
+	err := withAdvisoryLock(db, name, func(tx *sql.Tx) error {
+		var bound sql.NullString
+		err := tx.QueryRow(`SELECT pg_get_expr(relpartbound, oid) FROM pg_class WHERE oid = to_regclass($1)`, name).Scan(&bound)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return err
+		case bound.String == fmt.Sprintf("FOR VALUES IN ('%s')", tenantID):
+			return nil
+		default:
+			return fmt.Errorf("%w: %s", ErrPartitionConflict, name)
+		}
+
+		query := fmt.Sprintf(`
+			CREATE TABLE %s
+			PARTITION OF messages
+			FOR VALUES IN ('%s');
+		`, name, tenantID)
+		_, err = tx.Exec(query)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create partition for tenant %s: %w", tenantID, err)
 	}
@@ -72,13 +303,225 @@ func CreateTenantPartition(db *sql.DB, tenantID string) error {
 	return nil
 }
 
+// withAdvisoryLock runs fn in a transaction holding the advisory lock on
+// name, such as a partition name. The lock is released when the transaction
+// ends.
+func withAdvisoryLock(db *sql.DB, name string, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, name); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsMissingPartition reports whether err is Postgres refusing a row because
+// the messages table has no partition for its tenant.
+func IsMissingPartition(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23514" &&
+		strings.Contains(pqErr.Message, "no partition of relation")
+}
+
+// IsUniqueViolation reports whether err is Postgres rejecting a row whose
+// key already exists.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 func DropTenantPartition(db *sql.DB, tenantID string) error {
-	safeTenantID := strings.ReplaceAll(tenantID, "-", "_")
-	query := fmt.Sprintf(`DROP TABLE IF EXISTS messages_%s;`, safeTenantID)
-	_, err := db.Exec(query)
+	name := PartitionName(tenantID)
+	err := withAdvisoryLock(db, name, func(tx *sql.Tx) error {
+		_, err := tx.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, name))
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to drop partition for tenant %s: %w", tenantID, err)
 	}
 
 	return nil
 }
+
+// retiredPartitionPattern matches the names RetireTenantPartition gives
+// partitions: the tenant ID and the Unix time they were detached.
+var retiredPartitionPattern = regexp.MustCompile(`^retired_([0-9a-f_]{36})_([0-9]+)$`)
+
+// RetireTenantPartition detaches a tenant's partition from messages and
+// renames it to retired_<tenant>_<unix time>, so its rows are out of every
+// query but can still be recovered with ReattachRetiredPartition until
+// DropRetiredPartitions drops it. It returns the new name, or "" if the
+// tenant had no partition.
+func RetireTenantPartition(db *sql.DB, tenantID string) (string, error) {
+	name := PartitionName(tenantID)
+	retired := fmt.Sprintf("retired_%s_%d", strings.ReplaceAll(tenantID, "-", "_"), time.Now().Unix())
+	err := withAdvisoryLock(db, name, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			retired = ""
+			return nil
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE messages DETACH PARTITION %s`, name)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, name, retired))
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retire partition for tenant %s: %w", tenantID, err)
+	}
+
+	return retired, nil
+}
+
+// ErrRetiredPartitionNotFound is returned for a retired partition that does
+// not exist, or has already been dropped.
+var ErrRetiredPartitionNotFound = errors.New("retired partition not found")
+
+// RetiredPartition is the partition of a deleted tenant, kept detached by
+// RetireTenantPartition.
+type RetiredPartition struct {
+	Name      string
+	TenantID  string
+	RetiredAt time.Time
+}
+
+// ListRetiredPartitions returns the retired partitions, oldest first.
+func ListRetiredPartitions(db *sql.DB) ([]RetiredPartition, error) {
+	rows, err := db.Query(`SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename LIKE 'retired\_%'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retired partitions: %w", err)
+	}
+	defer rows.Close()
+
+	partitions := []RetiredPartition{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan retired partition: %w", err)
+		}
+		partition, ok := parseRetiredPartition(name)
+		if !ok {
+			continue
+		}
+		partitions = append(partitions, partition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list retired partitions: %w", err)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].RetiredAt.Before(partitions[j].RetiredAt)
+	})
+	return partitions, nil
+}
+
+func parseRetiredPartition(name string) (RetiredPartition, bool) {
+	match := retiredPartitionPattern.FindStringSubmatch(name)
+	if match == nil {
+		return RetiredPartition{}, false
+	}
+	retiredAt, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return RetiredPartition{}, false
+	}
+	return RetiredPartition{
+		Name:      name,
+		TenantID:  strings.ReplaceAll(match[1], "_", "-"),
+		RetiredAt: time.Unix(retiredAt, 0).UTC(),
+	}, true
+}
+
+// PartitionPayloadRefs returns the payload_ref of every message in a
+// tenant partition, attached or retired, that has its payload offloaded. A
+// partition that does not exist has none.
+func PartitionPayloadRefs(db *sql.DB, partition string) ([]string, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, partition).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up partition %s: %w", partition, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT payload_ref FROM %s WHERE payload_ref IS NOT NULL`, pq.QuoteIdentifier(partition)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offloaded payloads of %s: %w", partition, err)
+	}
+	defer rows.Close()
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("failed to scan payload_ref: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// DropRetiredPartition drops one retired partition ahead of its grace period.
+func DropRetiredPartition(db *sql.DB, name string) error {
+	if _, ok := parseRetiredPartition(name); !ok {
+		return ErrRetiredPartitionNotFound
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up retired partition %s: %w", name, err)
+	}
+	if !exists {
+		return ErrRetiredPartitionNotFound
+	}
+	if _, err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, name)); err != nil {
+		return fmt.Errorf("failed to drop retired partition %s: %w", name, err)
+	}
+	return nil
+}
+
+// ReattachRetiredPartition undoes RetireTenantPartition: it renames the
+// retired partition back to its tenant's partition and attaches it to
+// messages again, and returns the tenant ID. The tenant must not have a
+// partition of its own by then.
+func ReattachRetiredPartition(db *sql.DB, name string) (string, error) {
+	partition, ok := parseRetiredPartition(name)
+	if !ok {
+		return "", ErrRetiredPartitionNotFound
+	}
+	tenantName := PartitionName(partition.TenantID)
+	err := withAdvisoryLock(db, tenantName, func(tx *sql.Tx) error {
+		var retiredExists, partitionExists bool
+		err := tx.QueryRow(`SELECT to_regclass($1) IS NOT NULL, to_regclass($2) IS NOT NULL`, name, tenantName).
+			Scan(&retiredExists, &partitionExists)
+		if err != nil {
+			return err
+		}
+		if !retiredExists {
+			return ErrRetiredPartitionNotFound
+		}
+		if partitionExists {
+			return fmt.Errorf("tenant %s already has partition %s", partition.TenantID, tenantName)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, name, tenantName)); err != nil {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE messages ATTACH PARTITION %s FOR VALUES IN ('%s')`, tenantName, partition.TenantID))
+		return err
+	})
+	if errors.Is(err, ErrRetiredPartitionNotFound) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to reattach partition %s: %w", name, err)
+	}
+	return partition.TenantID, nil
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// PartitionChurn is a messages partition and the number of row changes
+// Postgres has recorded for it since it was last analyzed.
+type PartitionChurn struct {
+	Partition string
+	Changes   int64
+}
+
+// ListPartitionChurn returns every partition of the messages table with its
+// dead tuples plus rows modified since the last ANALYZE.
+func ListPartitionChurn(db *sql.DB) ([]PartitionChurn, error) {
+	query := `
+		SELECT c.relname,
+			COALESCE(s.n_dead_tup, 0) + COALESCE(s.n_mod_since_analyze, 0)
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+		WHERE p.relname = 'messages'
+		ORDER BY c.relname
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []PartitionChurn
+	for rows.Next() {
+		var p PartitionChurn
+		if err := rows.Scan(&p.Partition, &p.Changes); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		partitions = append(partitions, p)
+	}
+
+	return partitions, rows.Err()
+}
+
+// MaintainPartitions runs ANALYZE, or VACUUM (ANALYZE) when vacuum is set,
+// on every messages partition with at least minChanges recorded changes.
+// It returns the partitions that were maintained.
+func MaintainPartitions(db *sql.DB, vacuum bool, minChanges int64) ([]string, error) {
+	partitions, err := ListPartitionChurn(db)
+	if err != nil {
+		return nil, err
+	}
+
+	command := "ANALYZE"
+	if vacuum {
+		command = "VACUUM (ANALYZE)"
+	}
+
+	maintained := []string{}
+	for _, p := range partitions {
+		if p.Changes < minChanges {
+			continue
+		}
+		// VACUUM cannot run inside a transaction block, so each partition
+		// gets its own statement
+		if _, err := db.Exec(command + " " + pq.QuoteIdentifier(p.Partition)); err != nil {
+			return maintained, fmt.Errorf("failed to maintain partition %s: %w", p.Partition, err)
+		}
+		maintained = append(maintained, p.Partition)
+	}
+
+	return maintained, nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+
+	"jatis/internal/backoff"
+
+	"github.com/lib/pq"
+)
+
+// Retry runs fn until it succeeds, returns a non-retryable error, or has been
+// retried maxRetries times, waiting the backoff's delay between attempts.
+func Retry(b backoff.Backoff, maxRetries int, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) || attempt >= maxRetries {
+			return err
+		}
+		b.Sleep(attempt)
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// serialization failures, deadlocks and dropped connections. Constraint
+// violations and other errors are not retryable.
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08": // connection_exception
+			return true
+		case "40": // transaction_rollback (serialization_failure, deadlock_detected)
+			return pqErr.Code == "40001" || pqErr.Code == "40P01"
+		case "57": // operator_intervention (admin_shutdown, cannot_connect_now)
+			return pqErr.Code == "57P01" || pqErr.Code == "57P03"
+		}
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
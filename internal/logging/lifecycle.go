@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Lifecycle events, in the order a message normally goes through them.
+// A message that is retried goes from EventRequeued back to EventDelivered.
+const (
+	EventCreated      = "created"
+	EventPublished    = "published"
+	EventDelivered    = "delivered"
+	EventProcessing   = "processing"
+	EventProcessed    = "processed"
+	EventRequeued     = "requeued"
+	EventDeadLettered = "dead_lettered"
+	EventDropped      = "dropped"
+)
+
+// Lifecycle logs each state transition of a message as a single line of
+// key=value pairs, e.g.
+//
+//	lifecycle event=delivered message_id=... correlation_id=... tenant_id=...
+//
+// so one message can be followed through the system with grep. A nil or
+// disabled Lifecycle logs nothing.
+type Lifecycle struct {
+	logger *log.Logger
+}
+
+// NewLifecycle returns a Lifecycle writing to logger, or a disabled one
+// unless enabled is set.
+func NewLifecycle(enabled bool, logger *log.Logger) *Lifecycle {
+	if !enabled {
+		return nil
+	}
+	return &Lifecycle{logger: logger}
+}
+
+// Enabled reports whether events are logged.
+func (l *Lifecycle) Enabled() bool {
+	return l != nil
+}
+
+// Event logs a transition of the message. fields are extra key, value pairs
+// such as "lane", "realtime".
+func (l *Lifecycle) Event(event, tenantID, messageID, correlationID string, fields ...string) {
+	if l == nil {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("lifecycle event=")
+	b.WriteString(event)
+	writeField(&b, "message_id", messageID)
+	writeField(&b, "correlation_id", correlationID)
+	writeField(&b, "tenant_id", tenantID)
+	for i := 0; i+1 < len(fields); i += 2 {
+		writeField(&b, fields[i], fields[i+1])
+	}
+	l.logger.Print(b.String())
+}
+
+// writeField appends a key=value pair, quoting values that contain spaces
+// or quotes. Empty values are left out.
+func writeField(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	if strings.ContainsAny(value, " \"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(value)
+}
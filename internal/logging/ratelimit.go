@@ -0,0 +1,100 @@
+// Package logging keeps repeated errors from flooding the log.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how long the package-level Printf suppresses repeats of
+// a message after logging it.
+const DefaultInterval = 10 * time.Second
+
+var std = NewLimiter(DefaultInterval, log.Default())
+
+// Printf logs through a Limiter shared by the whole process, writing to the
+// standard logger.
+func Printf(format string, args ...interface{}) {
+	std.Printf(format, args...)
+}
+
+// Limiter logs the first of a run of repeated messages straight away and
+// suppresses the rest, logging a summary with their count once per interval
+// for as long as they keep coming.
+//
+// Messages count as repeats when they have the same format string and the
+// same errors among their arguments. Other arguments, such as message or
+// tenant IDs, are ignored, so "failed to settle message %s: %v" repeated for
+// a thousand messages with the same error is logged once.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	logger   *log.Logger
+	windows  map[string]*window
+}
+
+// window tracks a message that was logged and the repeats suppressed since.
+type window struct {
+	suppressed int
+	last       string
+}
+
+// NewLimiter returns a Limiter writing to logger.
+func NewLimiter(interval time.Duration, logger *log.Logger) *Limiter {
+	return &Limiter{
+		interval: interval,
+		logger:   logger,
+		windows:  make(map[string]*window),
+	}
+}
+
+// Printf logs the message unless it repeats one logged within the interval.
+func (l *Limiter) Printf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	key := repeatKey(format, args)
+
+	l.mu.Lock()
+	if w, open := l.windows[key]; open {
+		w.suppressed++
+		w.last = message
+		l.mu.Unlock()
+		return
+	}
+	l.windows[key] = &window{}
+	l.mu.Unlock()
+
+	l.logger.Print(message)
+	time.AfterFunc(l.interval, func() { l.closeWindow(key) })
+}
+
+// closeWindow logs a summary of the repeats suppressed during the last
+// interval. While repeats keep coming the window stays open for another
+// interval, so a sustained flood is summarized once per interval.
+func (l *Limiter) closeWindow(key string) {
+	l.mu.Lock()
+	w := l.windows[key]
+	if w.suppressed == 0 {
+		delete(l.windows, key)
+		l.mu.Unlock()
+		return
+	}
+	suppressed, last := w.suppressed, w.last
+	w.suppressed = 0
+	l.mu.Unlock()
+
+	l.logger.Printf("%s (repeated %d times in the last %s)", last, suppressed, l.interval)
+	time.AfterFunc(l.interval, func() { l.closeWindow(key) })
+}
+
+// repeatKey identifies a message by its format and the errors it reports.
+func repeatKey(format string, args []interface{}) string {
+	key := format
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			key += "\x00" + err.Error()
+		}
+	}
+	return key
+}
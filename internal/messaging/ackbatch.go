@@ -0,0 +1,119 @@
+package messaging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"jatis/internal/logging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ackBatcher acknowledges a consumer's deliveries in batches with a single
+// multiple-ack. Workers settle deliveries out of order, and a multiple-ack
+// of tag n settles every outstanding tag up to n, so a batch only ever
+// covers the contiguous run of settled tags above the last one acked; a
+// delivery still being processed holds back the acks after it.
+type ackBatcher struct {
+	channel *amqp.Channel
+	size    int
+	mu      sync.Mutex
+	// floor is the highest tag below which every delivery is settled with
+	// the broker
+	floor uint64
+	// pending holds the settled tags above floor: true for an ack waiting
+	// for its batch, false for a delivery already settled on its own
+	pending map[uint64]bool
+	// ready counts the acks waiting in pending
+	ready int
+}
+
+func newAckBatcher(channel *amqp.Channel, size int) *ackBatcher {
+	return &ackBatcher{channel: channel, size: size, pending: make(map[uint64]bool)}
+}
+
+// ack records tag as processed and acks the batch once size acks are
+// waiting.
+func (b *ackBatcher) ack(tag uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending[tag] = true
+	b.ready++
+	if b.ready < b.size {
+		return nil
+	}
+	return b.flush()
+}
+
+// settled records that tag was settled on its own, e.g. nacked. It must be
+// called after the settlement was sent, so a later batch cannot ack it
+// first.
+func (b *ackBatcher) settled(tag uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[tag] = false
+}
+
+// flush acks the contiguous run of settled tags above floor.
+func (b *ackBatcher) flush() error {
+	var last uint64
+	for tag := b.floor + 1; ; tag++ {
+		waiting, exists := b.pending[tag]
+		if !exists {
+			break
+		}
+		if waiting {
+			last = tag
+			b.ready--
+		}
+		delete(b.pending, tag)
+		b.floor = tag
+	}
+
+	if last == 0 {
+		return nil
+	}
+	if err := b.channel.Ack(last, true); err != nil {
+		return fmt.Errorf("failed to ack batch up to %d: %w", last, err)
+	}
+	return nil
+}
+
+// flushAll acks every waiting tag: the contiguous run with a multiple-ack,
+// the rest one by one.
+func (b *ackBatcher) flushAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.flush(); err != nil {
+		logging.Printf("Warning: %v", err)
+	}
+	for tag, waiting := range b.pending {
+		if !waiting {
+			continue
+		}
+		if err := b.channel.Ack(tag, false); err != nil {
+			logging.Printf("Warning: failed to ack delivery %d: %v", tag, err)
+		}
+		b.pending[tag] = false
+		b.ready--
+	}
+}
+
+// flushWorker acks waiting tags every interval until done is closed, so an
+// incomplete batch is not held indefinitely.
+func (b *ackBatcher) flushWorker(interval time.Duration, done <-chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.flushAll()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package messaging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"jatis/internal/backoff"
+	"jatis/internal/logging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers added to a message when it is dead-lettered.
+const (
+	TenantIDHeader         = "x-tenant-id"
+	LaneHeader             = "x-lane"
+	DeadLetterReasonHeader = "x-dead-letter-reason"
+)
+
+// DeadLetterQueueName returns the name of a tenant's dead letter queue,
+// shared by its main queue and lanes.
+func DeadLetterQueueName(tenantID string) string {
+	return fmt.Sprintf("tenant_%s_dlq", tenantID)
+}
+
+// DeadLetter is a message taken from a tenant's dead letter queue.
+type DeadLetter struct {
+	TenantID      string
+	Lane          string
+	MessageID     string
+	CorrelationID string
+	// Reason is why the message was dead-lettered, usually the error of its
+	// last processing attempt
+	Reason string
+	Body   []byte
+}
+
+// newDeadLetter reads a delivery from the dead letter queue of tenantID. The
+// tenant in the message's headers takes precedence over the queue's.
+func newDeadLetter(tenantID string, delivery amqp.Delivery) DeadLetter {
+	dl := DeadLetter{
+		TenantID:      tenantID,
+		MessageID:     delivery.MessageId,
+		CorrelationID: CorrelationID(delivery),
+		Body:          delivery.Body,
+	}
+	if id, ok := delivery.Headers[TenantIDHeader].(string); ok && id != "" {
+		dl.TenantID = id
+	}
+	dl.Lane, _ = delivery.Headers[LaneHeader].(string)
+	dl.Reason, _ = delivery.Headers[DeadLetterReasonHeader].(string)
+	return dl
+}
+
+// publishDeadLetter copies a delivery of the consumer's queue to the tenant's
+// dead letter queue, recording the tenant, lane and reason in its headers.
+func (c *Consumer) publishDeadLetter(delivery amqp.Delivery, reason string) error {
+	headers := amqp.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[TenantIDHeader] = c.tenantID
+	if c.lane != "" {
+		headers[LaneHeader] = c.lane
+	}
+	headers[DeadLetterReasonHeader] = reason
+
+	return c.channel.Publish("", DeadLetterQueueName(c.tenantID), false, false, amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		DeliveryMode: delivery.DeliveryMode,
+		Priority:     delivery.Priority,
+		MessageId:    delivery.MessageId,
+		Timestamp:    time.Now(),
+		Headers:      headers,
+		Body:         delivery.Body,
+	})
+}
+
+// DeadLetterConsumer consumes the dead letter queues of any number of tenants
+// on a single channel and hands their messages to one handler. Messages the
+// handler fails on go back to their queue.
+type DeadLetterConsumer struct {
+	rabbitmq *RabbitMQ
+	channel  *amqp.Channel
+	handler  func(DeadLetter) error
+	// retry spaces out redeliveries of dead letters the handler failed on,
+	// so a failing handler is not retried in a tight loop
+	retry  backoff.Backoff
+	mu     sync.Mutex
+	tags   map[string]string
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// ConsumeDeadLetters opens a DeadLetterConsumer. It consumes nothing until
+// tenants are subscribed. A dead letter the handler fails on is returned to
+// its queue after the retry backoff's delay, which grows while the handler
+// keeps failing.
+func (r *RabbitMQ) ConsumeDeadLetters(handler func(DeadLetter) error, retry backoff.Backoff) (*DeadLetterConsumer, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLetterConsumer{
+		rabbitmq: r,
+		channel:  ch,
+		handler:  handler,
+		retry:    retry,
+		tags:     make(map[string]string),
+	}, nil
+}
+
+// Subscribe starts consuming the tenant's dead letter queue, declaring it if
+// needed. Subscribing a tenant again has no effect.
+func (d *DeadLetterConsumer) Subscribe(tenantID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrConnectionClosed
+	}
+	if _, subscribed := d.tags[tenantID]; subscribed {
+		return nil
+	}
+
+	queue := DeadLetterQueueName(tenantID)
+	if _, err := d.channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter queue: %w", err)
+	}
+	tag := fmt.Sprintf("dead_letters_%s", tenantID)
+	deliveries, err := d.channel.Consume(queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume dead letter queue: %w", err)
+	}
+	d.tags[tenantID] = tag
+
+	d.wg.Add(1)
+	go d.handle(tenantID, deliveries)
+	return nil
+}
+
+// Unsubscribe stops consuming the tenant's dead letter queue. Messages in it
+// stay there.
+func (d *DeadLetterConsumer) Unsubscribe(tenantID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tag, subscribed := d.tags[tenantID]
+	if !subscribed || d.closed {
+		return
+	}
+	delete(d.tags, tenantID)
+	if err := d.channel.Cancel(tag, false); err != nil {
+		logging.Printf("Warning: failed to stop consuming dead letters of tenant %s: %v", tenantID, err)
+	}
+}
+
+// Close stops consuming every dead letter queue and releases the channel,
+// once the messages being handled are done.
+func (d *DeadLetterConsumer) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	// A channel lost with its connection, e.g. before a reconnect, has no
+	// consumers left to cancel
+	for tenantID, tag := range d.tags {
+		if d.channel.IsClosed() {
+			break
+		}
+		if err := d.channel.Cancel(tag, false); err != nil {
+			logging.Printf("Warning: failed to stop consuming dead letters of tenant %s: %v", tenantID, err)
+		}
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+	d.rabbitmq.closeChannel(d.channel)
+}
+
+func (d *DeadLetterConsumer) handle(tenantID string, deliveries <-chan amqp.Delivery) {
+	defer d.wg.Done()
+
+	// failures counts the handler's consecutive failures on this queue
+	failures := 0
+	for delivery := range deliveries {
+		dl := newDeadLetter(tenantID, delivery)
+		if err := d.handler(dl); err != nil {
+			logging.Printf("Failed to handle dead letter %s of tenant %s: %v", dl.MessageID, dl.TenantID, err)
+			d.retry.Sleep(failures)
+			failures++
+			delivery.Nack(false, true)
+			continue
+		}
+		failures = 0
+		if err := delivery.Ack(false); err != nil {
+			logging.Printf("Warning: failed to ack dead letter %s: %v", dl.MessageID, err)
+		}
+	}
+}
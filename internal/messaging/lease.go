@@ -0,0 +1,135 @@
+package messaging
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"jatis/internal/logging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Lease tracks a delivery until it is acked or nacked. AMQP has no native
+// visibility timeout, so a lease with a deadline requeues its delivery when
+// the deadline passes unsettled, letting another worker pick it up. Only the
+// first settlement of a lease reaches the broker.
+type Lease struct {
+	delivery amqp.Delivery
+	mu       sync.Mutex
+	settled  bool
+	timer    *time.Timer
+	// deadLetter publishes the delivery to its dead letter queue; set for
+	// leases handed out by a Consumer
+	deadLetter func(delivery amqp.Delivery, reason string) error
+	// acks batches the delivery's ack with others from its consumer; nil
+	// acks it on its own
+	acks *ackBatcher
+}
+
+// NewLease starts tracking delivery. A zero timeout disables the deadline.
+func NewLease(delivery amqp.Delivery, timeout time.Duration) *Lease {
+	l := &Lease{delivery: delivery}
+	if timeout > 0 {
+		l.timer = time.AfterFunc(timeout, l.expire)
+	}
+	return l
+}
+
+// Delivery returns the leased delivery.
+func (l *Lease) Delivery() amqp.Delivery {
+	return l.delivery
+}
+
+// Ack acknowledges the delivery. It returns false if the lease was already
+// settled, for example because its deadline passed and it was requeued.
+func (l *Lease) Ack() bool {
+	return l.settle(l.ack)
+}
+
+// Nack rejects the delivery, requeueing it or sending it to the dead letter
+// queue. It returns false if the lease was already settled.
+func (l *Lease) Nack(requeue bool) bool {
+	return l.settle(func() error { return l.nack(requeue) })
+}
+
+// DeadLetter moves the delivery to its tenant's dead letter queue with reason
+// recorded, and acknowledges it. If the dead letter cannot be published the
+// delivery is requeued rather than lost. Leases not handed out by a Consumer
+// are nacked without requeueing instead. It returns false if the lease was
+// already settled.
+func (l *Lease) DeadLetter(reason string) bool {
+	if l.deadLetter == nil {
+		return l.Nack(false)
+	}
+	return l.settle(func() error {
+		if err := l.deadLetter(l.delivery, reason); err != nil {
+			l.nack(true)
+			return fmt.Errorf("failed to dead-letter, requeued: %w", err)
+		}
+		return l.ack()
+	})
+}
+
+// Extend moves the deadline to d from now, for handlers that need more time.
+// It returns false if the lease was already settled.
+func (l *Lease) Extend(d time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.settled {
+		return false
+	}
+	if l.timer == nil {
+		l.timer = time.AfterFunc(d, l.expire)
+		return true
+	}
+	return l.timer.Reset(d)
+}
+
+// Abandon stops tracking the delivery without settling it. It is meant for
+// deliveries whose channel has gone away, which the broker redelivers on its
+// own. It returns false if the lease was already settled.
+func (l *Lease) Abandon() bool {
+	return l.settle(func() error { return nil })
+}
+
+func (l *Lease) ack() error {
+	if l.acks != nil {
+		return l.acks.ack(l.delivery.DeliveryTag)
+	}
+	return l.delivery.Ack(false)
+}
+
+func (l *Lease) nack(requeue bool) error {
+	err := l.delivery.Nack(false, requeue)
+	if l.acks != nil {
+		l.acks.settled(l.delivery.DeliveryTag)
+	}
+	return err
+}
+
+func (l *Lease) expire() {
+	if l.Nack(true) {
+		log.Printf("Message %s was not acknowledged before its deadline, requeued for redelivery", l.delivery.MessageId)
+	}
+}
+
+func (l *Lease) settle(fn func() error) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.settled {
+		return false
+	}
+	l.settled = true
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+
+	if err := fn(); err != nil {
+		logging.Printf("Warning: failed to settle message %s: %v", l.delivery.MessageId, err)
+	}
+	return true
+}
@@ -1,60 +1,262 @@
 package messaging
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"jatis/internal/logging"
+	"jatis/internal/metrics"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// CorrelationIDHeader is the AMQP header carrying a message's correlation ID.
+const CorrelationIDHeader = "x-correlation-id"
+
+// Envelope holds the identifiers published alongside a message payload.
+type Envelope struct {
+	MessageID     string
+	CorrelationID string
+	// Lane routes the message to one of the tenant's lane queues instead of
+	// its main queue.
+	Lane string
+	// Transient publishes the message without persisting it, for tenants
+	// whose queues are transient. Messages are persistent by default.
+	Transient bool
+}
+
+// QueueName returns the name of a tenant's queue. An empty lane names the
+// tenant's main queue.
+func QueueName(tenantID, lane string) string {
+	if lane == "" {
+		return fmt.Sprintf("tenant_%s_queue", tenantID)
+	}
+	return fmt.Sprintf("tenant_%s_lane_%s_queue", tenantID, lane)
+}
+
+// CorrelationID returns the correlation ID header of a delivery, if any.
+func CorrelationID(delivery amqp.Delivery) string {
+	id, _ := delivery.Headers[CorrelationIDHeader].(string)
+	return id
+}
+
+// ErrConnectionClosed is returned when a channel is requested on a connection
+// that is closed or in the middle of closing.
+var ErrConnectionClosed = errors.New("rabbitmq connection is closed")
+
+// ErrQueueNotFound is returned when inspecting a queue the broker does not
+// have.
+var ErrQueueNotFound = errors.New("queue not found")
+
 type RabbitMQ struct {
-	conn *amqp.Connection
+	url string
+	// connectionName labels the connection in the broker's management UI
+	connectionName string
+	// managementURL is the base URL of the broker's management API, if set
+	managementURL string
+	// consumerPriority is the x-priority consumers are registered with; zero
+	// leaves it unset
+	consumerPriority int
+	// onPublish holds the functions OnPublish registered, guarded by mu
+	onPublish      []func(tenantID string)
+	mu             sync.RWMutex
+	conn           *amqp.Connection
+	openChannels   int64
 }
 
 type Consumer struct {
+	rabbitmq   *RabbitMQ
 	channel    *amqp.Channel
 	queue      amqp.Queue
 	deliveries <-chan amqp.Delivery
 	done       chan bool
+	// finished is closed once the delivery loop started by Start returns
+	finished   chan struct{}
 	tag        string
+	// tenantID and lane identify the queue, for dead-lettering
+	tenantID   string
+	lane       string
+	// acks batches acknowledgements; nil acks each delivery on its own
+	acks       *ackBatcher
+	stopOnce   sync.Once
+	cancelOnce sync.Once
+	cancelErr  error
+}
+
+// NewRabbitMQ connects to the broker under connectionName, which the
+// management UI lists the connection by. An empty name uses the hostname.
+func NewRabbitMQ(url, connectionName string) (*RabbitMQ, error) {
+	if connectionName == "" {
+		connectionName, _ = os.Hostname()
+	}
+	r := &RabbitMQ{url: url, connectionName: connectionName}
+
+	conn, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	r.conn = conn
+	return r, nil
 }
 
-func NewRabbitMQ(url string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(url)
+func (r *RabbitMQ) dial() (*amqp.Connection, error) {
+	properties := amqp.NewConnectionProperties()
+	if r.connectionName != "" {
+		properties["connection_name"] = r.connectionName
+	}
+	conn, err := amqp.DialConfig(r.url, amqp.Config{
+		Heartbeat:  10 * time.Second,
+		Locale:     "en_US",
+		Properties: properties,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
+	return conn, nil
+}
 
-	return &RabbitMQ{conn: conn}, nil
+// SetConsumerPriority makes the consumers r registers from now on consume
+// with the given x-priority. The broker sends a queue's messages to its
+// highest-priority consumers that have capacity, and to lower ones only when
+// those are full or gone. It must be called before r is shared.
+func (r *RabbitMQ) SetConsumerPriority(priority int) {
+	r.consumerPriority = priority
+}
+
+// ConnectionName returns the name the connection is opened under.
+func (r *RabbitMQ) ConnectionName() string {
+	return r.connectionName
 }
 
 func (r *RabbitMQ) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.conn.Close()
 }
 
-func (r *RabbitMQ) CreateTenantQueue(tenantID string) (*Consumer, error) {
-	ch, err := r.conn.Channel()
+// Reconnect dials the broker again and swaps in the new connection, closing
+// the old one. Channels and consumers opened on the old connection are dead
+// afterwards and must be recreated by their owners.
+func (r *RabbitMQ) Reconnect() error {
+	conn, err := r.dial()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.conn
+	r.conn = conn
+	r.mu.Unlock()
+
+	if !old.IsClosed() {
+		old.Close()
+	}
+	return nil
+}
+
+// IsClosed reports whether the current connection to the broker is closed.
+func (r *RabbitMQ) IsClosed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.conn.IsClosed()
+}
+
+// OpenChannels returns the number of channels currently held open by this
+// connection, including consumer channels.
+func (r *RabbitMQ) OpenChannels() int64 {
+	return atomic.LoadInt64(&r.openChannels)
+}
+
+// openChannel opens a tracked channel. Every channel returned here must be
+// released with closeChannel, on success and error paths alike.
+func (r *RabbitMQ) openChannel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+
+	if conn.IsClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	ch, err := conn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
-	queueName := fmt.Sprintf("tenant_%s_queue", tenantID)
+	metrics.SetOpenChannels(float64(atomic.AddInt64(&r.openChannels, 1)))
+	return ch, nil
+}
+
+// closeChannel closes a channel obtained from openChannel. The open channel
+// count is decremented even if the broker already closed the channel.
+func (r *RabbitMQ) closeChannel(ch *amqp.Channel) error {
+	defer metrics.SetOpenChannels(float64(atomic.AddInt64(&r.openChannels, -1)))
+	return ch.Close()
+}
+
+// QueueOptions are the arguments a tenant's queues are declared with. RabbitMQ
+// refuses to redeclare an existing queue with different arguments, so they
+// cannot change over a queue's lifetime.
+type QueueOptions struct {
+	// SingleActiveConsumer lets only one consumer receive from the queue at a
+	// time. The others stay idle and the broker fails over to one of them
+	// when the active consumer goes away.
+	SingleActiveConsumer bool
+	// Transient declares the queues non-durable, so they are gone after the
+	// broker restarts, along with their messages. The dead letter queue stays
+	// durable.
+	Transient bool
+}
+
+func (o QueueOptions) arguments() amqp.Table {
+	if !o.SingleActiveConsumer {
+		return nil
+	}
+	return amqp.Table{"x-single-active-consumer": true}
+}
+
+// consumeArguments are the arguments consumers are registered with.
+func (r *RabbitMQ) consumeArguments() amqp.Table {
+	if r.consumerPriority == 0 {
+		return nil
+	}
+	return amqp.Table{"x-priority": int32(r.consumerPriority)}
+}
+
+func (r *RabbitMQ) CreateTenantQueue(tenantID string) (*Consumer, error) {
+	return r.CreateQueue(tenantID, "", QueueOptions{})
+}
+
+// CreateQueue declares the tenant's main queue, or one of its lane queues,
+// and starts consuming it. Lanes share the tenant's dead letter queue.
+func (r *RabbitMQ) CreateQueue(tenantID, lane string, opts QueueOptions) (*Consumer, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	queueName := QueueName(tenantID, lane)
 	
 	queue, err := ch.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
+		queueName,        // name
+		!opts.Transient,  // durable
+		false,            // delete when unused
+		false,            // exclusive
+		false,            // no-wait
+		opts.arguments(), // arguments
 	)
 	if err != nil {
-		ch.Close()
+		r.closeChannel(ch)
 		return nil, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
 	// Create dead letter queue for failed messages
-	dlqName := fmt.Sprintf("tenant_%s_dlq", tenantID)
+	dlqName := DeadLetterQueueName(tenantID)
 	_, err = ch.QueueDeclare(
 		dlqName,
 		true,
@@ -64,43 +266,52 @@ func (r *RabbitMQ) CreateTenantQueue(tenantID string) (*Consumer, error) {
 		nil,
 	)
 	if err != nil {
-		ch.Close()
+		r.closeChannel(ch)
 		return nil, fmt.Errorf("failed to declare dead letter queue: %w", err)
 	}
 
 	consumerTag := fmt.Sprintf("consumer_%s", tenantID)
+	if lane != "" {
+		consumerTag = fmt.Sprintf("consumer_%s_%s", tenantID, lane)
+	}
 	deliveries, err := ch.Consume(
-		queue.Name,  // queue
-		consumerTag, // consumer
-		false,       // auto-ack
-		false,       // exclusive
-		false,       // no-local
-		false,       // no-wait
-		nil,         // args
+		queue.Name,           // queue
+		consumerTag,          // consumer
+		false,                // auto-ack
+		false,                // exclusive
+		false,                // no-local
+		false,                // no-wait
+		r.consumeArguments(), // args
 	)
 	if err != nil {
-		ch.Close()
+		r.closeChannel(ch)
 		return nil, fmt.Errorf("failed to register consumer: %w", err)
 	}
 
 	return &Consumer{
+		rabbitmq:   r,
 		channel:    ch,
 		queue:      queue,
 		deliveries: deliveries,
 		done:       make(chan bool),
+		finished:   make(chan struct{}),
 		tag:        consumerTag,
+		tenantID:   tenantID,
+		lane:       lane,
 	}, nil
 }
 
-func (r *RabbitMQ) DeleteTenantQueue(tenantID string) error {
-	ch, err := r.conn.Channel()
+// DeleteTenantQueue deletes the tenant's main queue, its dead letter queue
+// and the queues of the given lanes.
+func (r *RabbitMQ) DeleteTenantQueue(tenantID string, lanes ...string) error {
+	ch, err := r.openChannel()
 	if err != nil {
-		return fmt.Errorf("failed to open channel: %w", err)
+		return err
 	}
-	defer ch.Close()
+	defer r.closeChannel(ch)
 
-	queueName := fmt.Sprintf("tenant_%s_queue", tenantID)
-	dlqName := fmt.Sprintf("tenant_%s_dlq", tenantID)
+	queueName := QueueName(tenantID, "")
+	dlqName := DeadLetterQueueName(tenantID)
 
 	// Delete main queue
 	_, err = ch.QueueDelete(queueName, false, false, false)
@@ -114,17 +325,54 @@ func (r *RabbitMQ) DeleteTenantQueue(tenantID string) error {
 		log.Printf("Warning: failed to delete DLQ %s: %v", dlqName, err)
 	}
 
+	for _, lane := range lanes {
+		laneQueue := QueueName(tenantID, lane)
+		if _, err := ch.QueueDelete(laneQueue, false, false, false); err != nil {
+			log.Printf("Warning: failed to delete lane queue %s: %v", laneQueue, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteLaneQueue deletes a lane queue if it holds no messages. A queue that
+// still has messages is kept, so re-adding the lane picks them up again.
+func (r *RabbitMQ) DeleteLaneQueue(tenantID, lane string) error {
+	ch, err := r.openChannel()
+	if err != nil {
+		return err
+	}
+	defer r.closeChannel(ch)
+
+	if _, err := ch.QueueDelete(QueueName(tenantID, lane), false, true, false); err != nil {
+		return fmt.Errorf("failed to delete lane queue: %w", err)
+	}
 	return nil
 }
 
 func (r *RabbitMQ) PublishMessage(tenantID string, payload []byte) error {
-	ch, err := r.conn.Channel()
+	return r.Publish(tenantID, payload, Envelope{})
+}
+
+// Publish sends payload to the tenant's queue, or to env.Lane's queue, along
+// with the identifiers in env.
+func (r *RabbitMQ) Publish(tenantID string, payload []byte, env Envelope) error {
+	ch, err := r.openChannel()
 	if err != nil {
-		return fmt.Errorf("failed to open channel: %w", err)
+		return err
 	}
-	defer ch.Close()
+	defer r.closeChannel(ch)
 
-	queueName := fmt.Sprintf("tenant_%s_queue", tenantID)
+	queueName := QueueName(tenantID, env.Lane)
+
+	var headers amqp.Table
+	if env.CorrelationID != "" {
+		headers = amqp.Table{CorrelationIDHeader: env.CorrelationID}
+	}
+	deliveryMode := amqp.Persistent
+	if env.Transient {
+		deliveryMode = amqp.Transient
+	}
 
 	err = ch.Publish(
 		"",        // exchange
@@ -132,27 +380,71 @@ func (r *RabbitMQ) PublishMessage(tenantID string, payload []byte) error {
 		false,     // mandatory
 		false,     // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        payload,
+			ContentType:  "application/json",
+			DeliveryMode: deliveryMode,
+			MessageId:    env.MessageID,
+			Headers:      headers,
+			Body:         payload,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
+	r.mu.RLock()
+	hooks := r.onPublish
+	r.mu.RUnlock()
+	for _, hook := range hooks {
+		hook(tenantID)
+	}
 	return nil
 }
 
-func (c *Consumer) Start(handler func([]byte) error) {
+// OnPublish registers fn to be called with the tenant of every message
+// published through r, once the broker has it. fn runs on the publishing
+// goroutine, so it must not block.
+func (r *RabbitMQ) OnPublish(fn func(tenantID string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPublish = append(r.onPublish, fn)
+}
+
+// BatchAcks acknowledges deliveries size at a time with a single
+// multiple-ack instead of one ack each, and acks an incomplete batch every
+// interval (zero waits for the batch to fill). It must be called before
+// Start; a size of 1 or less keeps individual acks. Acks still waiting in a
+// batch are lost if the connection drops, and the broker redelivers those
+// messages, so a larger batch trades more duplicates on a crash for fewer
+// round trips.
+func (c *Consumer) BatchAcks(size int, interval time.Duration) {
+	if size <= 1 {
+		return
+	}
+	c.acks = newAckBatcher(c.channel, size)
+	if interval > 0 {
+		go c.acks.flushWorker(interval, c.done)
+	}
+}
+
+// Start hands each delivery to handler wrapped in a Lease. The handler takes
+// ownership of the lease and must Ack or Nack it; deliveries still unsettled
+// after visibilityTimeout are requeued for redelivery. A handler error nacks
+// the delivery straight away. A zero visibilityTimeout disables the deadline.
+func (c *Consumer) Start(visibilityTimeout time.Duration, handler func(*Lease) error) {
 	go func() {
+		defer close(c.finished)
 		for {
 			select {
-			case delivery := <-c.deliveries:
-				if err := handler(delivery.Body); err != nil {
-					log.Printf("Failed to process message: %v", err)
-					delivery.Nack(false, false) // Send to DLQ
-				} else {
-					delivery.Ack(false)
+			case delivery, ok := <-c.deliveries:
+				if !ok {
+					return
+				}
+				lease := NewLease(delivery, visibilityTimeout)
+				lease.deadLetter = c.publishDeadLetter
+				lease.acks = c.acks
+				if err := handler(lease); err != nil {
+					logging.Printf("Failed to process message: %v", err)
+					lease.DeadLetter(err.Error())
 				}
 			case <-c.done:
 				return
@@ -161,13 +453,79 @@ func (c *Consumer) Start(handler func([]byte) error) {
 	}()
 }
 
-func (c *Consumer) Stop() error {
-	close(c.done)
-	
-	// Cancel consumer
-	if err := c.channel.Cancel(c.tag, false); err != nil {
-		log.Printf("Warning: failed to cancel consumer: %v", err)
+// Cancel stops the broker from sending the consumer new deliveries but keeps
+// its channel open, so deliveries already handed out can still be acked or
+// nacked. It returns once the deliveries the consumer had buffered have been
+// passed to the handler given to Start, which it must be called after. Stop
+// must still be called to release the channel.
+func (c *Consumer) Cancel() error {
+	if err := c.cancel(); err != nil {
+		return err
+	}
+	select {
+	case <-c.finished:
+	case <-c.done:
+	}
+	return nil
+}
+
+func (c *Consumer) cancel() error {
+	c.cancelOnce.Do(func() {
+		c.cancelErr = c.channel.Cancel(c.tag, false)
+	})
+	return c.cancelErr
+}
+
+// QueueStats is a queue's ready messages and subscribed consumers as
+// reported by the broker.
+type QueueStats struct {
+	Messages  int
+	Consumers int
+}
+
+// InspectQueue returns the stats of the tenant's main queue, or of one of its
+// lane queues, without declaring it.
+func (r *RabbitMQ) InspectQueue(tenantID, lane string) (QueueStats, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return QueueStats{}, err
 	}
+	defer r.closeChannel(ch)
+
+	queue, err := ch.QueueDeclarePassive(QueueName(tenantID, lane), true, false, false, false, nil)
+	if err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return QueueStats{}, ErrQueueNotFound
+		}
+		return QueueStats{}, fmt.Errorf("failed to inspect queue: %w", err)
+	}
+	return QueueStats{Messages: queue.Messages, Consumers: queue.Consumers}, nil
+}
+
+// ConsumerCount returns the number of consumers subscribed to the tenant's
+// main queue, or to one of its lane queues.
+func (r *RabbitMQ) ConsumerCount(tenantID, lane string) (int, error) {
+	stats, err := r.InspectQueue(tenantID, lane)
+	return stats.Consumers, err
+}
+
+// Stop cancels the consumer and releases its channel. It is safe to call
+// more than once; only the first call has any effect.
+func (c *Consumer) Stop() error {
+	var err error
+	c.stopOnce.Do(func() {
+		close(c.done)
+
+		// Cancel consumer
+		if cancelErr := c.cancel(); cancelErr != nil {
+			logging.Printf("Warning: failed to cancel consumer: %v", cancelErr)
+		}
+		if c.acks != nil {
+			c.acks.flushAll()
+		}
 
-	return c.channel.Close()
+		err = c.rabbitmq.closeChannel(c.channel)
+	})
+	return err
 }
\ No newline at end of file
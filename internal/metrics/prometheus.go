@@ -1,10 +1,13 @@
 package metrics
 
 import (
+	"database/sql"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -35,13 +38,132 @@ var (
 		},
 	)
 
+	// Per-tenant metrics, built by buildTenantMetrics
+	messagesProcessed   *prometheus.CounterVec
+	messagesDelivered   *prometheus.CounterVec
+	messagesRedelivered *prometheus.CounterVec
+	consumerRestarts    *prometheus.CounterVec
+	messageQueueDepth   *prometheus.GaugeVec
+	messagePayloadBytes *prometheus.HistogramVec
+	activeWorkers       *prometheus.GaugeVec
+
+	// Database connection pool metrics
+	dbOpenConnections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_open_connections",
+			Help: "Number of established database connections, in use and idle",
+		},
+	)
+
+	dbInUseConnections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_in_use_connections",
+			Help: "Number of database connections currently in use",
+		},
+	)
+
+	dbIdleConnections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_idle_connections",
+			Help: "Number of idle database connections",
+		},
+	)
+
+	dbWaitCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_wait_count",
+			Help: "Total number of connections waited for",
+		},
+	)
+
+	dbWaitDuration = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_wait_duration_seconds",
+			Help: "Total time blocked waiting for a new connection in seconds",
+		},
+	)
+
+	// RabbitMQ metrics
+	rabbitmqOpenChannels = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "rabbitmq_open_channels",
+			Help: "Number of AMQP channels currently open",
+		},
+	)
+
+	// Throughput metrics
+	throughputUtilization = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "throughput_utilization",
+			Help: "Share of throughput.max_per_second used over the last second, from 0 to 1",
+		},
+		func() float64 {
+			if source, ok := throughputSource.Load().(func() float64); ok {
+				return source()
+			}
+			return 0
+		},
+	)
+	// throughputSource holds the function throughputUtilization reads
+	throughputSource atomic.Value
+)
+
+// tenantLabel partitions the per-tenant metrics.
+const tenantLabel = "tenant_id"
+
+// OtherTenants is the tenant_id that tenants outside the allowlist set by
+// ConfigureTenantLabels are counted under.
+const OtherTenants = "other"
+
+var (
+	// perTenant is whether the per-tenant metrics carry the tenant_id label
+	perTenant = true
+	// tenantAllowlist, if not empty, holds the only tenants given their own
+	// tenant_id; the others are counted under OtherTenants
+	tenantAllowlist map[string]bool
+)
+
+// buildTenantMetrics creates the per-tenant metrics, with or without the
+// tenant_id label.
+func buildTenantMetrics() {
+	labels := func(names ...string) []string {
+		if perTenant {
+			return append([]string{tenantLabel}, names...)
+		}
+		return names
+	}
+
 	// Message metrics
 	messagesProcessed = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "messages_processed_total",
 			Help: "Total number of messages processed",
 		},
-		[]string{"tenant_id", "status"},
+		labels("status"),
+	)
+
+	messagesDelivered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_delivered_total",
+			Help: "Total number of messages delivered to consumers, redeliveries included",
+		},
+		labels(),
+	)
+
+	messagesRedelivered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_redelivered_total",
+			Help: "Total number of messages delivered again after a requeue, lost channel or expired deadline",
+		},
+		labels(),
+	)
+
+	consumerRestarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "consumer_restarts_total",
+			Help: "Total number of times a tenant's consumer was recreated after the broker connection was lost",
+		},
+		labels(),
 	)
 
 	messageQueueDepth = prometheus.NewGaugeVec(
@@ -49,7 +171,16 @@ var (
 			Name: "message_queue_depth",
 			Help: "Current depth of message queues",
 		},
-		[]string{"tenant_id"},
+		labels(),
+	)
+
+	messagePayloadBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "message_payload_bytes",
+			Help: "Size of accepted message payloads in bytes, as stored after transforms",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8), // 64B to 1MiB
+		},
+		labels(),
 	)
 
 	// Worker metrics
@@ -58,18 +189,72 @@ var (
 			Name: "active_workers_total",
 			Help: "Number of active workers per tenant",
 		},
-		[]string{"tenant_id"},
+		labels(),
 	)
-)
+}
+
+// tenantMetrics collects the per-tenant metrics. It is registered as an
+// unchecked collector, describing nothing, because a registry does not allow
+// a metric to be registered again with different labels, which
+// ConfigureTenantLabels needs.
+type tenantMetrics struct{}
+
+func (tenantMetrics) Describe(chan<- *prometheus.Desc) {}
+
+func (tenantMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range []prometheus.Collector{
+		messagesProcessed, messagesDelivered, messagesRedelivered, consumerRestarts,
+		messageQueueDepth, messagePayloadBytes, activeWorkers,
+	} {
+		c.Collect(ch)
+	}
+}
+
+// ConfigureTenantLabels controls how the per-tenant metrics are labeled, to
+// bound their cardinality when there are many tenants. Unless enabled, they
+// carry no tenant_id and aggregate every tenant. Otherwise, if allowlist
+// is not empty, only the listed tenants get their own tenant_id and the rest
+// share OtherTenants. The metrics are recreated, so values recorded before
+// are dropped; call it at startup, before any are recorded.
+func ConfigureTenantLabels(enabled bool, allowlist []string) {
+	perTenant = enabled
+	tenantAllowlist = nil
+	if len(allowlist) > 0 {
+		tenantAllowlist = make(map[string]bool, len(allowlist))
+		for _, tenantID := range allowlist {
+			tenantAllowlist[tenantID] = true
+		}
+	}
+
+	buildTenantMetrics()
+}
+
+// tenantLabelValues returns the label values of a per-tenant metric: the
+// tenant's tenant_id, if labels are enabled, followed by values.
+func tenantLabelValues(tenantID string, values ...string) []string {
+	if !perTenant {
+		return values
+	}
+	if tenantAllowlist != nil && !tenantAllowlist[tenantID] {
+		tenantID = OtherTenants
+	}
+	return append([]string{tenantID}, values...)
+}
 
 func init() {
 	// Register metrics
 	prometheus.MustRegister(httpRequestsTotal)
 	prometheus.MustRegister(httpRequestDuration)
 	prometheus.MustRegister(activeTenants)
-	prometheus.MustRegister(messagesProcessed)
-	prometheus.MustRegister(messageQueueDepth)
-	prometheus.MustRegister(activeWorkers)
+	buildTenantMetrics()
+	prometheus.MustRegister(tenantMetrics{})
+	prometheus.MustRegister(dbOpenConnections)
+	prometheus.MustRegister(dbInUseConnections)
+	prometheus.MustRegister(dbIdleConnections)
+	prometheus.MustRegister(dbWaitCount)
+	prometheus.MustRegister(dbWaitDuration)
+	prometheus.MustRegister(rabbitmqOpenChannels)
+	prometheus.MustRegister(throughputUtilization)
 }
 
 // PrometheusMiddleware creates a Gin middleware for Prometheus metrics
@@ -92,6 +277,32 @@ func MetricsHandler() gin.HandlerFunc {
 	return gin.WrapH(promhttp.Handler())
 }
 
+// StartDBStatsCollector copies the connection pool stats of db into the
+// db_* gauges every interval until the returned stop function is called.
+// An interval of zero or less collects nothing.
+func StartDBStatsCollector(db *sql.DB, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			RecordDBStats(db.Stats())
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
 // Metric update functions
 func IncrementActiveTenants() {
 	activeTenants.Inc()
@@ -102,13 +313,61 @@ func DecrementActiveTenants() {
 }
 
 func IncrementMessagesProcessed(tenantID, status string) {
-	messagesProcessed.WithLabelValues(tenantID, status).Inc()
+	messagesProcessed.WithLabelValues(tenantLabelValues(tenantID, status)...).Inc()
+}
+
+// RecordDelivery counts a delivery to a tenant's consumer, and whether the
+// broker flagged it as a redelivery.
+func RecordDelivery(tenantID string, redelivered bool) {
+	messagesDelivered.WithLabelValues(tenantLabelValues(tenantID)...).Inc()
+	if redelivered {
+		messagesRedelivered.WithLabelValues(tenantLabelValues(tenantID)...).Inc()
+	}
+}
+
+// RecordConsumerRestart counts a restart of a tenant's consumer after the
+// broker connection was lost.
+func RecordConsumerRestart(tenantID string) {
+	consumerRestarts.WithLabelValues(tenantLabelValues(tenantID)...).Inc()
 }
 
 func SetMessageQueueDepth(tenantID string, depth float64) {
-	messageQueueDepth.WithLabelValues(tenantID).Set(depth)
+	messageQueueDepth.WithLabelValues(tenantLabelValues(tenantID)...).Set(depth)
+}
+
+// ObservePayloadSize records the size of a payload accepted for a tenant.
+func ObservePayloadSize(tenantID string, bytes int) {
+	messagePayloadBytes.WithLabelValues(tenantLabelValues(tenantID)...).Observe(float64(bytes))
+}
+
+// ForgetPayloadSizes drops a deleted tenant's payload size histogram, whose
+// buckets would otherwise be exported for as long as the process runs.
+// Aggregated histograms are kept, as they cover other tenants too.
+func ForgetPayloadSizes(tenantID string) {
+	if !perTenant || (tenantAllowlist != nil && !tenantAllowlist[tenantID]) {
+		return
+	}
+	messagePayloadBytes.DeleteLabelValues(tenantID)
 }
 
 func SetActiveWorkers(tenantID string, workers float64) {
-	activeWorkers.WithLabelValues(tenantID).Set(workers)
+	activeWorkers.WithLabelValues(tenantLabelValues(tenantID)...).Set(workers)
+}
+
+// SetThroughputUtilizationSource sets the function throughput_utilization
+// is read from when scraped.
+func SetThroughputUtilizationSource(source func() float64) {
+	throughputSource.Store(source)
+}
+
+func SetOpenChannels(channels float64) {
+	rabbitmqOpenChannels.Set(channels)
+}
+
+func RecordDBStats(stats sql.DBStats) {
+	dbOpenConnections.Set(float64(stats.OpenConnections))
+	dbInUseConnections.Set(float64(stats.InUse))
+	dbIdleConnections.Set(float64(stats.Idle))
+	dbWaitCount.Set(float64(stats.WaitCount))
+	dbWaitDuration.Set(stats.WaitDuration.Seconds())
 }
\ No newline at end of file
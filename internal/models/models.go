@@ -7,45 +7,669 @@ import (
 type Tenant struct {
 	ID        string    `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name"`
+	Status    string    `json:"status" db:"status"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type Message struct {
-	ID        string      `json:"id" db:"id"`
-	TenantID  string      `json:"tenant_id" db:"tenant_id"`
-	Payload   interface{} `json:"payload" db:"payload" swaggertype:"object"`
-	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	ID            string                 `json:"id" db:"id"`
+	TenantID      string                 `json:"tenant_id" db:"tenant_id"`
+	Payload       interface{}            `json:"payload" db:"payload" swaggertype:"object"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
+	CorrelationID string                 `json:"correlation_id,omitempty" db:"correlation_id"`
+	Lane          string                 `json:"lane,omitempty" db:"lane"`
+	Status        string                 `json:"status" db:"status"`
+	CreatedAt     time.Time              `json:"created_at" db:"created_at"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty" db:"expires_at"`
 }
 
+// ReprocessResult reports a reprocessing run over a tenant's messages.
+// Reprocessed messages were republished; Failed ones could not be and were
+// left processed.
+type ReprocessResult struct {
+	TenantID    string    `json:"tenant_id"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Reprocessed int       `json:"reprocessed"`
+	Failed      int       `json:"failed"`
+}
+
+// PurgeResult reports how many of a tenant's messages a filtered delete
+// removed.
+type PurgeResult struct {
+	TenantID string `json:"tenant_id"`
+	Deleted  int64  `json:"deleted"`
+}
+
+// MoveMessagesRequest names the tenant messages are moved to. Filters are
+// payload filters as accepted by DELETE /tenants/{id}/messages; none moves
+// every message.
+type MoveMessagesRequest struct {
+	TargetTenantID string   `json:"target_tenant_id" binding:"required"`
+	Filters        []string `json:"filters"`
+}
+
+// MoveResult reports how many messages were moved between tenants, and how
+// many matching pending messages were left with the source.
+type MoveResult struct {
+	SourceTenantID string `json:"source_tenant_id"`
+	TargetTenantID string `json:"target_tenant_id"`
+	Moved          int64  `json:"moved"`
+	SkippedPending int64  `json:"skipped_pending"`
+}
+
+// MessageSearchHit is a message matching a text search, with its relevance.
+type MessageSearchHit struct {
+	Message
+	// Rank is the message's ts_rank for the query; higher is more relevant
+	Rank float64 `json:"rank"`
+}
+
+// MessageSearchResult lists the messages matching a text search, most
+// relevant first.
+type MessageSearchResult struct {
+	Query string             `json:"query"`
+	Data  []MessageSearchHit `json:"data"`
+}
+
+// RawPayload is a message's payload as it was received, before the tenant's
+// transforms were applied.
+type RawPayload struct {
+	ID       string      `json:"id"`
+	TenantID string      `json:"tenant_id"`
+	Payload  interface{} `json:"payload" swaggertype:"object"`
+}
+
+// Tenant statuses. A tenant is draining while a drained delete waits for
+// its backlog to be processed.
+const (
+	TenantStatusActive   = "active"
+	TenantStatusDraining = "draining"
+)
+
+// Message statuses
+const (
+	MessageStatusPending   = "pending"
+	MessageStatusProcessed = "processed"
+	MessageStatusFailed    = "failed"
+	// MessageStatusSampledOut marks a message acked without processing
+	// because its tenant's sample_rate left it out
+	MessageStatusSampledOut = "sampled_out"
+)
+
+// ValidMessageStatus reports whether status is a known message status.
+func ValidMessageStatus(status string) bool {
+	switch status {
+	case MessageStatusPending, MessageStatusProcessed, MessageStatusFailed, MessageStatusSampledOut:
+		return true
+	}
+	return false
+}
+
+// Failure policies decide what happens to a message whose processing fails.
+const (
+	FailurePolicyDLQ          = "dlq"            // dead-letter on the first failure
+	FailurePolicyRetry        = "retry"          // requeue until it succeeds
+	FailurePolicyDrop         = "drop"           // ack and discard
+	FailurePolicyRetryThenDLQ = "retry_then_dlq" // requeue up to consumer.max_attempts, then dead-letter
+)
+
 type TenantConfig struct {
-	TenantID  string    `json:"tenant_id" db:"tenant_id"`
-	Workers   int       `json:"workers" db:"workers"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	TenantID             string         `json:"tenant_id" db:"tenant_id"`
+	Workers              int            `json:"workers" db:"workers"`
+	DedicatedPool        bool           `json:"dedicated_pool" db:"dedicated_pool"`
+	FailurePolicy        string         `json:"failure_policy" db:"failure_policy"`
+	SingleActiveConsumer bool           `json:"single_active_consumer" db:"single_active_consumer"`
+	Weight               int            `json:"weight" db:"weight"`
+	MaxConcurrency       int            `json:"max_concurrency" db:"max_concurrency"`
+	Transforms           []string       `json:"transforms" db:"transforms"`
+	KeepRawPayload       bool           `json:"keep_raw_payload" db:"keep_raw_payload"`
+	SampleRate           float64        `json:"sample_rate" db:"sample_rate"`
+	AllowEmptyPayload    bool           `json:"allow_empty_payload" db:"allow_empty_payload"`
+	WorkerSchedule       []WorkerWindow `json:"worker_schedule" db:"worker_schedule"`
+	PayloadSchema        *PayloadSchema `json:"payload_schema,omitempty" db:"payload_schema"`
+	Transient            bool           `json:"transient" db:"transient"`
+	UpdatedAt            time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WorkerWindow sets a tenant's worker count for a daily time window, from
+// Start up to End as "HH:MM" in UTC. A window whose End is not after its
+// Start runs past midnight. Days limits the window to the weekdays it starts
+// on, "mon" to "sun"; empty means every day.
+type WorkerWindow struct {
+	Start   string   `json:"start" binding:"required"`
+	End     string   `json:"end" binding:"required"`
+	Days    []string `json:"days,omitempty"`
+	Workers int      `json:"workers" binding:"required,min=1"`
+}
+
+// TenantConfigHistory is a snapshot of a tenant's configuration taken after
+// a change.
+type TenantConfigHistory struct {
+	ID        int64                  `json:"id" db:"id"`
+	TenantID  string                 `json:"tenant_id" db:"tenant_id"`
+	Config    map[string]interface{} `json:"config" db:"config"`
+	ChangedAt time.Time              `json:"changed_at" db:"changed_at"`
 }
 
+// How MessageStats were counted: exactly, by scanning the tenant's messages,
+// or approximately, from counters and planner estimates.
+const (
+	StatsModeExact       = "exact"
+	StatsModeApproximate = "approximate"
+)
+
 type MessageStats struct {
-	TotalMessages int64 `json:"total_messages"`
-	Messages24h   int64 `json:"messages_24h"`
-	Messages1h    int64 `json:"messages_1h"`
+	// Mode is how TotalMessages, Messages24h and Messages1h were counted
+	Mode          string `json:"mode"`
+	TotalMessages int64  `json:"total_messages"`
+	Messages24h   int64  `json:"messages_24h"`
+	Messages1h    int64  `json:"messages_1h"`
+	// FailedPermanently counts messages that used up consumer.max_attempts.
+	// It takes a scan, so approximate stats leave it out.
+	FailedPermanently *int64 `json:"failed_permanently,omitempty"`
+	// Retrying counts messages that failed at least once and will be
+	// retried. Like FailedPermanently, only exact stats have it.
+	Retrying *int64 `json:"retrying,omitempty"`
+}
+
+// Throughput is a tenant's message count over time, for charting.
+type Throughput struct {
+	TenantID string       `json:"tenant_id"`
+	Interval string       `json:"interval"`
+	Window   string       `json:"window"`
+	Buckets  []TimeBucket `json:"buckets"`
+}
+
+// Histogram is a tenant's message count per bucket between From and To.
+type Histogram struct {
+	TenantID string       `json:"tenant_id"`
+	Bucket   string       `json:"bucket"`
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Buckets  []TimeBucket `json:"buckets"`
+}
+
+// TimeBucket counts the messages created in [Start, Start+interval).
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
 }
 
 // Request/Response DTOs
 type CreateTenantRequest struct {
 	Name string `json:"name" binding:"required"`
+	// SingleActiveConsumer declares the tenant's queues so that only one
+	// consumer across all instances receives messages at a time. It is fixed
+	// once the tenant exists.
+	SingleActiveConsumer bool `json:"single_active_consumer"`
+	// Transient declares the tenant's queues non-durable and publishes its
+	// messages without persistence, for throwaway data such as telemetry:
+	// queued messages are lost when the broker restarts. It is fixed once
+	// the tenant exists.
+	Transient bool `json:"transient"`
 }
 
 type CreateMessageRequest struct {
-	Payload interface{} `json:"payload" binding:"required" swaggertype:"object"`
+	// ID is an optional client-supplied message ID, which must be a UUID and
+	// unique within the tenant. The server generates one when it is empty.
+	ID string `json:"id,omitempty"`
+	// Payload is required unless the tenant has allow_empty_payload set, in
+	// which case a missing or null payload is stored as {}
+	Payload interface{} `json:"payload" swaggertype:"object"`
+	// Metadata describes the message rather than its content, e.g. the
+	// producing "source". It is stored alongside the payload.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// CorrelationID is taken from the X-Correlation-ID header; one is
+	// generated when the header is absent.
+	CorrelationID string `json:"-"`
+	// Lane routes the message to one of the tenant's lanes. The main queue
+	// is used when it is empty.
+	Lane string `json:"lane,omitempty"`
+	// CreatedAt backfills a message with its original time, e.g. when
+	// importing historical data. The server time is used when it is
+	// omitted.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// ExpiresAt is when the message expires: from then on it is left out of
+	// reads, and the retention job deletes it. It must be in the future;
+	// messages without it are kept.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UpdateLaneRequest creates a lane or resizes its worker pool.
+type UpdateLaneRequest struct {
+	Workers int `json:"workers" binding:"required,min=1"`
+}
+
+// ConsumerStatus describes the consumers and worker pools serving a tenant.
+type ConsumerStatus struct {
+	TenantID string `json:"tenant_id"`
+	// Running reports whether the tenant's main queue is being consumed
+	Running bool `json:"running"`
+	// Idle reports whether the tenant's consumers are stopped for having
+	// had no messages for consumer.idle_timeout; they start again when one
+	// is published
+	Idle bool `json:"idle,omitempty"`
+	// Owner is the instance consuming the tenant when cluster.enabled is
+	// set, empty while no instance owns it
+	Owner string `json:"owner,omitempty"`
+	// SingleActiveConsumer reports whether the tenant's queues let only one
+	// consumer across all instances receive at a time
+	SingleActiveConsumer bool `json:"single_active_consumer"`
+	// Transient reports whether the tenant's queues are non-durable
+	Transient bool `json:"transient"`
+	// Weight is the tenant's scheduler weight, omitted when the scheduler
+	// is disabled
+	Weight int `json:"weight,omitempty"`
+	// MaxConcurrency caps the tenant's concurrent handler runs across all of
+	// its pools; 0 means it is bounded only by the worker count
+	MaxConcurrency int `json:"max_concurrency"`
+	// SampleRate is the share of the tenant's messages that are processed;
+	// the rest are acked without processing
+	SampleRate float64 `json:"sample_rate"`
+	// Pool is "dedicated" or "shared". Workers, QueuedJobs and Processed
+	// describe that pool, so for the shared pool they cover every tenant on it.
+	Pool       string `json:"pool"`
+	Workers    int    `json:"workers"`
+	QueuedJobs int    `json:"queued_jobs"`
+	Processed  int64  `json:"processed"`
+	// Delivered and Redelivered count deliveries to the tenant's consumers,
+	// lanes included, since the service started. RedeliveryRate is the share
+	// of deliveries that were redeliveries; a high rate means messages keep
+	// failing or missing their visibility deadline.
+	Delivered      int64   `json:"delivered"`
+	Redelivered    int64   `json:"redelivered"`
+	RedeliveryRate float64 `json:"redelivery_rate"`
+	// Restarts counts the times the tenant's consumer was recreated after
+	// the broker connection was lost, and LastRestart is the latest; both
+	// are since the service started
+	Restarts    int64        `json:"restarts"`
+	LastRestart *time.Time   `json:"last_restart,omitempty"`
+	Lanes       []LaneStatus `json:"lanes"`
+}
+
+// LaneStatus describes one of a tenant's lanes.
+type LaneStatus struct {
+	Name       string `json:"name"`
+	Running    bool   `json:"running"`
+	Workers    int    `json:"workers"`
+	QueuedJobs int    `json:"queued_jobs"`
+	Processed  int64  `json:"processed"`
+}
+
+// PoolStats is the live state of a worker pool serving a tenant. Mode is
+// "dedicated" or "shared"; the counts of the shared pool cover every tenant
+// on it. Workers is the configured worker count and Running the worker
+// goroutines alive, which differ while a resize is taking effect.
+type PoolStats struct {
+	Lane          string `json:"lane,omitempty"`
+	Mode          string `json:"mode"`
+	Workers       int    `json:"workers"`
+	Running       int    `json:"running"`
+	Busy          int    `json:"busy"`
+	QueuedJobs    int    `json:"queued_jobs"`
+	QueueCapacity int    `json:"queue_capacity"`
+	Processed     int64  `json:"processed"`
+}
+
+// TenantPoolStats lists the worker pools of a tenant on this instance, its
+// main pool first, then its lanes' by name. It is empty while another
+// instance consumes the tenant.
+type TenantPoolStats struct {
+	TenantID string      `json:"tenant_id"`
+	Pools    []PoolStats `json:"pools"`
+}
+
+// DrainStatus is returned while a drained delete is still in progress. The
+// tenant at StatusURL reports "draining" until it is deleted, then 404.
+type DrainStatus struct {
+	TenantID  string `json:"tenant_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+}
+
+// UpdateTransformsRequest sets the transforms applied, in order, to a
+// tenant's payloads before they are stored. KeepRawPayload also stores each
+// payload as it was received.
+type UpdateTransformsRequest struct {
+	Transforms     []string `json:"transforms"`
+	KeepRawPayload bool     `json:"keep_raw_payload"`
+}
+
+// PayloadSchema describes the payloads a tenant accepts, in a subset of JSON
+// Schema: type ("object", "array", "string", "number", "integer", "boolean"
+// or "null"), properties and required for objects, items for arrays, and
+// enum. Other keywords are ignored.
+type PayloadSchema struct {
+	Type       string                    `json:"type,omitempty"`
+	Properties map[string]*PayloadSchema `json:"properties,omitempty"`
+	Required   []string                  `json:"required,omitempty"`
+	Items      *PayloadSchema            `json:"items,omitempty"`
+	Enum       []interface{}             `json:"enum,omitempty"`
+}
+
+// UpdatePayloadSchemaRequest sets the schema a tenant's payloads are checked
+// against before they are processed. A null schema removes it.
+type UpdatePayloadSchemaRequest struct {
+	Schema *PayloadSchema `json:"schema"`
+}
+
+// UpdateWorkerScheduleRequest replaces a tenant's worker schedule. Outside
+// its windows the tenant runs its configured worker count; an empty schedule
+// removes it.
+type UpdateWorkerScheduleRequest struct {
+	Schedule []WorkerWindow `json:"schedule" binding:"required,dive"`
+}
+
+// UpdateMaxConcurrencyRequest sets how many of a tenant's messages may be
+// processed at once. 0 removes the limit.
+type UpdateMaxConcurrencyRequest struct {
+	MaxConcurrency int `json:"max_concurrency" binding:"min=0,max=1000"`
+}
+
+// UpdateSampleRateRequest sets the share of a tenant's messages that are
+// processed, from 0 to 1.
+type UpdateSampleRateRequest struct {
+	SampleRate *float64 `json:"sample_rate" binding:"required,min=0,max=1"`
+}
+
+// UpdateEmptyPayloadRequest sets whether a tenant accepts messages without a
+// payload, such as heartbeats.
+type UpdateEmptyPayloadRequest struct {
+	AllowEmptyPayload *bool `json:"allow_empty_payload" binding:"required"`
+}
+
+type UpdateWeightRequest struct {
+	Weight int `json:"weight" binding:"required,min=1,max=100"`
 }
 
+// UpdateConcurrencyRequest sets a tenant's worker count, up to the
+// configured max_workers.
 type UpdateConcurrencyRequest struct {
-	Workers int `json:"workers" binding:"required,min=1,max=100"`
+	Workers int `json:"workers" binding:"required,min=1"`
+}
+
+type UpdatePoolModeRequest struct {
+	Dedicated *bool `json:"dedicated" binding:"required"`
+}
+
+type UpdateFailurePolicyRequest struct {
+	Policy string `json:"policy" binding:"required,oneof=dlq retry drop retry_then_dlq"`
+}
+
+// ValidateConfigRequest holds a tenant config change to check without
+// applying it. Each field takes the value of its PUT /tenants/{id}/config
+// endpoint, and is only checked when present.
+type ValidateConfigRequest struct {
+	Workers        *int           `json:"workers,omitempty"`
+	WorkerSchedule []WorkerWindow `json:"worker_schedule,omitempty"`
+	Weight         *int           `json:"weight,omitempty"`
+	MaxConcurrency *int           `json:"max_concurrency,omitempty"`
+	SampleRate     *float64       `json:"sample_rate,omitempty"`
+	FailurePolicy  *string        `json:"failure_policy,omitempty"`
+	Transforms     []string       `json:"transforms,omitempty"`
+	PayloadSchema  *PayloadSchema `json:"payload_schema,omitempty"`
+	// Lanes maps lane names to their worker counts
+	Lanes map[string]int `json:"lanes,omitempty"`
+}
+
+// ConfigValidation reports whether a tenant config change would be applied,
+// and if not, why.
+type ConfigValidation struct {
+	TenantID string        `json:"tenant_id"`
+	Valid    bool          `json:"valid"`
+	Errors   []ConfigError `json:"errors"`
+}
+
+// ConfigError is why one field of a config change would be rejected.
+type ConfigError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// ReconnectResult reports the outcome of a manual RabbitMQ reconnect.
+type ReconnectResult struct {
+	ConsumersRestarted int `json:"consumers_restarted"`
+	// DiscardedJobs counts buffered jobs dropped because their deliveries
+	// belonged to the old connection; the broker redelivers them
+	DiscardedJobs int `json:"discarded_jobs"`
+	// Failures maps tenant IDs to the error that kept their consumer down
+	Failures map[string]string `json:"failures,omitempty"`
+}
+
+type BenchmarkIngestRequest struct {
+	TenantID    string `json:"tenant_id" binding:"required,uuid"`
+	Count       int    `json:"count" binding:"required,min=1,max=10000"`
+	Concurrency int    `json:"concurrency" binding:"omitempty,min=1,max=50"`
+}
+
+// BenchmarkResult reports the outcome of an ingest benchmark. Latencies
+// cover a single message insert and publish.
+type BenchmarkResult struct {
+	TenantID          string             `json:"tenant_id"`
+	Messages          int                `json:"messages"`
+	Failed            int                `json:"failed"`
+	Concurrency       int                `json:"concurrency"`
+	DurationMs        float64            `json:"duration_ms"`
+	MessagesPerSecond float64            `json:"messages_per_second"`
+	LatencyMs         LatencyPercentiles `json:"latency_ms"`
+}
+
+type LatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Diagnostic checks run for a tenant
+const (
+	DiagnosticQueue     = "queue"
+	DiagnosticConsumer  = "consumer"
+	DiagnosticPartition = "partition"
+	DiagnosticWriteRead = "write_read"
+)
+
+// TenantDiagnostics reports the outcome of a tenant's diagnostic checks.
+type TenantDiagnostics struct {
+	TenantID string `json:"tenant_id"`
+	// Healthy is set when every check passed
+	Healthy bool              `json:"healthy"`
+	Checks  []DiagnosticCheck `json:"checks"`
+}
+
+// DiagnosticCheck is the outcome of a single diagnostic check. Remediation
+// suggests how to fix a check that failed.
+type DiagnosticCheck struct {
+	Name        string `json:"name"`
+	Passed      bool   `json:"passed"`
+	Detail      string `json:"detail,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Lag statuses, from best to worst
+const (
+	LagHealthy    = "healthy"
+	LagDegraded   = "degraded"
+	LagOverloaded = "overloaded"
+)
+
+// TenantLag summarises how far a tenant's processing is behind. Status is
+// derived from the indicators and the lag thresholds; Reasons names each
+// threshold that was reached.
+type TenantLag struct {
+	TenantID string `json:"tenant_id"`
+	Status   string `json:"status"`
+	// QueueDepth counts the messages waiting in the tenant's queues, lanes
+	// included, and in its dedicated worker pools
+	QueueDepth int `json:"queue_depth"`
+	// OldestPendingAgeSeconds is the age of the oldest message not yet
+	// processed, 0 when none is pending
+	OldestPendingAgeSeconds float64 `json:"oldest_pending_age_seconds"`
+	// WorkerUtilization is the share of the workers serving the tenant that
+	// are busy with a message, from 0 to 1. For tenants on the shared pool it
+	// covers every tenant on it.
+	WorkerUtilization float64  `json:"worker_utilization"`
+	Reasons           []string `json:"reasons"`
+}
+
+// Where an effective setting comes from: set for the tenant, left at the
+// tenant default, taken from the instance configuration, or set by the
+// tenant's worker schedule window in force.
+const (
+	SettingSourceTenant   = "tenant"
+	SettingSourceDefault  = "default"
+	SettingSourceInstance = "instance"
+	SettingSourceSchedule = "schedule"
+)
+
+// EffectiveSetting is the value a setting takes for a tenant.
+type EffectiveSetting struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// EffectiveConfig is every setting that applies to a tenant, keyed by name.
+// Tenant settings are named after their tenant_configs column, instance
+// settings after their configuration key.
+type EffectiveConfig struct {
+	TenantID string                      `json:"tenant_id"`
+	Settings map[string]EffectiveSetting `json:"settings"`
+}
+
+// Dependencies checked for readiness, and the status of a passed check.
+// CheckStartupBacklog is the startup gate enabled by health.startup_backlog.
+const (
+	DependencyPostgres  = "postgres"
+	DependencyRabbitMQ  = "rabbitmq"
+	CheckStartupBacklog = "startup_backlog"
+	DependencyOK        = "ok"
+)
+
+// ReadinessReport is the outcome of the dependency checks behind /readyz.
+type ReadinessReport struct {
+	Ready bool `json:"ready"`
+	// Dependencies maps each dependency, and the startup gate if enabled, to
+	// "ok" or the error its check failed with
+	Dependencies map[string]string `json:"dependencies"`
+	// CheckedAt is when the checks ran; probes are served a cached result
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// MaintenanceResult lists the partitions a maintenance run analyzed.
+type MaintenanceResult struct {
+	Partitions []string `json:"partitions"`
+	Vacuum     bool     `json:"vacuum"`
+	DurationMs float64  `json:"duration_ms"`
+}
+
+// ReconcileResult reports a reconciliation of live worker pools against the
+// stored config. Checked counts the pools compared.
+type ReconcileResult struct {
+	RanAt       time.Time        `json:"ran_at"`
+	Checked     int              `json:"checked"`
+	Corrections []PoolCorrection `json:"corrections"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// OrphanedQueue is a tenant queue on the broker whose tenant no longer
+// exists.
+type OrphanedQueue struct {
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id"`
+}
+
+// OrphanedQueues lists orphaned queues, found or deleted.
+type OrphanedQueues struct {
+	Queues []OrphanedQueue `json:"queues"`
+}
+
+// QueueInfo is a tenant queue as declared on the broker. Arguments are
+// those the queue was declared with, which may predate the current code.
+type QueueInfo struct {
+	TenantID               string                 `json:"tenant_id"`
+	Lane                   string                 `json:"lane,omitempty"`
+	Name                   string                 `json:"name"`
+	Durable                bool                   `json:"durable"`
+	AutoDelete             bool                   `json:"auto_delete"`
+	Exclusive              bool                   `json:"exclusive"`
+	Arguments              map[string]interface{} `json:"arguments"`
+	Messages               int                    `json:"messages"`
+	MessagesReady          int                    `json:"messages_ready"`
+	MessagesUnacknowledged int                    `json:"messages_unacknowledged"`
+	Consumers              int                    `json:"consumers"`
+	Bindings               []QueueBinding         `json:"bindings"`
+}
+
+// QueueBinding binds a queue to an exchange. Exchange is empty for the
+// default exchange, which routes to the queue by its name.
+type QueueBinding struct {
+	Exchange   string                 `json:"exchange"`
+	RoutingKey string                 `json:"routing_key"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// RetiredPartition is the partition of a deleted tenant, kept detached until
+// DropsAt in case the delete has to be undone.
+type RetiredPartition struct {
+	Name      string    `json:"name"`
+	TenantID  string    `json:"tenant_id"`
+	RetiredAt time.Time `json:"retired_at"`
+	DropsAt   time.Time `json:"drops_at"`
+}
+
+// RetiredPartitions lists retired partitions.
+type RetiredPartitions struct {
+	Partitions []RetiredPartition `json:"partitions"`
+}
+
+// RestoreRetiredPartitionRequest names the tenant recreated from a retired
+// partition; the original name is deleted with the tenant.
+type RestoreRetiredPartitionRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// PoolCorrection is a worker pool resized from From to To workers by a
+// reconciliation. Lane is empty for the tenant's main pool.
+type PoolCorrection struct {
+	TenantID string `json:"tenant_id"`
+	Lane     string `json:"lane,omitempty"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
 }
 
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
+	// Fields lists, for a request body that failed validation, each field
+	// at fault
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is one field of a request body that failed validation. Field is
+// the field's JSON path, e.g. "workers" or "items[2].payload", and Rule the
+// binding rule it broke, e.g. "required" or "min".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ListResponse is the envelope list endpoints respond with when asked to,
+// instead of a bare array or their own page object.
+type ListResponse struct {
+	Data interface{} `json:"data"`
+	Meta ListMeta    `json:"meta"`
+}
+
+// ListMeta describes the list in a ListResponse.
+type ListMeta struct {
+	// Count is the number of items in data
+	Count int `json:"count"`
+	// NextCursor fetches the next page of a paginated list; it is omitted on
+	// the last page and for lists that are not paginated
+	NextCursor *string `json:"next_cursor,omitempty"`
 }
 
 type SuccessResponse struct {
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"jatis/internal/config"
+)
+
+// maxAlertSamples is how many dead letters an alert lists; the rest are only
+// counted.
+const maxAlertSamples = 5
+
+// alertTimeout bounds a notifier's exchange with its server when it sets no
+// timeout of its own, and the flush of pending alerts on Shutdown.
+const alertTimeout = 10 * time.Second
+
+// Notifier sends dead-letter alerts to people. Unlike a DLQHandler, which
+// consumes the dead letters themselves, a notifier only reports them.
+type Notifier interface {
+	Notify(ctx context.Context, alert DeadLetterAlert) error
+}
+
+// DeadLetterSample is one dead-lettered message listed in an alert.
+type DeadLetterSample struct {
+	MessageID string
+	Reason    string
+}
+
+// DeadLetterAlert reports the messages of a tenant dead-lettered between
+// First and Last.
+type DeadLetterAlert struct {
+	TenantID string
+	Count    int
+	// Samples are the first dead letters of the batch
+	Samples []DeadLetterSample
+	First   time.Time
+	Last    time.Time
+}
+
+// Text renders the alert as plain text.
+func (a DeadLetterAlert) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d message(s) of tenant %s dead-lettered between %s and %s",
+		a.Count, a.TenantID, a.First.UTC().Format(time.RFC3339), a.Last.UTC().Format(time.RFC3339))
+	for _, s := range a.Samples {
+		fmt.Fprintf(&b, "\n- %s: %s", s.MessageID, s.Reason)
+	}
+	if more := a.Count - len(a.Samples); more > 0 {
+		fmt.Fprintf(&b, "\n- and %d more", more)
+	}
+	return b.String()
+}
+
+// DeadLetterAlerts batches dead-lettered messages per tenant until Flush, so
+// an outage that dead-letters thousands of messages raises one alert per
+// tenant and flush rather than one per message.
+type DeadLetterAlerts struct {
+	mu      sync.Mutex
+	global  []Notifier
+	tenants map[string][]Notifier
+	pending map[string]*DeadLetterAlert
+}
+
+// NewDeadLetterAlerts sends the alerts of the tenants in tenants to their own
+// notifiers, and those of every other tenant to global.
+func NewDeadLetterAlerts(global []Notifier, tenants map[string][]Notifier) *DeadLetterAlerts {
+	return &DeadLetterAlerts{
+		global:  global,
+		tenants: tenants,
+		pending: make(map[string]*DeadLetterAlert),
+	}
+}
+
+// notifiers returns the notifiers of the tenant.
+func (a *DeadLetterAlerts) notifiers(tenantID string) []Notifier {
+	if notifiers, exists := a.tenants[tenantID]; exists {
+		return notifiers
+	}
+	return a.global
+}
+
+// Record adds a dead-lettered message to its tenant's next alert. It never
+// blocks on a notifier, and does nothing on a nil DeadLetterAlerts.
+func (a *DeadLetterAlerts) Record(tenantID, messageID, reason string) {
+	if a == nil || len(a.notifiers(tenantID)) == 0 {
+		return
+	}
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alert, exists := a.pending[tenantID]
+	if !exists {
+		alert = &DeadLetterAlert{TenantID: tenantID, First: now}
+		a.pending[tenantID] = alert
+	}
+	alert.Count++
+	alert.Last = now
+	if len(alert.Samples) < maxAlertSamples {
+		alert.Samples = append(alert.Samples, DeadLetterSample{MessageID: messageID, Reason: reason})
+	}
+}
+
+// Flush sends one alert per tenant with dead letters since the last flush
+// through each of its notifiers. A notifier that fails is logged and its
+// alert dropped rather than retried, so a broken webhook cannot pile alerts
+// up.
+func (a *DeadLetterAlerts) Flush(ctx context.Context) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[string]*DeadLetterAlert)
+	a.mu.Unlock()
+
+	tenantIDs := make([]string, 0, len(pending))
+	for tenantID := range pending {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Strings(tenantIDs)
+
+	for _, tenantID := range tenantIDs {
+		for _, notifier := range a.notifiers(tenantID) {
+			if err := notifier.Notify(ctx, *pending[tenantID]); err != nil {
+				log.Printf("Warning: dead-letter alert for tenant %s failed: %v", tenantID, err)
+			}
+		}
+	}
+}
+
+// SlackNotifier posts alerts to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+	// Client defaults to a client with a 10s timeout
+	Client *http.Client
+}
+
+func (n SlackNotifier) Notify(ctx context.Context, alert DeadLetterAlert) error {
+	body, err := json.Marshal(map[string]string{"text": alert.Text()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: alertTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post slack alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier emails alerts through an SMTP server.
+type SMTPNotifier struct {
+	Addr string
+	// Auth may be nil for a server that accepts mail without it
+	Auth smtp.Auth
+	From string
+	To   []string
+	// Timeout bounds the whole exchange with the server; zero means 10s
+	Timeout time.Duration
+}
+
+func (n SMTPNotifier) Notify(ctx context.Context, alert DeadLetterAlert) error {
+	msg := "From: " + n.From + "\r\n" +
+		"To: " + strings.Join(n.To, ", ") + "\r\n" +
+		"Subject: Dead letters for tenant " + alert.TenantID + "\r\n" +
+		"\r\n" +
+		strings.ReplaceAll(alert.Text(), "\n", "\r\n") + "\r\n"
+	if err := n.send(ctx, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err)
+	}
+	return nil
+}
+
+// send does what smtp.SendMail does, within the notifier's timeout and for
+// no longer than ctx allows: the connection is closed once ctx is done,
+// which fails whatever step of the exchange is waiting on the server.
+func (n SMTPNotifier) send(ctx context.Context, msg []byte) error {
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = alertTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(n.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(n.Auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// alertNotifiers builds the notifiers of targets.
+func alertNotifiers(targets config.AlertTargets) []Notifier {
+	var notifiers []Notifier
+	if targets.SlackWebhookURL != "" {
+		notifiers = append(notifiers, SlackNotifier{WebhookURL: targets.SlackWebhookURL})
+	}
+	if s := targets.SMTP; s.Addr != "" && len(s.To) > 0 {
+		n := SMTPNotifier{Addr: s.Addr, From: s.From, To: s.To}
+		if s.Username != "" {
+			host, _, _ := net.SplitHostPort(s.Addr)
+			n.Auth = smtp.PlainAuth("", s.Username, s.Password, host)
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers
+}
+
+// newDeadLetterAlerts builds the alerts of cfg, or returns nil when no
+// targets are configured.
+func newDeadLetterAlerts(cfg config.AlertsConfig) *DeadLetterAlerts {
+	global := alertNotifiers(cfg.AlertTargets)
+	tenants := make(map[string][]Notifier, len(cfg.Tenants))
+	for tenantID, targets := range cfg.Tenants {
+		tenants[tenantID] = alertNotifiers(targets)
+	}
+	if len(global) == 0 && len(cfg.Tenants) == 0 {
+		return nil
+	}
+	return NewDeadLetterAlerts(global, tenants)
+}
+
+// alertWorker flushes dead-letter alerts every interval, and once more on
+// Shutdown.
+func (tm *TenantManager) alertWorker(interval time.Duration) {
+	defer close(tm.alertsExited)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tm.alerts.Flush(context.Background())
+		case <-tm.alertsDone:
+			// Shutdown waits for this flush, so it gets a deadline
+			ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+			tm.alerts.Flush(ctx)
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"jatis/internal/logging"
+)
+
+// ErrUnknownBatchHandler is returned when consumer.batch.handler names no
+// registered handler.
+var ErrUnknownBatchHandler = errors.New("unknown batch handler")
+
+// BatchHandler processes several jobs at once, for work that is cheaper in
+// bulk such as batched writes downstream. Returning nil acks every job in
+// the batch; an error fails all of them, and each is settled as a JobHandler
+// error would be.
+type BatchHandler func(ctx context.Context, jobs []Job) error
+
+var (
+	batchHandlersMu sync.RWMutex
+	batchHandlers   = map[string]BatchHandler{}
+)
+
+// RegisterBatchHandler makes a batch handler available to
+// consumer.batch.handler under name, replacing any handler registered under
+// the same name. Register handlers before the tenant manager is created.
+func RegisterBatchHandler(name string, h BatchHandler) {
+	batchHandlersMu.Lock()
+	defer batchHandlersMu.Unlock()
+	batchHandlers[name] = h
+}
+
+// lookupBatchHandler returns the batch handler registered under name.
+func lookupBatchHandler(name string) (BatchHandler, error) {
+	batchHandlersMu.RLock()
+	defer batchHandlersMu.RUnlock()
+
+	h, exists := batchHandlers[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBatchHandler, name)
+	}
+	return h, nil
+}
+
+// batchMode is how a batch pool groups jobs.
+type batchMode struct {
+	size    int
+	wait    time.Duration
+	handler BatchHandler
+	// settle, if set, turns the batch's result into each job's, as
+	// handleJob does for a single job
+	settle func(ctx context.Context, job Job, err error) error
+}
+
+// NewBatchWorkerPool returns a pool whose workers each collect up to size
+// queued jobs, waiting at most wait after the first one arrives, and hand
+// them to handler together. A zero wait hands over what can be collected
+// without waiting. Batch pools do not take scheduler slots or per-tenant
+// concurrency limits.
+func NewBatchWorkerPool(ctx context.Context, workers int32, size int, wait time.Duration, handler BatchHandler) *WorkerPool {
+	return newBatchWorkerPool(ctx, workers, &batchMode{size: max(size, 1), wait: wait, handler: handler})
+}
+
+func newBatchWorkerPool(ctx context.Context, workers int32, batch *batchMode) *WorkerPool {
+	ctx, cancel := context.WithCancel(ctx)
+	pool := &WorkerPool{
+		workers:  workers,
+		jobQueue: newJobQueue(max(100, batch.size)),
+		ctx:      ctx,
+		cancel:   cancel,
+		quit:     make(chan bool),
+		shrink:   make(chan struct{}, 1),
+		batch:    batch,
+	}
+
+	pool.start()
+	return pool
+}
+
+func (wp *WorkerPool) batchWorker() {
+	for {
+		select {
+		case <-wp.jobQueue.ready:
+			job, ok := wp.jobQueue.pop()
+			if !ok {
+				continue
+			}
+			jobs, quit := wp.collectBatch(job)
+			wp.processBatch(jobs)
+			if quit {
+				return
+			}
+		case <-wp.shrink:
+		case <-wp.quit:
+			return
+		}
+		if wp.retire() {
+			return
+		}
+	}
+}
+
+// collectBatch adds queued jobs to first until the batch is full or the wait
+// has passed. It reports whether the worker was told to quit meanwhile, in
+// which case the jobs collected so far are still handled.
+func (wp *WorkerPool) collectBatch(first Job) ([]Job, bool) {
+	jobs := []Job{first}
+	timer := time.NewTimer(wp.batch.wait)
+	defer timer.Stop()
+
+	for len(jobs) < wp.batch.size {
+		select {
+		case <-wp.jobQueue.ready:
+			if job, ok := wp.jobQueue.pop(); ok {
+				jobs = append(jobs, job)
+			}
+		case <-timer.C:
+			return jobs, false
+		case <-wp.quit:
+			return jobs, true
+		}
+	}
+	return jobs, false
+}
+
+func (wp *WorkerPool) processBatch(jobs []Job) {
+	atomic.AddInt32(&wp.busy, 1)
+	defer atomic.AddInt32(&wp.busy, -1)
+	for _, job := range jobs {
+		job.track(1)
+		defer job.track(-1)
+	}
+
+	err := wp.batch.handler(wp.ctx, jobs)
+	atomic.AddInt64(&wp.processed, int64(len(jobs)))
+	for _, job := range jobs {
+		jobErr := err
+		if wp.batch.settle != nil {
+			jobErr = wp.batch.settle(wp.ctx, job, err)
+		}
+		wp.finish(job, jobErr)
+	}
+}
+
+// newBatchPool creates a tenant pool handing its jobs to the handler named
+// by consumer.batch.handler. Each job's attempt is recorded and settled as
+// in a pool handling jobs one at a time.
+func (tm *TenantManager) newBatchPool(workers int) *WorkerPool {
+	cfg := tm.cfg.Consumer.Batch
+	return newBatchWorkerPool(tm.ctx, int32(workers), &batchMode{
+		size:    max(cfg.Size, 1),
+		wait:    cfg.Wait,
+		handler: tm.handleBatch,
+		settle:  tm.settleAttempt,
+	})
+}
+
+// handleBatch is the BatchHandler of batch tenant pools. consumer.job_timeout
+// bounds the whole batch.
+func (tm *TenantManager) handleBatch(ctx context.Context, jobs []Job) error {
+	if tm.cfg.Consumer.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tm.cfg.Consumer.JobTimeout)
+		defer cancel()
+	}
+	for _, job := range jobs {
+		tm.lifecycle.Event(logging.EventProcessing, job.TenantID, job.MessageID, job.CorrelationID)
+	}
+	return tm.batchHandler(ctx, jobs)
+}
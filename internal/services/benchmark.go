@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"jatis/internal/models"
+)
+
+// BenchmarkSource is the metadata source of messages created by
+// BenchmarkIngest, so they can be told apart from real traffic.
+const BenchmarkSource = "benchmark"
+
+// BenchmarkIngest creates count synthetic messages for a tenant through the
+// regular create path (insert and publish) using concurrency parallel
+// writers, and reports the achieved throughput and per-message latency.
+func (ms *MessageService) BenchmarkIngest(tenantID string, count, concurrency int) (*models.BenchmarkResult, error) {
+	if err := ms.checkTenant(tenantID); err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > count {
+		concurrency = count
+	}
+
+	var mu sync.Mutex
+	latencies := make([]time.Duration, 0, count)
+	failed := 0
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				req := &models.CreateMessageRequest{
+					Payload:  map[string]interface{}{"benchmark": true, "seq": i},
+					Metadata: map[string]interface{}{"source": BenchmarkSource},
+				}
+
+				began := time.Now()
+				_, err := ms.CreateMessage(tenantID, req)
+				elapsed := time.Since(began)
+
+				mu.Lock()
+				if err != nil {
+					failed++
+				} else {
+					latencies = append(latencies, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	duration := time.Since(start)
+
+	if len(latencies) == 0 {
+		return nil, fmt.Errorf("benchmark failed: none of %d messages were created", count)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return &models.BenchmarkResult{
+		TenantID:          tenantID,
+		Messages:          len(latencies),
+		Failed:            failed,
+		Concurrency:       concurrency,
+		DurationMs:        durationMs(duration),
+		MessagesPerSecond: float64(len(latencies)) / duration.Seconds(),
+		LatencyMs: models.LatencyPercentiles{
+			P50: durationMs(percentile(latencies, 50)),
+			P90: durationMs(percentile(latencies, 90)),
+			P99: durationMs(percentile(latencies, 99)),
+			Max: durationMs(latencies[len(latencies)-1]),
+		},
+	}, nil
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"jatis/internal/config"
+)
+
+// ErrBlobNotFound is returned for a blob key the store does not have.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps message payloads too large to store inline in the messages
+// table, under keys of the form "<tenant_id>/<blob id>".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes a blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// payloadBlobStore returns the store payloads above
+// payload_storage.offload_threshold go to, or nil if they are kept inline.
+func payloadBlobStore(cfg *config.Config) (BlobStore, error) {
+	if cfg.PayloadStorage.OffloadThreshold <= 0 {
+		return nil, nil
+	}
+	return newBlobStore(cfg.PayloadStorage)
+}
+
+// deleteBlobs removes the offloaded payloads of messages that are gone. A
+// failure is only logged; the blob is left behind.
+func deleteBlobs(store BlobStore, keys []string) {
+	if store == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("Warning: failed to delete offloaded payload %s: %v", key, err)
+		}
+	}
+}
+
+// newBlobStore builds the store payload_storage.store names.
+func newBlobStore(cfg config.PayloadStorageConfig) (BlobStore, error) {
+	switch cfg.Store {
+	case "", "file":
+		if cfg.Dir == "" {
+			return nil, errors.New("payload_storage.dir is required for the file store")
+		}
+		return FileBlobStore{Dir: cfg.Dir}, nil
+	case "s3":
+		s3 := cfg.S3
+		if s3.Endpoint == "" || s3.Bucket == "" {
+			return nil, errors.New("payload_storage.s3.endpoint and bucket are required for the s3 store")
+		}
+		region := s3.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		return S3BlobStore{
+			Endpoint:        s3.Endpoint,
+			Region:          region,
+			Bucket:          s3.Bucket,
+			AccessKeyID:     s3.AccessKeyID,
+			SecretAccessKey: s3.SecretAccessKey,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown payload_storage.store %q", cfg.Store)
+}
+
+// FileBlobStore keeps blobs as files under Dir. It suits a single instance
+// and tests; instances sharing a database need a store they all reach.
+type FileBlobStore struct {
+	Dir string
+}
+
+// path returns the file of key, refusing keys that would leave Dir.
+func (s FileBlobStore) path(key string) (string, error) {
+	name := filepath.FromSlash(key)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+func (s FileBlobStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	// Written aside and renamed, so a reader never sees half a blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	return nil
+}
+
+func (s FileBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+func (s FileBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// S3BlobStore keeps blobs in a bucket of an S3-compatible object store.
+// Objects are addressed path-style, Endpoint/Bucket/key, which MinIO and
+// other S3-compatible stores accept as well as AWS, and requests are signed
+// with AWS Signature Version 4.
+type S3BlobStore struct {
+	// Endpoint is the store's base URL, e.g. https://s3.eu-west-1.amazonaws.com
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Client defaults to a client with a 30s timeout
+	Client *http.Client
+}
+
+func (s S3BlobStore) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to put blob %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (s S3BlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get blob %s: %s", key, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	return data, nil
+}
+
+func (s S3BlobStore) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete blob %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+// do sends a signed request for the object key.
+func (s S3BlobStore) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	segments := strings.Split(s.Bucket+"/"+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.Endpoint, "/")+"/"+strings.Join(segments, "/"), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build blob request: %w", err)
+	}
+	s.sign(req, body, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach blob store: %w", err)
+	}
+	return resp, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to req.
+func (s S3BlobStore) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	bodyHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// ConcurrencyLimits caps how many of each tenant's jobs run their handler at
+// once, independently of how many workers serve the tenant. Tenants without a
+// limit are not restricted.
+type ConcurrencyLimits struct {
+	mu     sync.Mutex
+	limits map[string]*semaphore
+}
+
+// NewConcurrencyLimits returns an empty set of per-tenant limits.
+func NewConcurrencyLimits() *ConcurrencyLimits {
+	return &ConcurrencyLimits{limits: make(map[string]*semaphore)}
+}
+
+// SetLimit caps the tenant's concurrent handler runs at limit. Zero or less
+// removes the cap. Lowering a limit does not interrupt running handlers; new
+// ones wait until the tenant is back under it.
+func (l *ConcurrencyLimits) SetLimit(tenantID string, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit < 0 {
+		limit = 0
+	}
+	// An existing semaphore is resized rather than replaced, so handlers
+	// already running keep counting against the new limit
+	if sem, exists := l.limits[tenantID]; exists {
+		sem.resize(int64(limit))
+		return
+	}
+	if limit > 0 {
+		l.limits[tenantID] = newSemaphore(int64(limit))
+	}
+}
+
+// Forget drops the tenant's limit.
+func (l *ConcurrencyLimits) Forget(tenantID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if sem, exists := l.limits[tenantID]; exists {
+		sem.resize(0)
+		delete(l.limits, tenantID)
+	}
+}
+
+// Limit returns the tenant's limit, or 0 if it has none.
+func (l *ConcurrencyLimits) Limit(tenantID string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if sem, exists := l.limits[tenantID]; exists {
+		return int(sem.capacity())
+	}
+	return 0
+}
+
+// Acquire blocks until the tenant may run another handler or ctx is done. On
+// success it returns the function that gives the permit back.
+func (l *ConcurrencyLimits) Acquire(ctx context.Context, tenantID string) (func(), error) {
+	l.mu.Lock()
+	sem, exists := l.limits[tenantID]
+	l.mu.Unlock()
+	if !exists {
+		return func() {}, nil
+	}
+
+	if err := sem.acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { sem.release(1) }, nil
+}
+
+// TenantSemaphores bounds how many operations run at once for each tenant,
+// with the same bound for every tenant. A tenant's semaphore only exists
+// while something holds or waits for it, so unknown tenant IDs do not
+// accumulate.
+type TenantSemaphores struct {
+	size int64
+	mu   sync.Mutex
+	sems map[string]*tenantSemaphore
+}
+
+type tenantSemaphore struct {
+	sem *semaphore
+	// users counts the holders and waiters
+	users int
+}
+
+// NewTenantSemaphores bounds each tenant to limit concurrent operations.
+func NewTenantSemaphores(limit int) *TenantSemaphores {
+	return &TenantSemaphores{size: int64(limit), sems: make(map[string]*tenantSemaphore)}
+}
+
+// Acquire blocks until the tenant is under its bound or ctx is done. On
+// success it returns the function that gives the permit back.
+func (t *TenantSemaphores) Acquire(ctx context.Context, tenantID string) (func(), error) {
+	t.mu.Lock()
+	ts, exists := t.sems[tenantID]
+	if !exists {
+		ts = &tenantSemaphore{sem: newSemaphore(t.size)}
+		t.sems[tenantID] = ts
+	}
+	ts.users++
+	t.mu.Unlock()
+
+	if err := ts.sem.acquire(ctx, 1); err != nil {
+		t.done(tenantID, ts)
+		return nil, err
+	}
+	return func() {
+		ts.sem.release(1)
+		t.done(tenantID, ts)
+	}, nil
+}
+
+// done drops a user of the tenant's semaphore, and the semaphore with its
+// last user.
+func (t *TenantSemaphores) done(tenantID string, ts *tenantSemaphore) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ts.users--
+	if ts.users == 0 {
+		delete(t.sems, tenantID)
+	}
+}
+
+// semaphore is a weighted semaphore whose size can change while in use.
+// Waiters are served in order, so a large request is not starved by a stream
+// of small ones.
+type semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters []*semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newSemaphore(size int64) *semaphore {
+	return &semaphore{size: size}
+}
+
+func (s *semaphore) capacity() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// acquire takes n permits, blocking until they are free or ctx is done.
+func (s *semaphore) acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if len(s.waiters) == 0 && s.fits(n) {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	w := &semaphoreWaiter{n: n, ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, waiter := range s.waiters {
+		if waiter == w {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			// Waiters behind this one may fit now
+			s.notify()
+			return ctx.Err()
+		}
+	}
+	// Granted just as ctx was cancelled; give the permits back
+	s.cur -= n
+	s.notify()
+	return ctx.Err()
+}
+
+// release returns n permits.
+func (s *semaphore) release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	s.notify()
+}
+
+// resize changes the number of permits. A size of 0 means unlimited.
+func (s *semaphore) resize(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.size = size
+	s.notify()
+}
+
+func (s *semaphore) fits(n int64) bool {
+	return s.size == 0 || s.cur+n <= s.size
+}
+
+// notify grants permits to waiters at the head of the line for as long as
+// they fit.
+func (s *semaphore) notify() {
+	for len(s.waiters) > 0 {
+		w := s.waiters[0]
+		if !s.fits(w.n) && s.cur > 0 {
+			return
+		}
+		s.cur += w.n
+		s.waiters = s.waiters[1:]
+		close(w.ready)
+	}
+}
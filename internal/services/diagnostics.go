@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"jatis/internal/database"
+	"jatis/internal/messaging"
+	"jatis/internal/models"
+)
+
+const (
+	remediationRestart   = "Restart the tenant's consumer with POST /api/v1/tenants/{id}/consumer/restart"
+	remediationBroker    = "Check that RabbitMQ is reachable, then reconnect with POST /api/v1/admin/rabbitmq/reconnect"
+	remediationPartition = "Send a message to the tenant; inserts create a missing partition on demand"
+)
+
+// Diagnose checks that the tenant's queue exists with a consumer attached,
+// that its partition exists, and that a message can be written and read back.
+// The written message is rolled back. Failed checks are reported with a
+// remediation hint rather than as an error.
+func (tm *TenantManager) Diagnose(tenantID string) (*models.TenantDiagnostics, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	report := &models.TenantDiagnostics{TenantID: tenantID}
+	report.Checks = append(report.Checks, tm.diagnoseQueue(tenantID)...)
+	report.Checks = append(report.Checks, tm.diagnosePartition(tenantID), tm.diagnoseWriteRead(tenantID))
+
+	report.Healthy = true
+	for _, check := range report.Checks {
+		report.Healthy = report.Healthy && check.Passed
+	}
+	return report, nil
+}
+
+// diagnoseQueue checks the tenant's main queue and its consumers, on any
+// instance.
+func (tm *TenantManager) diagnoseQueue(tenantID string) []models.DiagnosticCheck {
+	queue := models.DiagnosticCheck{Name: models.DiagnosticQueue}
+	consumer := models.DiagnosticCheck{Name: models.DiagnosticConsumer}
+
+	stats, err := tm.rabbitmq.InspectQueue(tenantID, "")
+	switch {
+	case errors.Is(err, messaging.ErrQueueNotFound):
+		queue.Detail = fmt.Sprintf("queue %s does not exist", messaging.QueueName(tenantID, ""))
+		queue.Remediation = remediationRestart
+		consumer.Detail = "no queue to consume"
+		consumer.Remediation = remediationRestart
+	case err != nil:
+		queue.Detail = err.Error()
+		queue.Remediation = remediationBroker
+		consumer.Detail = "queue could not be inspected"
+		consumer.Remediation = remediationBroker
+	default:
+		queue.Passed = true
+		queue.Detail = fmt.Sprintf("%d messages ready", stats.Messages)
+		consumer.Passed = stats.Consumers > 0
+		consumer.Detail = fmt.Sprintf("%d consumers attached", stats.Consumers)
+		if !consumer.Passed {
+			consumer.Remediation = remediationRestart
+		}
+	}
+
+	return []models.DiagnosticCheck{queue, consumer}
+}
+
+// diagnosePartition checks that the tenant's messages partition exists.
+func (tm *TenantManager) diagnosePartition(tenantID string) models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: models.DiagnosticPartition}
+	partition := database.PartitionName(tenantID)
+
+	err := tm.db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, partition).Scan(&check.Passed)
+	switch {
+	case err != nil:
+		check.Detail = err.Error()
+	case check.Passed:
+		check.Detail = fmt.Sprintf("partition %s exists", partition)
+	default:
+		check.Detail = fmt.Sprintf("partition %s does not exist", partition)
+		check.Remediation = remediationPartition
+	}
+	return check
+}
+
+// diagnoseWriteRead inserts a message for the tenant and reads it back in a
+// transaction that is rolled back, so nothing is left behind.
+func (tm *TenantManager) diagnoseWriteRead(tenantID string) models.DiagnosticCheck {
+	check := models.DiagnosticCheck{Name: models.DiagnosticWriteRead}
+
+	err := func() error {
+		tx, err := tm.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var id string
+		insert := `INSERT INTO messages (tenant_id, payload) VALUES ($1, '{"diagnostic": true}') RETURNING id`
+		if err := tx.QueryRow(insert, tenantID).Scan(&id); err != nil {
+			return fmt.Errorf("failed to insert: %w", err)
+		}
+		if err := tx.QueryRow(`SELECT id FROM messages WHERE tenant_id = $1 AND id = $2`, tenantID, id).Scan(&id); err != nil {
+			return fmt.Errorf("failed to read back: %w", err)
+		}
+		return nil
+	}()
+
+	if err != nil {
+		check.Detail = err.Error()
+		if database.IsMissingPartition(err) {
+			check.Remediation = remediationPartition
+		}
+		return check
+	}
+	check.Passed = true
+	check.Detail = "message written and read back"
+	return check
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"jatis/internal/logging"
+	"jatis/internal/messaging"
+)
+
+// ErrUnknownDLQHandler is returned when dead_letters.handler names no
+// registered handler.
+var ErrUnknownDLQHandler = errors.New("unknown DLQ handler")
+
+// DLQHandler handles the dead-lettered messages of every tenant, e.g. to
+// raise alerts or keep failures in a table. Returning an error puts the
+// message back in its dead letter queue to be handled again.
+type DLQHandler interface {
+	HandleDeadLetter(ctx context.Context, dl messaging.DeadLetter) error
+}
+
+// DLQHandlerFunc adapts a function to the DLQHandler interface.
+type DLQHandlerFunc func(ctx context.Context, dl messaging.DeadLetter) error
+
+func (f DLQHandlerFunc) HandleDeadLetter(ctx context.Context, dl messaging.DeadLetter) error {
+	return f(ctx, dl)
+}
+
+// Built-in DLQ handlers.
+const (
+	// DLQHandlerLog logs each dead letter and drops it
+	DLQHandlerLog = "log"
+)
+
+var (
+	dlqHandlersMu sync.RWMutex
+	dlqHandlers   = map[string]DLQHandler{
+		DLQHandlerLog: DLQHandlerFunc(logDeadLetter),
+	}
+)
+
+// RegisterDLQHandler makes a DLQ handler available to dead_letters.handler
+// under name, replacing any handler registered under the same name. Register
+// custom handlers before the tenant manager is created.
+func RegisterDLQHandler(name string, h DLQHandler) {
+	dlqHandlersMu.Lock()
+	defer dlqHandlersMu.Unlock()
+	dlqHandlers[name] = h
+}
+
+// lookupDLQHandler returns the DLQ handler registered under name.
+func lookupDLQHandler(name string) (DLQHandler, error) {
+	dlqHandlersMu.RLock()
+	defer dlqHandlersMu.RUnlock()
+
+	h, exists := dlqHandlers[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDLQHandler, name)
+	}
+	return h, nil
+}
+
+// logDeadLetter logs the dead letter without its body, which may hold fields
+// logging.redact_fields is meant to keep out of the logs.
+func logDeadLetter(ctx context.Context, dl messaging.DeadLetter) error {
+	logging.Printf("Dead letter %s of tenant %s (lane %q): %s (%d byte body)", dl.MessageID, dl.TenantID, dl.Lane, dl.Reason, len(dl.Body))
+	return nil
+}
+
+// SetDLQHandler consumes the dead letter queue of every tenant this instance
+// consumes into h, on a single channel, replacing any handler set before. A
+// nil handler stops consuming dead letters and leaves them in their queues.
+func (tm *TenantManager) SetDLQHandler(h DLQHandler) error {
+	tm.mu.Lock()
+	previous := tm.deadLetters
+	tm.deadLetters = nil
+	tm.dlqHandler = h
+	tm.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	if h == nil {
+		return nil
+	}
+	return tm.consumeDeadLetters()
+}
+
+// consumeDeadLetters opens the dead letter consumer for the current DLQ
+// handler and subscribes the tenants this instance consumes.
+func (tm *TenantManager) consumeDeadLetters() error {
+	tm.mu.RLock()
+	h := tm.dlqHandler
+	tm.mu.RUnlock()
+
+	deadLetters, err := tm.rabbitmq.ConsumeDeadLetters(func(dl messaging.DeadLetter) error {
+		return h.HandleDeadLetter(tm.ctx, dl)
+	}, tm.cfg.Retry.Backoff())
+	if err != nil {
+		return fmt.Errorf("failed to consume dead letters: %w", err)
+	}
+
+	tm.mu.Lock()
+	tm.deadLetters = deadLetters
+	tenantIDs := make([]string, 0, len(tm.consumers))
+	for tenantID := range tm.consumers {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	tm.mu.Unlock()
+
+	for _, tenantID := range tenantIDs {
+		tm.subscribeDeadLetters(tenantID)
+	}
+	return nil
+}
+
+// subscribeDeadLetters hands the tenant's dead letters to the DLQ handler,
+// if one is set.
+func (tm *TenantManager) subscribeDeadLetters(tenantID string) {
+	tm.mu.RLock()
+	deadLetters := tm.deadLetters
+	tm.mu.RUnlock()
+
+	if deadLetters == nil {
+		return
+	}
+	if err := deadLetters.Subscribe(tenantID); err != nil {
+		logging.Printf("Failed to consume dead letters of tenant %s: %v", tenantID, err)
+	}
+}
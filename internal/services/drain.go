@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"jatis/internal/models"
+)
+
+// DefaultDrainTimeout bounds how long a drained delete waits for the
+// tenant's backlog before deleting it anyway.
+const DefaultDrainTimeout = 5 * time.Minute
+
+// drainPollInterval is how often the remaining backlog is checked.
+const drainPollInterval = 100 * time.Millisecond
+
+// DrainTenant stops the tenant from accepting new messages and deletes it
+// once its pending messages have been processed and the jobs being handled
+// have settled, or once timeout has passed. The deletion runs in the background; the returned channel is
+// closed when it is done. Draining a tenant that is already draining returns
+// the channel of the running drain.
+func (tm *TenantManager) DrainTenant(tenantID string, timeout time.Duration) (<-chan struct{}, error) {
+	result, err := tm.db.Exec(
+		`UPDATE tenants SET status = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL`,
+		models.TenantStatusDraining, tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark tenant as draining: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("tenant not found")
+	}
+
+	return tm.startDrain(tenantID, timeout), nil
+}
+
+// startDrain runs a tenant's drain unless one is already running.
+func (tm *TenantManager) startDrain(tenantID string, timeout time.Duration) <-chan struct{} {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if done, running := tm.drains[tenantID]; running {
+		return done
+	}
+	done := make(chan struct{})
+	tm.drains[tenantID] = done
+	// Draining needs the tenant consumed
+	if tm.idle[tenantID] {
+		delete(tm.idle, tenantID)
+		go tm.resumeIdleTenant(tenantID)
+	}
+
+	go func() {
+		defer func() {
+			tm.mu.Lock()
+			delete(tm.drains, tenantID)
+			tm.mu.Unlock()
+			close(done)
+		}()
+		tm.drain(tenantID, timeout)
+	}()
+
+	return done
+}
+
+func (tm *TenantManager) drain(tenantID string, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending, err := tm.pendingMessages(tenantID)
+		// A message is marked processed before its delivery is settled, so
+		// wait for the workers too
+		handling := tm.handlingJobs(tenantID)
+		if err != nil {
+			log.Printf("Failed to check backlog of draining tenant %s: %v", tenantID, err)
+		} else if pending == 0 && handling == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			log.Printf("Tenant %s still had %d pending messages and %d being handled after %s, deleting anyway",
+				tenantID, pending, handling, timeout)
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-tm.ctx.Done():
+			// Shutting down; the drain resumes on the next start
+			return
+		}
+	}
+
+	// The tenant may have been deleted outright in the meantime
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return
+	}
+	if err := tm.DeleteTenant(tenantID); err != nil {
+		log.Printf("Failed to delete drained tenant %s: %v", tenantID, err)
+	}
+}
+
+// pendingMessages counts the tenant's messages that are neither processed
+// nor permanently failed.
+func (tm *TenantManager) pendingMessages(tenantID string) (int, error) {
+	var pending int
+	err := tm.db.QueryRow(
+		`SELECT COUNT(*) FROM messages WHERE tenant_id = $1 AND status = $2`,
+		tenantID, models.MessageStatusPending,
+	).Scan(&pending)
+	return pending, err
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"jatis/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// EffectiveConfig returns every setting that applies to the tenant and where
+// each one comes from. A tenant_configs column is reported as set for the
+// tenant once it has been updated through the API, even back to its default
+// value; lanes are set for the tenant when it has any. While a worker
+// schedule window is in force, workers is the count it sets. Instance
+// settings apply to every tenant and are reported under their configuration
+// key.
+func (tm *TenantManager) EffectiveConfig(tenantID string) (*models.EffectiveConfig, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	var overridden []string
+	query := `
+		SELECT to_jsonb(tc) - 'tenant_id' - 'updated_at' - 'overridden', overridden
+		FROM tenant_configs tc
+		WHERE tenant_id = $1
+	`
+	if err := tm.db.QueryRow(query, tenantID).Scan(&raw, pq.Array(&overridden)); err != nil {
+		return nil, fmt.Errorf("failed to get tenant config: %w", err)
+	}
+	var columns map[string]interface{}
+	if err := json.Unmarshal(raw, &columns); err != nil {
+		return nil, fmt.Errorf("failed to decode tenant config: %w", err)
+	}
+
+	config := &models.EffectiveConfig{
+		TenantID: tenantID,
+		Settings: make(map[string]models.EffectiveSetting, len(columns)),
+	}
+	set := make(map[string]bool, len(overridden))
+	for _, column := range overridden {
+		set[column] = true
+	}
+	for column, value := range columns {
+		source := models.SettingSourceDefault
+		if set[column] {
+			source = models.SettingSourceTenant
+		}
+		config.Settings[column] = models.EffectiveSetting{Value: value, Source: source}
+	}
+
+	schedule, err := tm.loadWorkerSchedule(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if _, inWindow := ScheduledWorkers(schedule, time.Now()); inWindow {
+		config.Settings["workers"] = models.EffectiveSetting{Value: tm.workerTarget(0, schedule), Source: models.SettingSourceSchedule}
+	}
+
+	rows, err := tm.db.Query(`SELECT name, workers FROM tenant_lanes WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lanes: %w", err)
+	}
+	defer rows.Close()
+	lanes := map[string]int{}
+	for rows.Next() {
+		var name string
+		var workers int
+		if err := rows.Scan(&name, &workers); err != nil {
+			return nil, fmt.Errorf("failed to scan lane: %w", err)
+		}
+		lanes[name] = workers
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load lanes: %w", err)
+	}
+	lanesSource := models.SettingSourceDefault
+	if len(lanes) > 0 {
+		lanesSource = models.SettingSourceTenant
+	}
+	config.Settings["lanes"] = models.EffectiveSetting{Value: lanes, Source: lanesSource}
+
+	instance := map[string]interface{}{
+		"consumer.max_attempts":              tm.cfg.Consumer.MaxAttempts,
+		"consumer.max_redeliveries":          tm.cfg.Consumer.MaxRedeliveries,
+		"consumer.visibility_timeout":        tm.cfg.Consumer.VisibilityTimeout.String(),
+		"consumer.job_timeout":               tm.cfg.Consumer.JobTimeout.String(),
+		"consumer.idle_timeout":              tm.cfg.Consumer.IdleTimeout.String(),
+		"api.max_concurrent_message_creates": tm.cfg.API.MaxConcurrentMessageCreates,
+		"api.max_created_at_skew":            tm.cfg.API.MaxCreatedAtSkew.String(),
+		"throughput.max_per_second":          tm.cfg.Throughput.MaxPerSecond,
+	}
+	for key, value := range instance {
+		config.Settings[key] = models.EffectiveSetting{Value: value, Source: models.SettingSourceInstance}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notExpired keeps messages past their expires_at out of reads until the
+// retention job deletes them.
+const notExpired = "(expires_at IS NULL OR expires_at > NOW())"
+
+// retentionWorker deletes expired messages every interval until Shutdown.
+func (tm *TenantManager) retentionWorker(interval time.Duration) {
+	defer close(tm.retentionExited)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := tm.PurgeExpiredMessages(); err != nil {
+				log.Printf("Deleting expired messages failed: %v", err)
+			}
+			if _, err := tm.PruneMessageCounts(); err != nil {
+				log.Printf("Dropping expired message counts failed: %v", err)
+			}
+		case <-tm.retentionDone:
+			return
+		}
+	}
+}
+
+// PurgeExpiredMessages deletes every message whose expires_at has passed,
+// across all tenants, along with their offloaded payloads, and returns how
+// many were deleted.
+func (tm *TenantManager) PurgeExpiredMessages() (int64, error) {
+	query := `
+		WITH deleted AS (
+			DELETE FROM messages WHERE expires_at <= NOW() RETURNING payload_ref
+		)
+		SELECT COUNT(*), COALESCE(array_agg(payload_ref) FILTER (WHERE payload_ref IS NOT NULL), '{}') FROM deleted
+	`
+	var deleted int64
+	var blobs []string
+	if err := tm.db.QueryRow(query).Scan(&deleted, pq.Array(&blobs)); err != nil {
+		return 0, fmt.Errorf("failed to delete expired messages: %w", err)
+	}
+	deleteBlobs(tm.blobs, blobs)
+	if deleted > 0 {
+		log.Printf("Deleted %d expired messages", deleted)
+	}
+	return deleted, nil
+}
+
+// PruneMessageCounts drops the per-minute message counters older than the
+// 24h window of approximate stats, across all tenants, and returns how many
+// were dropped.
+func (tm *TenantManager) PruneMessageCounts() (int64, error) {
+	result, err := tm.db.Exec(`DELETE FROM message_counts WHERE bucket < date_trunc('minute', NOW() - INTERVAL '24 hours')`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop expired message counts: %w", err)
+	}
+	return result.RowsAffected()
+}
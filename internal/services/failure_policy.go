@@ -0,0 +1,57 @@
+package services
+
+import "jatis/internal/models"
+
+// Settlement is how a delivery is settled with the broker.
+type Settlement int
+
+const (
+	// SettleAck acknowledges the delivery, removing it from the queue
+	SettleAck Settlement = iota
+	// SettleRequeue returns the delivery to the queue for another attempt
+	SettleRequeue
+	// SettleDeadLetter rejects the delivery without requeueing it
+	SettleDeadLetter
+)
+
+func (s Settlement) String() string {
+	switch s {
+	case SettleAck:
+		return "ack"
+	case SettleRequeue:
+		return "requeue"
+	case SettleDeadLetter:
+		return "dead-letter"
+	}
+	return "unknown"
+}
+
+// FailureSettlement decides how to settle a message whose processing failed
+// under the given tenant failure policy, after attempts of maxAttempts.
+// Unknown policies fall back to retry_then_dlq.
+func FailureSettlement(policy string, attempts, maxAttempts int) Settlement {
+	switch policy {
+	case models.FailurePolicyDLQ:
+		return SettleDeadLetter
+	case models.FailurePolicyRetry:
+		return SettleRequeue
+	case models.FailurePolicyDrop:
+		return SettleAck
+	}
+
+	if attempts < maxAttempts {
+		return SettleRequeue
+	}
+	return SettleDeadLetter
+}
+
+// jobFailure is a failed job together with how its delivery is to be
+// settled. Plain handler errors are dead-lettered.
+type jobFailure struct {
+	err        error
+	settlement Settlement
+}
+
+func (e *jobFailure) Error() string { return e.err.Error() }
+
+func (e *jobFailure) Unwrap() error { return e.err }
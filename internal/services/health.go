@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"jatis/internal/messaging"
+	"jatis/internal/models"
+)
+
+// healthCheckTimeout bounds a single dependency check, so a hung dependency
+// is reported as down rather than stalling the refresh.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck checks that a dependency is reachable.
+type HealthCheck func(ctx context.Context) error
+
+// DependencyChecks returns the checks readiness is based on: a Postgres ping
+// and the state of the RabbitMQ connection.
+func DependencyChecks(db *sql.DB, rabbitmq *messaging.RabbitMQ) map[string]HealthCheck {
+	return map[string]HealthCheck{
+		models.DependencyPostgres: db.PingContext,
+		models.DependencyRabbitMQ: func(ctx context.Context) error {
+			if rabbitmq.IsClosed() {
+				return messaging.ErrConnectionClosed
+			}
+			return nil
+		},
+	}
+}
+
+// Readiness returns the latest result of the dependency checks, refreshed
+// every health.cache_ttl.
+func (tm *TenantManager) Readiness() models.ReadinessReport {
+	return tm.health.Report()
+}
+
+// startupBacklogCheck keeps readiness failing until fewer than threshold
+// messages wait for the tenants this instance consumes, or maxWarmup has
+// passed. Once it has passed it stays passed, so a later backlog does not
+// take the instance out of rotation.
+func (tm *TenantManager) startupBacklogCheck(threshold int, maxWarmup time.Duration) HealthCheck {
+	started := time.Now()
+	var passed atomic.Bool
+	return func(ctx context.Context) error {
+		if passed.Load() {
+			return nil
+		}
+		if maxWarmup > 0 && time.Since(started) >= maxWarmup {
+			passed.Store(true)
+			return nil
+		}
+		backlog, err := tm.backlog()
+		if err != nil {
+			return err
+		}
+		if backlog >= threshold {
+			return fmt.Errorf("warming up: %d messages waiting, ready below %d", backlog, threshold)
+		}
+		passed.Store(true)
+		return nil
+	}
+}
+
+// backlog counts the messages waiting for the tenants this instance
+// consumes: those still ready in their queues on the broker, lanes
+// included, and those already delivered and queued in the worker pools.
+func (tm *TenantManager) backlog() (int, error) {
+	type queue struct{ tenantID, lane string }
+	tm.mu.RLock()
+	var queues []queue
+	for tenantID := range tm.consumers {
+		queues = append(queues, queue{tenantID: tenantID})
+		for name := range tm.lanes[tenantID] {
+			queues = append(queues, queue{tenantID: tenantID, lane: name})
+		}
+	}
+	backlog := 0
+	for _, pool := range tm.workerPools {
+		backlog += pool.Queued()
+	}
+	for _, tenantLanes := range tm.lanes {
+		for _, l := range tenantLanes {
+			backlog += l.pool.Queued()
+		}
+	}
+	if tm.sharedPool != nil {
+		backlog += tm.sharedPool.Queued()
+	}
+	tm.mu.RUnlock()
+
+	for _, q := range queues {
+		stats, err := tm.rabbitmq.InspectQueue(q.tenantID, q.lane)
+		if errors.Is(err, messaging.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect queue of tenant %s: %w", q.tenantID, err)
+		}
+		backlog += stats.Messages
+	}
+	return backlog, nil
+}
+
+// HealthChecker runs dependency checks in the background and serves their
+// last result, so frequent readiness probes do not each hit the
+// dependencies. The result is at most one TTL old, plus the time the checks
+// take.
+type HealthChecker struct {
+	checks map[string]HealthCheck
+	names  []string
+	ttl    time.Duration
+	mu     sync.RWMutex
+	report models.ReadinessReport
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewHealthChecker runs checks once, then again every ttl until Stop. A zero
+// ttl disables the cache: every Report runs the checks.
+func NewHealthChecker(ttl time.Duration, checks map[string]HealthCheck) *HealthChecker {
+	h := &HealthChecker{
+		checks: checks,
+		ttl:    ttl,
+		done:   make(chan struct{}),
+	}
+	for name := range checks {
+		h.names = append(h.names, name)
+	}
+	sort.Strings(h.names)
+
+	if ttl > 0 {
+		h.refresh()
+		go h.refreshWorker()
+	}
+	return h
+}
+
+// Report returns the result of the latest checks.
+func (h *HealthChecker) Report() models.ReadinessReport {
+	if h.ttl <= 0 {
+		return h.run()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.report
+}
+
+// Stop stops the background refresh.
+func (h *HealthChecker) Stop() {
+	h.once.Do(func() { close(h.done) })
+}
+
+func (h *HealthChecker) refreshWorker() {
+	ticker := time.NewTicker(h.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.refresh()
+		case <-h.done:
+			return
+		}
+	}
+}
+
+func (h *HealthChecker) refresh() {
+	report := h.run()
+
+	h.mu.Lock()
+	h.report = report
+	h.mu.Unlock()
+}
+
+// run runs every check.
+func (h *HealthChecker) run() models.ReadinessReport {
+	report := models.ReadinessReport{
+		Ready:        true,
+		Dependencies: make(map[string]string, len(h.names)),
+		CheckedAt:    time.Now().UTC(),
+	}
+	for _, name := range h.names {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		err := h.checks[name](ctx)
+		cancel()
+
+		if err != nil {
+			report.Ready = false
+			report.Dependencies[name] = err.Error()
+			continue
+		}
+		report.Dependencies[name] = models.DependencyOK
+	}
+	return report
+}
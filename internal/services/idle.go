@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"jatis/internal/messaging"
+)
+
+// idleWorker stops the consumers of idle tenants, and restarts idle tenants
+// whose queues have messages again, every interval until Shutdown.
+func (tm *TenantManager) idleWorker(interval time.Duration) {
+	defer close(tm.idleExited)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tm.stopIdleTenants()
+			tm.resumeQueuedIdleTenants()
+		case <-tm.idleDone:
+			return
+		}
+	}
+}
+
+// stopIdleTenants stops the consumers and worker pools of every tenant that
+// has had no deliveries and finished no jobs for consumer.idle_timeout.
+// Tenants with jobs still in a dedicated pool, and tenants being drained,
+// are left running.
+func (tm *TenantManager) stopIdleTenants() {
+	cutoff := time.Now().Add(-tm.cfg.Consumer.IdleTimeout).UnixNano()
+
+	tm.mu.RLock()
+	var idle []string
+	for tenantID := range tm.consumers {
+		if tm.tenantIdle(tenantID, cutoff) {
+			idle = append(idle, tenantID)
+		}
+	}
+	tm.mu.RUnlock()
+
+	for _, tenantID := range idle {
+		if tm.stopIdleTenant(tenantID, cutoff) {
+			log.Printf("Stopped consumer of tenant %s after %s without messages", tenantID, tm.cfg.Consumer.IdleTimeout)
+		}
+	}
+}
+
+// stopIdleTenant stops the tenant's consumers if it is still idle, and
+// reports whether it did. The idle mark is set as the consumers are taken
+// out, under the tenant's consumer lock, so a message published meanwhile
+// finds the mark and restarts the tenant once it is stopped.
+func (tm *TenantManager) stopIdleTenant(tenantID string, cutoff int64) bool {
+	unlock := tm.consumerLocks.lock(tenantID)
+	defer unlock()
+
+	tm.mu.Lock()
+	if _, running := tm.consumers[tenantID]; !running || !tm.tenantIdle(tenantID, cutoff) {
+		tm.mu.Unlock()
+		return false
+	}
+	consumers, pools := tm.detachTenantConsumer(tenantID)
+	tm.idle[tenantID] = true
+	tm.mu.Unlock()
+
+	tm.stopDetached(tenantID, consumers, pools)
+	return true
+}
+
+// tenantIdle reports whether the tenant has had no deliveries since cutoff,
+// has no jobs left in its pools and is not being drained. The caller must
+// hold tm.mu.
+func (tm *TenantManager) tenantIdle(tenantID string, cutoff int64) bool {
+	counts := tm.deliveries[tenantID]
+	if counts == nil || atomic.LoadInt64(&counts.lastActive) > cutoff {
+		return false
+	}
+	if _, draining := tm.drains[tenantID]; draining {
+		return false
+	}
+	return tm.poolsIdle(tenantID)
+}
+
+// poolsIdle reports whether the tenant's dedicated and lane pools have no
+// jobs queued or running. The caller must hold tm.mu.
+func (tm *TenantManager) poolsIdle(tenantID string) bool {
+	pools := []*WorkerPool{}
+	if pool, exists := tm.workerPools[tenantID]; exists {
+		pools = append(pools, pool)
+	}
+	for _, l := range tm.lanes[tenantID] {
+		pools = append(pools, l.pool)
+	}
+	for _, pool := range pools {
+		if pool.Queued() > 0 || pool.Busy() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// resumeQueuedIdleTenants restarts the idle tenants that have messages
+// waiting in their main or lane queues, such as those published by another
+// instance, which wakeIdleTenant does not see.
+func (tm *TenantManager) resumeQueuedIdleTenants() {
+	tm.mu.RLock()
+	idle := make([]string, 0, len(tm.idle))
+	for tenantID := range tm.idle {
+		idle = append(idle, tenantID)
+	}
+	tm.mu.RUnlock()
+
+	for _, tenantID := range idle {
+		queued, err := tm.hasQueuedMessages(tenantID)
+		if err != nil {
+			log.Printf("Failed to check queues of idle tenant %s: %v", tenantID, err)
+			continue
+		}
+		if queued && tm.takeIdle(tenantID) {
+			tm.resumeIdleTenant(tenantID)
+		}
+	}
+}
+
+// hasQueuedMessages reports whether any of the tenant's queues, lanes
+// included, has messages ready.
+func (tm *TenantManager) hasQueuedMessages(tenantID string) (bool, error) {
+	lanes := []string{""}
+	rows, err := tm.db.Query(`SELECT name FROM tenant_lanes WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		lanes = append(lanes, name)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, lane := range lanes {
+		stats, err := tm.rabbitmq.InspectQueue(tenantID, lane)
+		if errors.Is(err, messaging.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if stats.Messages > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// wakeIdleTenant restarts the tenant's consumers if they were stopped for
+// being idle. It is called for every message published, so the restart
+// happens in the background.
+func (tm *TenantManager) wakeIdleTenant(tenantID string) {
+	if tm.takeIdle(tenantID) {
+		go tm.resumeIdleTenant(tenantID)
+	}
+}
+
+// takeIdle clears the tenant's idle mark, and reports whether it had one.
+// Only the caller that clears it restarts the tenant.
+func (tm *TenantManager) takeIdle(tenantID string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if !tm.idle[tenantID] {
+		return false
+	}
+	delete(tm.idle, tenantID)
+	return true
+}
+
+// resumeIdleTenant starts consuming an idle tenant again, unless it has been
+// deleted or the manager is shutting down in the meantime.
+func (tm *TenantManager) resumeIdleTenant(tenantID string) {
+	if tm.ctx.Err() != nil {
+		return
+	}
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return
+	}
+	if err := tm.startTenantConsumer(tenantID); err != nil {
+		log.Printf("Failed to restart consumer of idle tenant %s: %v", tenantID, err)
+		// Try again on the next check
+		tm.mu.Lock()
+		tm.idle[tenantID] = true
+		tm.mu.Unlock()
+		return
+	}
+	log.Printf("Restarted consumer of idle tenant %s", tenantID)
+}
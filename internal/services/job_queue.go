@@ -0,0 +1,131 @@
+package services
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned when a job is submitted to a worker pool whose
+// queue is at capacity.
+var ErrQueueFull = errors.New("worker pool queue is full")
+
+// jobQueue is a bounded priority queue. Higher priority jobs are dequeued
+// first; jobs of equal priority keep their submission order, unless the queue
+// is fair, in which case they are interleaved across tenants by weight.
+type jobQueue struct {
+	mu       sync.Mutex
+	items    jobHeap
+	capacity int
+	seq      uint64
+	// weight is set for fair queues and tags hands out their fairness tags
+	weight func(tenantID string) int
+	tags   virtualClock
+	// ready holds one token per queued job so workers can select on it
+	// alongside their quit signal.
+	ready chan struct{}
+}
+
+func newJobQueue(capacity int) *jobQueue {
+	return &jobQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, capacity),
+		tags:     newVirtualClock(),
+	}
+}
+
+// newFairJobQueue returns a queue that interleaves jobs of equal priority
+// across tenants in proportion to their weight.
+func newFairJobQueue(capacity int, weight func(tenantID string) int) *jobQueue {
+	q := newJobQueue(capacity)
+	q.weight = weight
+	return q
+}
+
+func (q *jobQueue) push(job Job) error {
+	q.mu.Lock()
+	if len(q.items) >= q.capacity {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+	q.seq++
+	item := queuedJob{job: job, seq: q.seq}
+	if q.weight != nil {
+		item.tag = q.tags.next(job.TenantID, q.weight(job.TenantID))
+	}
+	heap.Push(&q.items, item)
+	q.mu.Unlock()
+
+	q.ready <- struct{}{}
+	return nil
+}
+
+// pop removes the highest priority job. Callers must first receive a token
+// from ready. The queue can still turn out empty if drain took the job in the
+// meantime, in which case ok is false.
+func (q *jobQueue) pop() (job Job, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return Job{}, false
+	}
+	item := heap.Pop(&q.items).(queuedJob)
+	q.tags.advance(item.tag)
+	return item.job, true
+}
+
+// len returns the number of queued jobs.
+func (q *jobQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// drain removes and returns every queued job, highest priority first.
+func (q *jobQueue) drain() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.items))
+	for len(q.items) > 0 {
+		jobs = append(jobs, heap.Pop(&q.items).(queuedJob).job)
+		// Take back the job's token if no worker has claimed it yet
+		select {
+		case <-q.ready:
+		default:
+		}
+	}
+	return jobs
+}
+
+type queuedJob struct {
+	job Job
+	seq uint64
+	tag uint64
+}
+
+type jobHeap []queuedJob
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].job.Priority != h[j].job.Priority {
+		return h[i].job.Priority > h[j].job.Priority
+	}
+	if h[i].tag != h[j].tag {
+		return h[i].tag < h[j].tag
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(queuedJob)) }
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
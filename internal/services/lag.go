@@ -0,0 +1,119 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"jatis/internal/config"
+	"jatis/internal/messaging"
+	"jatis/internal/models"
+)
+
+// Lag reports the tenant's queue depth, the age of its oldest pending
+// message and the utilization of its workers, classified against the lag
+// thresholds. Queue depth and utilization cover this instance's pools only.
+func (tm *TenantManager) Lag(tenantID string) (*models.TenantLag, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	lanes, err := tm.laneNames(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	lag := &models.TenantLag{TenantID: tenantID}
+	for _, lane := range append([]string{""}, lanes...) {
+		stats, err := tm.rabbitmq.InspectQueue(tenantID, lane)
+		if err != nil && !errors.Is(err, messaging.ErrQueueNotFound) {
+			return nil, err
+		}
+		lag.QueueDepth += stats.Messages
+	}
+
+	var age sql.NullFloat64
+	query := `SELECT EXTRACT(EPOCH FROM NOW() - MIN(created_at)) FROM messages WHERE tenant_id = $1 AND status = $2`
+	if err := tm.db.QueryRow(query, tenantID, models.MessageStatusPending).Scan(&age); err != nil {
+		return nil, fmt.Errorf("failed to get oldest pending message: %w", err)
+	}
+	lag.OldestPendingAgeSeconds = max(age.Float64, 0)
+
+	tm.mu.RLock()
+	pools := []*WorkerPool{}
+	if pool, dedicated := tm.workerPools[tenantID]; dedicated {
+		pools = append(pools, pool)
+		lag.QueueDepth += pool.Queued()
+	} else if tm.sharedPool != nil {
+		pools = append(pools, tm.sharedPool)
+	}
+	for _, l := range tm.lanes[tenantID] {
+		pools = append(pools, l.pool)
+		lag.QueueDepth += l.pool.Queued()
+	}
+	tm.mu.RUnlock()
+
+	var busy, workers int32
+	for _, pool := range pools {
+		busy += pool.Busy()
+		workers += pool.Workers()
+	}
+	if workers > 0 {
+		lag.WorkerUtilization = min(float64(busy)/float64(workers), 1)
+	}
+
+	ClassifyLag(lag, tm.cfg.Lag)
+	return lag, nil
+}
+
+// ClassifyLag sets lag.Status and lag.Reasons from its indicators: the worst
+// status any indicator reaches wins.
+func ClassifyLag(lag *models.TenantLag, thresholds config.LagConfig) {
+	lag.Status = models.LagHealthy
+	lag.Reasons = []string{}
+
+	format := func(v float64, unit string) string {
+		return strconv.FormatFloat(v, 'f', -1, 64) + unit
+	}
+	check := func(indicator string, value, degraded, overloaded float64, unit string) {
+		switch {
+		case overloaded > 0 && value >= overloaded:
+			lag.Status = models.LagOverloaded
+			lag.Reasons = append(lag.Reasons, fmt.Sprintf("%s %s reached the overloaded threshold of %s",
+				indicator, format(value, unit), format(overloaded, unit)))
+		case degraded > 0 && value >= degraded:
+			if lag.Status == models.LagHealthy {
+				lag.Status = models.LagDegraded
+			}
+			lag.Reasons = append(lag.Reasons, fmt.Sprintf("%s %s reached the degraded threshold of %s",
+				indicator, format(value, unit), format(degraded, unit)))
+		}
+	}
+
+	check("queue depth", float64(lag.QueueDepth),
+		float64(thresholds.DegradedQueueDepth), float64(thresholds.OverloadedQueueDepth), "")
+	check("oldest pending message age", lag.OldestPendingAgeSeconds,
+		thresholds.DegradedPendingAge.Seconds(), thresholds.OverloadedPendingAge.Seconds(), "s")
+	check("worker utilization", lag.WorkerUtilization,
+		thresholds.DegradedUtilization, thresholds.OverloadedUtilization, "")
+}
+
+// laneNames returns the names of the tenant's stored lanes.
+func (tm *TenantManager) laneNames(tenantID string) ([]string, error) {
+	rows, err := tm.db.Query(`SELECT name FROM tenant_lanes WHERE tenant_id = $1 ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lanes: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan lane: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+
+	"jatis/internal/messaging"
+)
+
+var (
+	ErrInvalidLane = errors.New("invalid lane name")
+	ErrUnknownLane = errors.New("lane not found")
+)
+
+// laneNamePattern limits lane names to what can safely appear in a queue
+// name and a consumer tag.
+var laneNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// lane is a named sub-queue of a tenant, such as "realtime" or "batch",
+// consumed into a worker pool of its own.
+type lane struct {
+	consumer *messaging.Consumer
+	pool     *WorkerPool
+}
+
+// checkLane returns ErrInvalidLane for a name that cannot be used, and
+// ErrTooManyWorkers for workers above max_workers.
+func (tm *TenantManager) checkLane(name string, workers int) error {
+	if !laneNamePattern.MatchString(name) {
+		return ErrInvalidLane
+	}
+	return tm.checkWorkers(workers)
+}
+
+// UpdateLane creates a lane for the tenant, or resizes the worker pool of an
+// existing one. A new lane is consumed straight away.
+func (tm *TenantManager) UpdateLane(tenantID, name string, workers int) error {
+	if err := tm.checkLane(name, workers); err != nil {
+		return err
+	}
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tenant_lanes (tenant_id, name, workers) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, name) DO UPDATE SET workers = EXCLUDED.workers
+	`
+	if _, err := tm.db.Exec(query, tenantID, name, workers); err != nil {
+		return fmt.Errorf("failed to update lane: %w", err)
+	}
+
+	// Another instance consumes the tenant and starts the lane itself
+	if tm.cfg.Cluster.Enabled && !tm.ownsTenant(tenantID) {
+		return nil
+	}
+
+	tm.mu.Lock()
+	if l, exists := tm.lanes[tenantID][name]; exists {
+		l.pool.UpdateWorkers(int32(workers))
+		tm.mu.Unlock()
+		return nil
+	}
+	tm.mu.Unlock()
+
+	return tm.startLane(tenantID, name, workers)
+}
+
+// RemoveLane stops a lane's consumer and worker pool. Unfinished messages go
+// back to the lane queue, which is deleted only once it is empty; adding the
+// lane again resumes it.
+func (tm *TenantManager) RemoveLane(tenantID, name string) error {
+	result, err := tm.db.Exec(`DELETE FROM tenant_lanes WHERE tenant_id = $1 AND name = $2`, tenantID, name)
+	if err != nil {
+		return fmt.Errorf("failed to remove lane: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		if _, err := tm.GetTenant(tenantID); err != nil {
+			return err
+		}
+		return ErrUnknownLane
+	}
+
+	tm.mu.Lock()
+	l, exists := tm.lanes[tenantID][name]
+	delete(tm.lanes[tenantID], name)
+	tm.mu.Unlock()
+
+	if exists {
+		l.stop()
+	}
+
+	if err := tm.rabbitmq.DeleteLaneQueue(tenantID, name); err != nil {
+		log.Printf("Warning: kept queue of lane %s for tenant %s: %v", name, tenantID, err)
+	}
+
+	return nil
+}
+
+// startLanes starts every lane configured for the tenant.
+func (tm *TenantManager) startLanes(tenantID string) error {
+	rows, err := tm.db.Query(`SELECT name, workers FROM tenant_lanes WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load lanes: %w", err)
+	}
+
+	lanes := map[string]int{}
+	for rows.Next() {
+		var name string
+		var workers int
+		if err := rows.Scan(&name, &workers); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan lane: %w", err)
+		}
+		lanes[name] = workers
+	}
+	rows.Close()
+
+	for name, workers := range lanes {
+		if err := tm.startLane(tenantID, name, workers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startLane declares a lane's queue and consumes it into a new worker pool.
+func (tm *TenantManager) startLane(tenantID, name string, workers int) error {
+	consumer, err := tm.rabbitmq.CreateQueue(tenantID, name, tm.tenantQueueOptions(tenantID))
+	if err != nil {
+		return fmt.Errorf("failed to start lane %s: %w", name, err)
+	}
+
+	tm.mu.Lock()
+	if existing, exists := tm.lanes[tenantID][name]; exists {
+		// Started concurrently; keep the running lane
+		tm.mu.Unlock()
+		consumer.Stop()
+		existing.pool.UpdateWorkers(int32(workers))
+		return nil
+	}
+	l := &lane{pool: tm.newTenantPool(workers)}
+	if tm.lanes[tenantID] == nil {
+		tm.lanes[tenantID] = make(map[string]*lane)
+	}
+	tm.lanes[tenantID][name] = l
+	tm.mu.Unlock()
+
+	tm.consumeLane(tenantID, name, l, consumer)
+	return nil
+}
+
+// consumeLane starts consumer, handing its deliveries to the lane's pool.
+func (tm *TenantManager) consumeLane(tenantID, name string, l *lane, consumer *messaging.Consumer) {
+	tm.mu.Lock()
+	l.consumer = consumer
+	tm.mu.Unlock()
+
+	consumer.BatchAcks(tm.cfg.Consumer.AckBatchSize, tm.cfg.Consumer.AckBatchInterval)
+	consumer.Start(tm.cfg.Consumer.VisibilityTimeout, func(lease *messaging.Lease) error {
+		tm.recordDelivery(tenantID, name, lease)
+		if tm.poisoned(tenantID, lease) || tm.invalid(tenantID, lease) || tm.sampleOut(tenantID, lease) {
+			return nil
+		}
+		tm.mu.RLock()
+		job := tm.tenantJob(tenantID, lease)
+		tm.mu.RUnlock()
+		job.Lane = name
+		return l.pool.Submit(job)
+	})
+}
+
+// stop shuts the lane down like DeleteTenant does a tenant: the consumer
+// first, so the broker takes back its unacknowledged deliveries, then the
+// pool. Jobs left in the pool's queue are abandoned.
+func (l *lane) stop() {
+	if l.consumer != nil {
+		l.consumer.Stop()
+	}
+	l.pool.Stop()
+	for _, job := range l.pool.jobQueue.drain() {
+		if job.lease != nil {
+			job.lease.Abandon()
+		}
+	}
+}
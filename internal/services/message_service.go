@@ -1,18 +1,89 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"jatis/internal/config"
+	"jatis/internal/database"
+	"jatis/internal/logging"
+	"jatis/internal/messaging"
+	"jatis/internal/metrics"
 	"jatis/internal/models"
 
 	"github.com/google/uuid"
 )
 
 type MessageService struct {
-	db *sql.DB
+	db        *sql.DB
+	rabbitmq  *messaging.RabbitMQ
+	cfg       *config.Config
+	lifecycle *logging.Lifecycle
+	// creates bounds each tenant's concurrent message creations
+	// (api.max_concurrent_message_creates); nil means no bound
+	creates *TenantSemaphores
+	// blobs holds payloads above payload_storage.offload_threshold; nil
+	// stores every payload inline
+	blobs BlobStore
+}
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidStatus = errors.New("invalid message status")
+	ErrInvalidRange  = errors.New("invalid throughput range")
+	// ErrTenantDraining is returned for messages sent to a tenant that is
+	// being drained for deletion
+	ErrTenantDraining = errors.New("tenant is being deleted")
+	// ErrTooManyMessageCreates is returned when a message create waited
+	// longer than api.message_create_wait_timeout for its tenant to get
+	// under api.max_concurrent_message_creates
+	ErrTooManyMessageCreates = errors.New("too many message creations in progress for tenant")
+	// ErrEmptyPayload is returned for a message without a payload sent to a
+	// tenant that does not have allow_empty_payload set
+	ErrEmptyPayload = errors.New("payload is required")
+	// ErrRawPayloadNotKept is returned for the raw payload of a message
+	// whose tenant did not have keep_raw_payload set when it was created
+	ErrRawPayloadNotKept = errors.New("raw payload not kept")
+	// ErrInvalidMessageID is returned for a client-supplied message ID that
+	// is not a UUID
+	ErrInvalidMessageID = errors.New("invalid message id")
+	// ErrInvalidCreatedAt is returned for a client-supplied created_at
+	// further in the future than api.max_created_at_skew
+	ErrInvalidCreatedAt = errors.New("invalid created_at")
+	// ErrInvalidExpiresAt is returned for a client-supplied expires_at that
+	// has already passed
+	ErrInvalidExpiresAt = errors.New("invalid expires_at")
+	// ErrDuplicateMessage is returned for a client-supplied message ID the
+	// tenant already has a message with
+	ErrDuplicateMessage = errors.New("message already exists")
+)
+
+// maxThroughputBuckets caps the size of a throughput series or histogram.
+const maxThroughputBuckets = 1000
+
+// throughputIntervals is the allowlist of bucket sizes and their lengths.
+var throughputIntervals = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// BucketEnd returns the end of the UTC-aligned bucket holding t, so a range
+// ending there takes in t's bucket whole and stays the same until the next
+// bucket starts. An unknown bucket size returns t unchanged.
+func BucketEnd(bucket string, t time.Time) time.Time {
+	length, ok := throughputIntervals[bucket]
+	if !ok {
+		return t
+	}
+	return t.UTC().Truncate(length).Add(length)
 }
 
 type PaginatedMessages struct {
@@ -20,100 +91,337 @@ type PaginatedMessages struct {
 	NextCursor *string           `json:"next_cursor"`
 }
 
-func NewMessageService(db *sql.DB) *MessageService {
-	return &MessageService{db: db}
+// MessageFilter narrows a cross-tenant message query.
+type MessageFilter struct {
+	From   *time.Time
+	To     *time.Time
+	Status string
+	Source string
+	Cursor string
+	Limit  int
 }
 
-func (ms *MessageService) CreateMessage(tenantID string, payload interface{}) (*models.Message, error) {
-	messageID := uuid.New().String()
+func NewMessageService(db *sql.DB, rabbitmq *messaging.RabbitMQ, cfg *config.Config) *MessageService {
+	ms := &MessageService{
+		db:        db,
+		rabbitmq:  rabbitmq,
+		cfg:       cfg,
+		lifecycle: logging.NewLifecycle(cfg.Logging.Lifecycle, log.Default()),
+	}
+	if cfg.API.MaxConcurrentMessageCreates > 0 {
+		ms.creates = NewTenantSemaphores(cfg.API.MaxConcurrentMessageCreates)
+	}
+	if blobs, err := payloadBlobStore(cfg); err != nil {
+		log.Printf("Warning: payloads are stored inline: %v", err)
+	} else {
+		ms.blobs = blobs
+	}
+	return ms
+}
+
+// CreateMessage stores a message and publishes it to the tenant's queue. A
+// created_at in the request backfills the message with that time.
+// Creates beyond api.max_concurrent_message_creates for the tenant wait for
+// their turn, and fail with ErrTooManyMessageCreates once
+// api.message_create_wait_timeout has passed.
+func (ms *MessageService) CreateMessage(tenantID string, req *models.CreateMessageRequest) (*models.Message, error) {
+	if ms.creates != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), ms.cfg.API.MessageCreateWaitTimeout)
+		release, err := ms.creates.Acquire(ctx, tenantID)
+		cancel()
+		if err != nil {
+			return nil, ErrTooManyMessageCreates
+		}
+		defer release()
+	}
+
+	messageID, err := newMessageID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	var createdAt sql.NullTime
+	if req.CreatedAt != nil {
+		if skew := ms.cfg.API.MaxCreatedAtSkew; req.CreatedAt.After(time.Now().Add(skew)) {
+			return nil, fmt.Errorf("%w: %s is more than %s in the future", ErrInvalidCreatedAt,
+				req.CreatedAt.UTC().Format(time.RFC3339Nano), skew)
+		}
+		createdAt = sql.NullTime{Time: req.CreatedAt.UTC(), Valid: true}
+	}
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: %s has already passed", ErrInvalidExpiresAt, req.ExpiresAt.UTC().Format(time.RFC3339Nano))
+		}
+		expiresAt = sql.NullTime{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+
+	ingest, err := ms.tenantIngest(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	received := req.Payload
+	if received == nil {
+		if !ingest.allowEmptyPayload {
+			return nil, ErrEmptyPayload
+		}
+		received = map[string]interface{}{}
+	}
+
+	// Apply the tenant's transforms; the payload as received is stored too
+	// if the tenant keeps it
+	payload, err := ApplyTransforms(received, ingest.transforms)
+	if err != nil {
+		return nil, err
+	}
+	var rawPayloadBytes []byte
+	if ingest.keepRaw {
+		rawPayloadBytes, err = json.Marshal(received)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal raw payload: %w", err)
+		}
+	}
 	
 	// Convert payload to JSON
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
+
+	var metadataBytes []byte
+	if req.Metadata != nil {
+		metadataBytes, err = json.Marshal(req.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
 	
+	correlationID := req.CorrelationID
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+
+	var lane sql.NullString
+	if req.Lane != "" {
+		if err := ms.checkLane(tenantID, req.Lane); err != nil {
+			return nil, err
+		}
+		lane = sql.NullString{String: req.Lane, Valid: true}
+	}
+	
+	// Payloads above payload_storage.offload_threshold go to the blob store,
+	// under a key of their own so a rejected insert never touches another
+	// message's blob
+	storedPayload := payloadBytes
+	var payloadRef sql.NullString
+	if ms.blobs != nil && len(payloadBytes) > ms.cfg.PayloadStorage.OffloadThreshold {
+		key := tenantID + "/" + uuid.New().String()
+		if err := ms.blobs.Put(context.Background(), key, payloadBytes); err != nil {
+			return nil, fmt.Errorf("failed to offload payload: %w", err)
+		}
+		storedPayload = nil
+		payloadRef = sql.NullString{String: key, Valid: true}
+	}
+	created := false
+	defer func() {
+		if payloadRef.Valid && !created {
+			ms.deleteBlob(payloadRef.String)
+		}
+	}()
+
+	// Nothing is inserted for a draining or soft-deleted tenant
 	query := `
-		INSERT INTO messages (id, tenant_id, payload) 
-		VALUES ($1, $2, $3) 
-		RETURNING created_at
+		INSERT INTO messages (id, tenant_id, payload, metadata, correlation_id, lane, raw_payload, created_at, expires_at, payload_ref) 
+		SELECT $1::uuid, $2::uuid, $3::jsonb, $4::jsonb, $5::varchar, $6::varchar, $7::jsonb, COALESCE($8::timestamptz, NOW()), $9::timestamptz, $10::text
+		WHERE NOT EXISTS (SELECT 1 FROM tenants WHERE id = $2::uuid AND (status = 'draining' OR deleted_at IS NOT NULL))
+		RETURNING status, created_at
 	`
 	
 	var message models.Message
 	message.ID = messageID
 	message.TenantID = tenantID
 	message.Payload = payload
+	message.Metadata = req.Metadata
+	message.CorrelationID = correlationID
+	message.Lane = req.Lane
+	if expiresAt.Valid {
+		message.ExpiresAt = &expiresAt.Time
+	}
+
+	insert := func() error {
+		return database.Retry(ms.cfg.Retry.Backoff(), ms.cfg.Database.MaxRetries, func() error {
+			return ms.db.QueryRow(query, messageID, tenantID, storedPayload, metadataBytes, correlationID, lane, rawPayloadBytes, createdAt, expiresAt, payloadRef).Scan(&message.Status, &message.CreatedAt)
+		})
+	}
 
-	err = ms.db.QueryRow(query, messageID, tenantID, payloadBytes).Scan(&message.CreatedAt)
+	err = insert()
+	if database.IsMissingPartition(err) {
+		// The tenant's partition has not been created yet (see
+		// database.async_partitions); create it now and try again
+		if err := ms.ensurePartition(tenantID); err != nil {
+			return nil, err
+		}
+		err = insert()
+	}
+	if err == sql.ErrNoRows {
+		if err := ms.checkTenant(tenantID); err != nil {
+			return nil, err
+		}
+		return nil, ErrTenantDraining
+	}
+	if database.IsUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateMessage, messageID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
+	message.CreatedAt = message.CreatedAt.UTC()
+	ms.lifecycle.Event(logging.EventCreated, tenantID, messageID, correlationID)
+
+	// Hand the message to the tenant's consumer. If that fails, remove the
+	// row again so a client retry does not leave a duplicate behind.
+	envelope := messaging.Envelope{MessageID: messageID, CorrelationID: correlationID, Lane: req.Lane, Transient: ingest.transient}
+	if err := ms.rabbitmq.Publish(tenantID, payloadBytes, envelope); err != nil {
+		if _, delErr := ms.db.Exec(`DELETE FROM messages WHERE tenant_id = $1 AND id = $2`, tenantID, messageID); delErr != nil {
+			log.Printf("Failed to remove unpublished message %s: %v", messageID, delErr)
+		}
+		return nil, fmt.Errorf("failed to publish message: %w", err)
+	}
+	ms.lifecycle.Event(logging.EventPublished, tenantID, messageID, correlationID, "lane", req.Lane)
+	metrics.ObservePayloadSize(tenantID, len(payloadBytes))
+	ms.countMessage(tenantID, message.CreatedAt)
 
+	created = true
 	return &message, nil
 }
 
-func (ms *MessageService) GetMessages(tenantID string, cursor *string, limit int) (*PaginatedMessages, error) {
+// newMessageID returns the client-supplied ID in canonical form, or a new
+// one if none was supplied. The messages table keys on UUIDs, so that is the
+// only format accepted.
+func newMessageID(id string) (string, error) {
+	if id == "" {
+		return uuid.New().String(), nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not a UUID", ErrInvalidMessageID, id)
+	}
+	return parsed.String(), nil
+}
+
+// ingestConfig is the part of a tenant's configuration that shapes how its
+// messages are stored.
+type ingestConfig struct {
+	transforms        []string
+	keepRaw           bool
+	allowEmptyPayload bool
+	// transient publishes without persistence, to match the tenant's
+	// non-durable queues
+	transient bool
+}
+
+// tenantIngest returns the transforms configured for the tenant, whether it
+// keeps raw payloads, whether it accepts empty ones and whether its messages
+// are transient. Unknown tenants have none of them.
+func (ms *MessageService) tenantIngest(tenantID string) (ingestConfig, error) {
+	var ingest ingestConfig
+	var transformsBytes []byte
+	err := ms.db.QueryRow(
+		`SELECT transforms, keep_raw_payload, allow_empty_payload, transient FROM tenant_configs WHERE tenant_id = $1`, tenantID,
+	).Scan(&transformsBytes, &ingest.keepRaw, &ingest.allowEmptyPayload, &ingest.transient)
+	if err == sql.ErrNoRows {
+		return ingestConfig{}, nil
+	}
+	if err != nil {
+		return ingestConfig{}, fmt.Errorf("failed to load transforms: %w", err)
+	}
+
+	if err := json.Unmarshal(transformsBytes, &ingest.transforms); err != nil {
+		return ingestConfig{}, fmt.Errorf("failed to unmarshal transforms: %w", err)
+	}
+	return ingest, nil
+}
+
+// ensurePartition creates the messages partition of an existing tenant.
+func (ms *MessageService) ensurePartition(tenantID string) error {
+	if err := ms.checkTenant(tenantID); err != nil {
+		return err
+	}
+
+	return database.CreateTenantPartition(ms.db, tenantID)
+}
+
+// checkLane returns ErrUnknownLane unless the tenant has the lane, or a
+// "tenant not found" error if the tenant does not exist.
+func (ms *MessageService) checkLane(tenantID, lane string) error {
+	var exists bool
+	err := ms.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM tenant_lanes WHERE tenant_id = $1 AND name = $2)`,
+		tenantID, lane,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up lane: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	if err := ms.checkTenant(tenantID); err != nil {
+		return err
+	}
+	return ErrUnknownLane
+}
+
+// checkTenant returns a "tenant not found" error unless the tenant exists.
+func (ms *MessageService) checkTenant(tenantID string) error {
+	var exists bool
+	err := ms.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1 AND deleted_at IS NULL)`, tenantID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up tenant: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("tenant not found")
+	}
+	return nil
+}
+
+// GetMessages pages through a tenant's messages, newest first. A non-empty
+// source restricts the results to messages whose metadata.source matches.
+func (ms *MessageService) GetMessages(tenantID string, cursor *string, limit int, source string) (*PaginatedMessages, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20 // Default limit
 	}
 
-	var query string
-	var args []interface{}
+	var where whereClause
+	where.add("tenant_id = ?", tenantID)
+	where.add(notExpired)
 
 	if cursor != nil && *cursor != "" {
-		// Parse cursor (timestamp)
-		cursorTime, err := time.Parse(time.RFC3339, *cursor)
-		if err != nil {
-			return nil, fmt.Errorf("invalid cursor format: %w", err)
+		// Parse cursor (timestamp). Any zone offset is accepted, the instant
+		// is compared in UTC. Pages ending among messages that share a
+		// timestamp hand out a (created_at, id) cursor instead.
+		if cursorTime, err := time.Parse(time.RFC3339Nano, *cursor); err == nil {
+			where.add("created_at < ?", cursorTime.UTC())
+		} else if cursorTime, cursorID, err := decodeCursor(*cursor); err == nil {
+			where.add("(created_at, id) < (?, ?)", cursorTime, cursorID)
+		} else {
+			return nil, fmt.Errorf("%w %q: pass the next_cursor of a previous page, or an RFC 3339 timestamp", ErrInvalidCursor, *cursor)
 		}
-
-		query = `
-			SELECT id, tenant_id, payload, created_at 
-			FROM messages 
-			WHERE tenant_id = $1 AND created_at < $2 
-			ORDER BY created_at DESC 
-			LIMIT $3
-		`
-		args = []interface{}{tenantID, cursorTime, limit + 1} // +1 to check if there's a next page
-	} else {
-		query = `
-			SELECT id, tenant_id, payload, created_at 
-			FROM messages 
-			WHERE tenant_id = $1 
-			ORDER BY created_at DESC 
-			LIMIT $2
-		`
-		args = []interface{}{tenantID, limit + 1}
 	}
+	if source != "" {
+		where.add("metadata->>'source' = ?", source)
+	}
+
+	query := `SELECT id, tenant_id, payload, metadata, correlation_id, lane, status, created_at, expires_at, payload_ref FROM messages` + where.String() +
+		" ORDER BY created_at DESC, id DESC LIMIT " + where.arg(limit+1) // +1 to check if there's a next page
 
-	rows, err := ms.db.Query(query, args...)
+	rows, err := ms.db.Query(query, where.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query messages: %w", err)
 	}
 	defer rows.Close()
 
-	var messages []*models.Message
-	for rows.Next() {
-		var message models.Message
-		var payloadBytes []byte
-		err := rows.Scan(
-			&message.ID,
-			&message.TenantID,
-			&payloadBytes,
-			&message.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
-		}
-		
-		// Unmarshal payload
-		var payload interface{}
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
-		}
-		message.Payload = payload
-		
-		messages = append(messages, &message)
+	messages, err := ms.scanMessages(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	result := &PaginatedMessages{
@@ -124,9 +432,16 @@ func (ms *MessageService) GetMessages(tenantID string, cursor *string, limit int
 	if len(messages) > limit {
 		// Remove the extra message
 		result.Data = messages[:limit]
-		// Set next cursor to the last message's timestamp
+		// Set next cursor to the last message's timestamp, in UTC and at
+		// full precision so messages created within the same second are
+		// not skipped
 		lastMessage := messages[limit-1]
-		nextCursor := lastMessage.CreatedAt.Format(time.RFC3339)
+		nextCursor := lastMessage.CreatedAt.UTC().Format(time.RFC3339Nano)
+		// A timestamp would skip the next message if it shares the last
+		// one's, as backfilled messages often do
+		if messages[limit].CreatedAt.Equal(lastMessage.CreatedAt) {
+			nextCursor = encodeCursor(lastMessage.CreatedAt, lastMessage.ID)
+		}
 		result.NextCursor = &nextCursor
 	}
 
@@ -135,19 +450,12 @@ func (ms *MessageService) GetMessages(tenantID string, cursor *string, limit int
 
 func (ms *MessageService) GetMessage(messageID string) (*models.Message, error) {
 	query := `
-		SELECT id, tenant_id, payload, created_at 
+		SELECT id, tenant_id, payload, metadata, correlation_id, lane, status, created_at, expires_at, payload_ref
 		FROM messages 
-		WHERE id = $1
+		WHERE id = $1 AND ` + notExpired + `
 	`
 
-	var message models.Message
-	var payloadBytes []byte
-	err := ms.db.QueryRow(query, messageID).Scan(
-		&message.ID,
-		&message.TenantID,
-		&payloadBytes,
-		&message.CreatedAt,
-	)
+	message, err := ms.scanMessage(ms.db.QueryRow(query, messageID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("message not found")
@@ -155,21 +463,37 @@ func (ms *MessageService) GetMessage(messageID string) (*models.Message, error)
 		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
 
-	// Unmarshal payload
-	var payload interface{}
-	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	return message, nil
+}
+
+// GetRawPayload returns a message's payload as it was received, before the
+// tenant's transforms were applied.
+func (ms *MessageService) GetRawPayload(messageID string) (*models.RawPayload, error) {
+	var rawPayloadBytes []byte
+	raw := models.RawPayload{ID: messageID}
+	err := ms.db.QueryRow(`SELECT tenant_id, raw_payload FROM messages WHERE id = $1 AND `+notExpired, messageID).
+		Scan(&raw.TenantID, &rawPayloadBytes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("message not found")
+		}
+		return nil, fmt.Errorf("failed to get raw payload: %w", err)
+	}
+	if rawPayloadBytes == nil {
+		return nil, ErrRawPayloadNotKept
 	}
-	message.Payload = payload
 
-	return &message, nil
+	if err := json.Unmarshal(rawPayloadBytes, &raw.Payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal raw payload: %w", err)
+	}
+	return &raw, nil
 }
 
 func (ms *MessageService) GetMessagesByTenant(tenantID string) ([]*models.Message, error) {
 	query := `
-		SELECT id, tenant_id, payload, created_at 
+		SELECT id, tenant_id, payload, metadata, correlation_id, lane, status, created_at, expires_at, payload_ref
 		FROM messages 
-		WHERE tenant_id = $1 
+		WHERE tenant_id = $1 AND ` + notExpired + `
 		ORDER BY created_at DESC
 	`
 
@@ -179,71 +503,414 @@ func (ms *MessageService) GetMessagesByTenant(tenantID string) ([]*models.Messag
 	}
 	defer rows.Close()
 
-	var messages []*models.Message
-	for rows.Next() {
-		var message models.Message
-		var payloadBytes []byte
-		err := rows.Scan(
-			&message.ID,
-			&message.TenantID,
-			&payloadBytes,
-			&message.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
-		}
-		
-		// Unmarshal payload
-		var payload interface{}
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
-		}
-		message.Payload = payload
-		
-		messages = append(messages, &message)
+	messages, err := ms.scanMessages(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	return messages, nil
 }
 
-func (ms *MessageService) DeleteMessage(messageID string) error {
-	query := `DELETE FROM messages WHERE id = $1`
-	result, err := ms.db.Exec(query, messageID)
-	if err != nil {
-		return fmt.Errorf("failed to delete message: %w", err)
+// DeleteMessage deletes a message from its tenant's partition. Without the
+// partition key Postgres would have to search every tenant's partition, so
+// when tenantID is empty it is looked up from the message first.
+func (ms *MessageService) DeleteMessage(tenantID, messageID string) error {
+	if tenantID == "" {
+		err := ms.db.QueryRow(`SELECT tenant_id FROM messages WHERE id = $1`, messageID).Scan(&tenantID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("message not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up message: %w", err)
+		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	query := `DELETE FROM messages WHERE tenant_id = $1 AND id = $2 RETURNING payload_ref`
+	var payloadRef sql.NullString
+	err := ms.db.QueryRow(query, tenantID, messageID).Scan(&payloadRef)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("message not found")
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to delete message: %w", err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("message not found")
+	if payloadRef.Valid {
+		ms.deleteBlob(payloadRef.String)
 	}
-
 	return nil
 }
 
-func (ms *MessageService) GetMessageStats(tenantID string) (*models.MessageStats, error) {
+func (ms *MessageService) GetMessageStats(ctx context.Context, tenantID string) (*models.MessageStats, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_messages,
 			COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '24 hours') as messages_24h,
-			COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '1 hour') as messages_1h
+			COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '1 hour') as messages_1h,
+			COUNT(*) FILTER (WHERE status = 'failed') as failed_permanently,
+			COUNT(*) FILTER (WHERE status = 'pending' AND attempts > 0) as retrying
 		FROM messages 
 		WHERE tenant_id = $1
 	`
 
-	var stats models.MessageStats
-	err := ms.db.QueryRow(query, tenantID).Scan(
+	stats := models.MessageStats{Mode: models.StatsModeExact}
+	var failed, retrying int64
+	err := ms.db.QueryRowContext(ctx, query, tenantID).Scan(
 		&stats.TotalMessages,
 		&stats.Messages24h,
 		&stats.Messages1h,
+		&failed,
+		&retrying,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message stats: %w", err)
 	}
+	stats.FailedPermanently = &failed
+	stats.Retrying = &retrying
+
+	return &stats, nil
+}
+
+// GetApproximateMessageStats is GetMessageStats without the scan over the
+// tenant's messages for its totals. Messages24h and Messages1h are summed
+// from the per-minute counters kept as messages are created, so they also
+// count messages deleted since and may include up to a minute more than the
+// window; TotalMessages is the planner's estimate of the tenant's partition
+// size, as of its last ANALYZE. FailedPermanently and Retrying are left out,
+// as counting them would take the scan this avoids.
+func (ms *MessageService) GetApproximateMessageStats(ctx context.Context, tenantID string) (*models.MessageStats, error) {
+	stats := models.MessageStats{Mode: models.StatsModeApproximate}
+
+	query := `
+		SELECT
+			COALESCE(SUM(count), 0),
+			COALESCE(SUM(count) FILTER (WHERE bucket >= date_trunc('minute', NOW() - INTERVAL '1 hour')), 0)
+		FROM message_counts
+		WHERE tenant_id = $1 AND bucket >= date_trunc('minute', NOW() - INTERVAL '24 hours')
+	`
+	if err := ms.db.QueryRowContext(ctx, query, tenantID).Scan(&stats.Messages24h, &stats.Messages1h); err != nil {
+		return nil, fmt.Errorf("failed to get message counts: %w", err)
+	}
+
+	// reltuples is -1 for a partition that was never analyzed
+	estimate := `SELECT COALESCE((SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = to_regclass($1)), 0)`
+	if err := ms.db.QueryRowContext(ctx, estimate, database.PartitionName(tenantID)).Scan(&stats.TotalMessages); err != nil {
+		return nil, fmt.Errorf("failed to estimate message count: %w", err)
+	}
 
 	return &stats, nil
-}
\ No newline at end of file
+}
+
+// countMessage adds a created message to the per-minute counter of its
+// created_at. The counters only feed approximate stats, so a failure is
+// logged rather than failing the create.
+func (ms *MessageService) countMessage(tenantID string, createdAt time.Time) {
+	query := `
+		INSERT INTO message_counts (tenant_id, bucket, count)
+		VALUES ($1, date_trunc('minute', $2::timestamptz), 1)
+		ON CONFLICT (tenant_id, bucket) DO UPDATE SET count = message_counts.count + 1
+	`
+	if _, err := ms.db.Exec(query, tenantID, createdAt); err != nil {
+		log.Printf("Failed to count message of tenant %s: %v", tenantID, err)
+	}
+}
+
+// GetThroughput counts a tenant's messages per interval ("minute", "hour" or
+// "day") over the window ending now. Buckets are aligned to UTC and every
+// bucket in the window is returned, including empty ones.
+func (ms *MessageService) GetThroughput(ctx context.Context, tenantID, interval string, window time.Duration) (*models.Throughput, error) {
+	end := time.Now().UTC()
+	buckets, err := ms.countByBucket(ctx, tenantID, interval, end.Add(-window), end)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Throughput{
+		TenantID: tenantID,
+		Interval: interval,
+		Window:   window.String(),
+		Buckets:  buckets,
+	}, nil
+}
+
+// GetHistogram counts a tenant's messages per bucket ("minute", "hour" or
+// "day") between from and to. Every UTC-aligned bucket overlapping
+// [from, to) is returned, including empty ones.
+func (ms *MessageService) GetHistogram(ctx context.Context, tenantID, bucket string, from, to time.Time) (*models.Histogram, error) {
+	buckets, err := ms.countByBucket(ctx, tenantID, bucket, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Histogram{
+		TenantID: tenantID,
+		Bucket:   bucket,
+		From:     from.UTC(),
+		To:       to.UTC(),
+		Buckets:  buckets,
+	}, nil
+}
+
+// countByBucket counts a tenant's messages in each UTC-aligned interval
+// bucket overlapping [from, to). Buckets count every message within them,
+// even at the edges of the range.
+func (ms *MessageService) countByBucket(ctx context.Context, tenantID, interval string, from, to time.Time) ([]models.TimeBucket, error) {
+	length, ok := throughputIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: interval must be minute, hour or day", ErrInvalidRange)
+	}
+	span := to.Sub(from)
+	if span <= 0 || span/length >= maxThroughputBuckets {
+		return nil, fmt.Errorf("%w: range must be positive and span fewer than %d %ss", ErrInvalidRange, maxThroughputBuckets, interval)
+	}
+	if err := ms.checkTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH buckets AS (
+			SELECT generate_series(
+				date_trunc($2, $3::timestamptz AT TIME ZONE 'UTC'),
+				date_trunc($2, ($4::timestamptz - interval '1 microsecond') AT TIME ZONE 'UTC'),
+				('1 ' || $2)::interval
+			) AT TIME ZONE 'UTC' AS bucket
+		)
+		SELECT b.bucket, COUNT(m.id)
+		FROM buckets b
+		LEFT JOIN messages m
+			ON m.tenant_id = $1
+			AND m.created_at >= b.bucket
+			AND m.created_at < b.bucket + ('1 ' || $2)::interval
+		GROUP BY b.bucket
+		ORDER BY b.bucket
+	`
+	rows, err := ms.db.QueryContext(ctx, query, tenantID, interval, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages by %s: %w", interval, err)
+	}
+	defer rows.Close()
+
+	buckets := []models.TimeBucket{}
+	for rows.Next() {
+		var bucket models.TimeBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan bucket: %w", err)
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
+
+// ListAllMessages queries the parent messages table across every tenant
+// partition. Results are keyset-paginated on (created_at, id).
+func (ms *MessageService) ListAllMessages(ctx context.Context, filter MessageFilter) (*PaginatedMessages, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20 // Default limit
+	}
+
+	var where whereClause
+	where.add(notExpired)
+	if filter.From != nil {
+		where.add("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		where.add("created_at < ?", *filter.To)
+	}
+	if filter.Status != "" {
+		if !models.ValidMessageStatus(filter.Status) {
+			return nil, ErrInvalidStatus
+		}
+		where.add("status = ?", filter.Status)
+	}
+	if filter.Source != "" {
+		where.add("metadata->>'source' = ?", filter.Source)
+	}
+	if filter.Cursor != "" {
+		cursorTime, cursorID, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where.add("(created_at, id) < (?, ?)", cursorTime, cursorID)
+	}
+
+	query := `SELECT id, tenant_id, payload, metadata, correlation_id, lane, status, created_at, expires_at, payload_ref FROM messages` + where.String() +
+		" ORDER BY created_at DESC, id DESC LIMIT " + where.arg(limit+1) // +1 to check if there's a next page
+
+	rows, err := ms.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages, err := ms.scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PaginatedMessages{
+		Data: messages,
+	}
+
+	if len(messages) > limit {
+		result.Data = messages[:limit]
+		lastMessage := messages[limit-1]
+		nextCursor := encodeCursor(lastMessage.CreatedAt, lastMessage.ID)
+		result.NextCursor = &nextCursor
+	}
+
+	return result, nil
+}
+
+// encodeCursor builds an opaque keyset cursor from a message's position.
+func encodeCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return createdAt.UTC(), parts[1], nil
+}
+
+// whereClause accumulates SQL conditions, numbering "?" placeholders as
+// Postgres positional arguments in the order they are added.
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, values ...interface{}) {
+	for _, value := range values {
+		condition = strings.Replace(condition, "?", w.arg(value), 1)
+	}
+	w.conditions = append(w.conditions, condition)
+}
+
+// arg appends a bare argument and returns its placeholder.
+func (w *whereClause) arg(value interface{}) string {
+	w.args = append(w.args, value)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMessage scans a row of the message columns, ending in payload_ref,
+// and reads an offloaded payload back from the blob store.
+func (ms *MessageService) scanMessage(row rowScanner) (*models.Message, error) {
+	var message models.Message
+	var payloadBytes, metadataBytes []byte
+	var correlationID, lane, payloadRef sql.NullString
+	var expiresAt sql.NullTime
+	err := row.Scan(
+		&message.ID,
+		&message.TenantID,
+		&payloadBytes,
+		&metadataBytes,
+		&correlationID,
+		&lane,
+		&message.Status,
+		&message.CreatedAt,
+		&expiresAt,
+		&payloadRef,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if payloadRef.Valid {
+		if payloadBytes, err = ms.loadBlob(payloadRef.String); err != nil {
+			return nil, err
+		}
+	}
+	if expiresAt.Valid {
+		expiresAt.Time = expiresAt.Time.UTC()
+		message.ExpiresAt = &expiresAt.Time
+	}
+
+	message.CorrelationID = correlationID.String
+	message.Lane = lane.String
+	message.CreatedAt = message.CreatedAt.UTC()
+
+	// Unmarshal payload
+	var payload interface{}
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	message.Payload = payload
+
+	if metadataBytes != nil {
+		if err := json.Unmarshal(metadataBytes, &message.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	return &message, nil
+}
+
+func (ms *MessageService) scanMessages(rows *sql.Rows) ([]*models.Message, error) {
+	var messages []*models.Message
+	for rows.Next() {
+		message, err := ms.scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// loadBlob reads an offloaded payload from the blob store.
+func (ms *MessageService) loadBlob(key string) ([]byte, error) {
+	if ms.blobs == nil {
+		return nil, fmt.Errorf("payload %s is offloaded but no payload_storage is configured", key)
+	}
+	data, err := ms.blobs.Get(context.Background(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offloaded payload: %w", err)
+	}
+	return data, nil
+}
+
+// deleteBlob removes an offloaded payload whose message is gone.
+func (ms *MessageService) deleteBlob(key string) {
+	deleteBlobs(ms.blobs, []string{key})
+}
+
+// checkPayloadQuery refuses queries over payload contents while payloads
+// may be offloaded: an offloaded message has no payload in the database, so
+// such a query would silently pass it over.
+func (ms *MessageService) checkPayloadQuery(kind error, what string) error {
+	if ms.blobs == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s cannot see offloaded payloads, so they are unavailable while payload_storage.offload_threshold is set", kind, what)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"jatis/internal/database"
+	"jatis/internal/models"
+)
+
+var (
+	// ErrTargetTenantNotFound is returned when the tenant messages are moved
+	// to does not exist
+	ErrTargetTenantNotFound = errors.New("target tenant not found")
+	// ErrInvalidMove is returned for a move whose source and target are the
+	// same tenant
+	ErrInvalidMove = errors.New("invalid move")
+)
+
+// MoveMessages moves the source tenant's messages whose payload matches
+// every filter to the target tenant, e.g. when two customers merge. No
+// filters moves them all. tenant_id is the partition key, so each message is
+// re-inserted into the target's partition and deleted from the source's, in
+// a single statement. Messages keep their IDs, payloads, status and
+// attempts; a lane the target does not have is cleared.
+//
+// Pending messages are skipped and counted: their deliveries are still on
+// the source's queue, and processing them would no longer find the row.
+func (ms *MessageService) MoveMessages(sourceID, targetID string, filters []PayloadFilter) (*models.MoveResult, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("%w: source and target are the same tenant", ErrInvalidMove)
+	}
+	if len(filters) > 0 {
+		if err := ms.checkPayloadQuery(ErrInvalidFilter, "payload filters"); err != nil {
+			return nil, err
+		}
+	}
+	if err := ms.checkTenant(sourceID); err != nil {
+		return nil, err
+	}
+	// Moved rows need the target's partition
+	if err := ms.ensurePartition(targetID); err != nil {
+		if err.Error() == "tenant not found" {
+			return nil, fmt.Errorf("%w: %s", ErrTargetTenantNotFound, targetID)
+		}
+		return nil, err
+	}
+
+	// matching selects the source's messages that match the filters and
+	// have, or with negate do not have, status
+	matching := func(status string, negate bool) (whereClause, error) {
+		var where whereClause
+		where.add("tenant_id = ?", sourceID)
+		if negate {
+			where.add("status <> ?", status)
+		} else {
+			where.add("status = ?", status)
+		}
+		err := where.addPayloadFilters(filters)
+		return where, err
+	}
+	pending, err := matching(models.MessageStatusPending, false)
+	if err != nil {
+		return nil, err
+	}
+	where, _ := matching(models.MessageStatusPending, true)
+
+	tx, err := ms.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin move: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Keep the target from starting to drain until the move commits
+	var status string
+	err = tx.QueryRow(`SELECT status FROM tenants WHERE id = $1 AND deleted_at IS NULL FOR SHARE`, targetID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrTargetTenantNotFound, targetID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up target tenant: %w", err)
+	}
+	if status == models.TenantStatusDraining {
+		return nil, ErrTenantDraining
+	}
+
+	result := &models.MoveResult{SourceTenantID: sourceID, TargetTenantID: targetID}
+
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM messages`+pending.String(), pending.args...).Scan(&result.SkippedPending); err != nil {
+		return nil, fmt.Errorf("failed to count pending messages: %w", err)
+	}
+
+	target := where.arg(targetID)
+	query := `
+		WITH moved AS (
+			DELETE FROM messages` + where.String() + `
+			RETURNING id, payload, created_at, status, metadata, correlation_id, attempts, lane, raw_payload, expires_at, payload_ref
+		)
+		INSERT INTO messages (id, tenant_id, payload, created_at, status, metadata, correlation_id, attempts, lane, raw_payload, expires_at, payload_ref)
+		SELECT id, ` + target + `, payload, created_at, status, metadata, correlation_id, attempts,
+			CASE WHEN EXISTS (SELECT 1 FROM tenant_lanes l WHERE l.tenant_id = ` + target + ` AND l.name = moved.lane) THEN lane END,
+			raw_payload, expires_at, payload_ref
+		FROM moved
+	`
+	moved, err := tx.Exec(query, where.args...)
+	if database.IsUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: a moved message ID is already used by the target tenant", ErrDuplicateMessage)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to move messages: %w", err)
+	}
+	if result.Moved, err = moved.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit move: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+
+	"jatis/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// tenantQueuePattern matches the names of tenant queues, lane queues and
+// dead letter queues, capturing the tenant ID.
+var tenantQueuePattern = regexp.MustCompile(`^tenant_([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})_(?:queue|dlq|lane_[a-z0-9_-]+_queue)$`)
+
+// OrphanedQueues lists the tenant queues on the broker whose tenant no longer
+// exists, such as those left by a delete that failed halfway. Soft-deleted
+// tenants still exist and keep their queues. Listing queues needs
+// rabbitmq.management_url.
+func (tm *TenantManager) OrphanedQueues(ctx context.Context) ([]models.OrphanedQueue, error) {
+	names, err := tm.rabbitmq.ListQueues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []models.OrphanedQueue
+	var tenantIDs []string
+	for _, name := range names {
+		match := tenantQueuePattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		candidates = append(candidates, models.OrphanedQueue{Name: name, TenantID: match[1]})
+		tenantIDs = append(tenantIDs, match[1])
+	}
+
+	orphaned := []models.OrphanedQueue{}
+	if len(candidates) == 0 {
+		return orphaned, nil
+	}
+	rows, err := tm.db.QueryContext(ctx, `SELECT id::text FROM tenants WHERE id = ANY($1::uuid[])`, pq.Array(tenantIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up tenants: %w", err)
+	}
+	defer rows.Close()
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		existing[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to look up tenants: %w", err)
+	}
+
+	for _, q := range candidates {
+		if !existing[q.TenantID] {
+			orphaned = append(orphaned, q)
+		}
+	}
+	return orphaned, nil
+}
+
+// CleanupOrphanedQueues deletes the queues OrphanedQueues finds, messages
+// and all, and returns those deleted. A queue that fails to delete is logged
+// and left for the next cleanup.
+func (tm *TenantManager) CleanupOrphanedQueues(ctx context.Context) ([]models.OrphanedQueue, error) {
+	orphaned, err := tm.OrphanedQueues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []models.OrphanedQueue{}
+	for _, q := range orphaned {
+		if err := tm.rabbitmq.DeleteQueue(q.Name); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		log.Printf("Deleted queue %s of missing tenant %s", q.Name, q.TenantID)
+		deleted = append(deleted, q)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"jatis/internal/logging"
+	"jatis/internal/models"
+)
+
+// ErrNotOwner is returned when an instance is asked to act on the consumer
+// of a tenant that another instance owns.
+var ErrNotOwner = errors.New("tenant is consumed by another instance")
+
+// With cluster.enabled, each tenant is consumed by the one instance holding
+// its lease in tenant_owners. Instances renew their leases every third of
+// cluster.lease_ttl, take over tenants whose lease has expired, and stop
+// consuming tenants whose lease they lost. Worker pools, lanes, drains and
+// metrics of a tenant therefore only exist on its owner, which applies
+// configuration changed through any instance on its next renewal.
+
+// InstanceID returns the name this instance holds tenant leases under, empty
+// unless cluster.enabled is set.
+func (tm *TenantManager) InstanceID() string {
+	return tm.instanceID
+}
+
+// ownsTenant reports whether this instance holds the tenant's lease.
+func (tm *TenantManager) ownsTenant(tenantID string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.owned[tenantID]
+}
+
+// ownershipWorker rebalances every third of the lease TTL until Shutdown.
+func (tm *TenantManager) ownershipWorker() {
+	defer close(tm.ownershipExited)
+
+	ticker := time.NewTicker(tm.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tm.rebalance()
+		case <-tm.ownershipDone:
+			return
+		}
+	}
+}
+
+// rebalance renews this instance's leases, stops consuming tenants whose
+// lease it lost, applies the stored config of the tenants it keeps, and
+// claims tenants that no instance owns.
+func (tm *TenantManager) rebalance() {
+	held, err := tm.renewLeases()
+	if err != nil {
+		logging.Printf("Failed to renew tenant leases: %v", err)
+		return
+	}
+
+	tm.mu.Lock()
+	var lost, adopted, kept []string
+	for tenantID := range tm.owned {
+		if !held[tenantID] {
+			lost = append(lost, tenantID)
+			delete(tm.owned, tenantID)
+		}
+	}
+	for tenantID := range held {
+		if tm.owned[tenantID] {
+			kept = append(kept, tenantID)
+		} else {
+			// Still leased to this instance ID from before a restart
+			adopted = append(adopted, tenantID)
+			tm.owned[tenantID] = true
+		}
+	}
+	tm.mu.Unlock()
+
+	// Lost to another instance after missing renewals, or deleted along with
+	// the tenant
+	for _, tenantID := range lost {
+		log.Printf("Lost ownership of tenant %s, stopping its consumer", tenantID)
+		tm.stopTenantConsumer(tenantID)
+	}
+
+	for _, tenantID := range kept {
+		if err := tm.syncTenantConfig(tenantID); err != nil {
+			logging.Printf("Failed to apply config of tenant %s: %v", tenantID, err)
+		}
+	}
+
+	claimed, err := tm.claimTenants("")
+	if err != nil {
+		logging.Printf("Failed to claim tenants: %v", err)
+	}
+	for _, tenantID := range append(adopted, claimed...) {
+		tm.startOwnedTenant(tenantID)
+	}
+}
+
+// startOwnedTenant starts consuming a tenant this instance has claimed and
+// finishes its drain if one was interrupted. If the consumer cannot start,
+// the lease is released so the tenant can be claimed again.
+func (tm *TenantManager) startOwnedTenant(tenantID string) {
+	tenant, err := tm.GetTenant(tenantID)
+	started := false
+	if err == nil {
+		// A concurrent CreateTenant may have started it already
+		started, err = tm.ensureTenantConsumer(tenantID)
+	}
+	if err != nil {
+		log.Printf("Failed to start consumer for tenant %s: %v", tenantID, err)
+		tm.releaseLease(tenantID)
+		return
+	}
+
+	if started && tenant.Status == models.TenantStatusDraining {
+		tm.startDrain(tenantID, DefaultDrainTimeout)
+	}
+}
+
+// leaseInterval is the lease TTL as a Postgres interval.
+func (tm *TenantManager) leaseInterval() string {
+	return fmt.Sprintf("%d milliseconds", tm.leaseTTL.Milliseconds())
+}
+
+// claimTenants leases tenants no live instance owns to this instance, up to
+// cluster.max_tenants, and returns them. A non-empty tenantID claims only
+// that tenant.
+func (tm *TenantManager) claimTenants(tenantID string) ([]string, error) {
+	var limit interface{}
+	if max := tm.cfg.Cluster.MaxTenants; max > 0 {
+		tm.mu.RLock()
+		remaining := max - len(tm.owned)
+		tm.mu.RUnlock()
+		if remaining <= 0 {
+			return nil, nil
+		}
+		limit = remaining
+	}
+
+	var where whereClause
+	where.add("(o.tenant_id IS NULL OR o.expires_at < NOW())")
+	where.add("t.deleted_at IS NULL")
+	if tenantID != "" {
+		where.add("t.id = ?", tenantID)
+	}
+
+	// Instances claiming the same tenant at once conflict on its row; only
+	// the one that finds the lease still free or expired gets it
+	query := `
+		INSERT INTO tenant_owners (tenant_id, instance_id, expires_at)
+		SELECT t.id, ` + where.arg(tm.instanceID) + `, NOW() + ` + where.arg(tm.leaseInterval()) + `::interval
+		FROM tenants t
+		LEFT JOIN tenant_owners o ON o.tenant_id = t.id` + where.String() + `
+		ORDER BY t.created_at
+		LIMIT ` + where.arg(limit) + `
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET instance_id = EXCLUDED.instance_id, expires_at = EXCLUDED.expires_at
+		WHERE tenant_owners.expires_at < NOW()
+		RETURNING tenant_id
+	`
+	rows, err := tm.db.Query(query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claimed []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tm.mu.Lock()
+	for _, id := range claimed {
+		tm.owned[id] = true
+	}
+	tm.mu.Unlock()
+
+	return claimed, nil
+}
+
+// renewLeases extends every lease this instance holds and returns the
+// tenants they cover.
+func (tm *TenantManager) renewLeases() (map[string]bool, error) {
+	query := `UPDATE tenant_owners SET expires_at = NOW() + $2::interval WHERE instance_id = $1 RETURNING tenant_id`
+	rows, err := tm.db.Query(query, tm.instanceID, tm.leaseInterval())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		held[id] = true
+	}
+	return held, rows.Err()
+}
+
+// releaseLease gives up this instance's lease on a tenant.
+func (tm *TenantManager) releaseLease(tenantID string) {
+	tm.mu.Lock()
+	delete(tm.owned, tenantID)
+	tm.mu.Unlock()
+
+	query := `DELETE FROM tenant_owners WHERE tenant_id = $1 AND instance_id = $2`
+	if _, err := tm.db.Exec(query, tenantID, tm.instanceID); err != nil {
+		log.Printf("Failed to release lease on tenant %s: %v", tenantID, err)
+	}
+}
+
+// releaseLeases gives up all of this instance's leases, so other instances
+// take its tenants over without waiting for the leases to expire.
+func (tm *TenantManager) releaseLeases() {
+	if _, err := tm.db.Exec(`DELETE FROM tenant_owners WHERE instance_id = $1`, tm.instanceID); err != nil {
+		log.Printf("Failed to release tenant leases: %v", err)
+	}
+	tm.mu.Lock()
+	tm.owned = make(map[string]bool)
+	tm.mu.Unlock()
+}
+
+// tenantOwner returns the instance holding the tenant's lease, or "" if it
+// has none or it has expired.
+func (tm *TenantManager) tenantOwner(tenantID string) (string, error) {
+	var owner string
+	query := `SELECT instance_id FROM tenant_owners WHERE tenant_id = $1 AND expires_at > NOW()`
+	err := tm.db.QueryRow(query, tenantID).Scan(&owner)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to load tenant owner: %w", err)
+	}
+	return owner, nil
+}
+
+// syncTenantConfig applies the tenant's stored config to its consumer here,
+// so changes made through other instances take effect on the owner. Queue
+// options such as single_active_consumer only change with a restart.
+func (tm *TenantManager) syncTenantConfig(tenantID string) error {
+	var workers, weight, maxConcurrency int
+	var dedicated bool
+	var policy string
+	var sampleRate float64
+	var payloadSchema, schedule []byte
+	query := `SELECT workers, dedicated_pool, failure_policy, weight, max_concurrency, sample_rate, payload_schema, worker_schedule FROM tenant_configs WHERE tenant_id = $1`
+	err := tm.db.QueryRow(query, tenantID).Scan(&workers, &dedicated, &policy, &weight, &maxConcurrency, &sampleRate, &payloadSchema, &schedule)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	// A window of the worker schedule overrides the stored count, as it
+	// does where the tenant was started
+	workers = tm.workerTarget(workers, decodeWorkerSchedule(schedule))
+
+	if tm.scheduler != nil {
+		tm.scheduler.SetWeight(tenantID, weight)
+	}
+	tm.limits.SetLimit(tenantID, maxConcurrency)
+
+	tm.mu.Lock()
+	tm.failurePolicies[tenantID] = policy
+	tm.sampleRates[tenantID] = sampleRate
+	tm.setPayloadSchema(tenantID, decodePayloadSchema(payloadSchema))
+	if pool, exists := tm.workerPools[tenantID]; exists && pool.Workers() != int32(workers) {
+		pool.UpdateWorkers(int32(workers))
+	}
+	tm.mu.Unlock()
+	tm.applyPoolMode(tenantID, dedicated, workers)
+
+	return tm.syncLanes(tenantID)
+}
+
+// syncLanes starts, resizes and stops the tenant's lanes to match
+// tenant_lanes.
+func (tm *TenantManager) syncLanes(tenantID string) error {
+	rows, err := tm.db.Query(`SELECT name, workers FROM tenant_lanes WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load lanes: %w", err)
+	}
+	stored := map[string]int{}
+	for rows.Next() {
+		var name string
+		var workers int
+		if err := rows.Scan(&name, &workers); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan lane: %w", err)
+		}
+		stored[name] = workers
+	}
+	rows.Close()
+
+	tm.mu.Lock()
+	var added []string
+	var removed []*lane
+	for name, workers := range stored {
+		l, exists := tm.lanes[tenantID][name]
+		if !exists {
+			added = append(added, name)
+		} else if l.pool.Workers() != int32(workers) {
+			l.pool.UpdateWorkers(int32(workers))
+		}
+	}
+	for name, l := range tm.lanes[tenantID] {
+		if _, exists := stored[name]; !exists {
+			removed = append(removed, l)
+			delete(tm.lanes[tenantID], name)
+		}
+	}
+	tm.mu.Unlock()
+
+	for _, l := range removed {
+		l.stop()
+	}
+	for _, name := range added {
+		if err := tm.startLane(tenantID, name, stored[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"jatis/internal/logging"
+	"jatis/internal/messaging"
+	"jatis/internal/metrics"
+	"jatis/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// RedeliveryCounts counts how often each message has been redelivered to
+// this instance, keyed by tenant and message ID. A message's count starts
+// over when it is delivered for the first time.
+type RedeliveryCounts struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int
+}
+
+func NewRedeliveryCounts() *RedeliveryCounts {
+	return &RedeliveryCounts{counts: make(map[string]map[string]int)}
+}
+
+// Observe records a delivery of the message and returns how many times it
+// has been redelivered. Messages without an ID are not tracked.
+func (r *RedeliveryCounts) Observe(tenantID, messageID string, redelivered bool) int {
+	if messageID == "" {
+		return 0
+	}
+	if !redelivered {
+		r.Forget(tenantID, messageID)
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tenant, exists := r.counts[tenantID]
+	if !exists {
+		tenant = make(map[string]int)
+		r.counts[tenantID] = tenant
+	}
+	tenant[messageID]++
+	return tenant[messageID]
+}
+
+// Forget drops the message's count, once it will not be delivered again.
+func (r *RedeliveryCounts) Forget(tenantID, messageID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tenant, exists := r.counts[tenantID]; exists {
+		delete(tenant, messageID)
+		if len(tenant) == 0 {
+			delete(r.counts, tenantID)
+		}
+	}
+}
+
+// ForgetTenant drops the counts of all the tenant's messages.
+func (r *RedeliveryCounts) ForgetTenant(tenantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.counts, tenantID)
+}
+
+// poisoned reports whether a delivery has been redelivered more than
+// consumer.max_redeliveries times. If it has, it is dead-lettered without
+// being processed, counted under messages_processed_total with status
+// poisoned, and its message marked failed.
+func (tm *TenantManager) poisoned(tenantID string, lease *messaging.Lease) bool {
+	delivery := lease.Delivery()
+	redeliveries := tm.redeliveries.Observe(tenantID, delivery.MessageId, delivery.Redelivered)
+	limit := tm.cfg.Consumer.MaxRedeliveries
+	if limit <= 0 || redeliveries <= limit {
+		return false
+	}
+
+	reason := fmt.Sprintf("redelivered %d times, more than consumer.max_redeliveries of %d", redeliveries, limit)
+	log.Printf("Warning: message %s for tenant %s %s; dead-lettering it as poison", delivery.MessageId, tenantID, reason)
+
+	// Messages published outside the API have no row to mark
+	if _, err := uuid.Parse(delivery.MessageId); err == nil {
+		query := `UPDATE messages SET status = $3 WHERE tenant_id = $1 AND id = $2`
+		if _, err := tm.db.Exec(query, tenantID, delivery.MessageId, models.MessageStatusFailed); err != nil {
+			log.Printf("Failed to mark message %s as failed: %v", delivery.MessageId, err)
+		}
+	}
+	lease.DeadLetter(reason)
+	tm.redeliveries.Forget(tenantID, delivery.MessageId)
+	tm.lifecycle.Event(logging.EventDeadLettered, tenantID, delivery.MessageId, messaging.CorrelationID(delivery), "error", reason)
+	tm.alerts.Record(tenantID, delivery.MessageId, reason)
+	metrics.IncrementMessagesProcessed(tenantID, "poisoned")
+	return true
+}
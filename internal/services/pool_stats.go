@@ -0,0 +1,50 @@
+package services
+
+import (
+	"sort"
+
+	"jatis/internal/models"
+)
+
+// PoolStats reports the live state of the tenant's worker pools on this
+// instance, read from their in-memory counters.
+func (tm *TenantManager) PoolStats(tenantID string) (*models.TenantPoolStats, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	stats := &models.TenantPoolStats{TenantID: tenantID, Pools: []models.PoolStats{}}
+	if !tm.cfg.Cluster.Enabled || tm.owned[tenantID] {
+		if pool, dedicated := tm.workerPools[tenantID]; dedicated {
+			stats.Pools = append(stats.Pools, poolStats(pool, "", "dedicated"))
+		} else if tm.sharedPool != nil {
+			stats.Pools = append(stats.Pools, poolStats(tm.sharedPool, "", "shared"))
+		}
+	}
+
+	names := make([]string, 0, len(tm.lanes[tenantID]))
+	for name := range tm.lanes[tenantID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stats.Pools = append(stats.Pools, poolStats(tm.lanes[tenantID][name].pool, name, "dedicated"))
+	}
+	return stats, nil
+}
+
+func poolStats(pool *WorkerPool, lane, mode string) models.PoolStats {
+	return models.PoolStats{
+		Lane:          lane,
+		Mode:          mode,
+		Workers:       int(pool.Workers()),
+		Running:       int(pool.Running()),
+		Busy:          int(pool.Busy()),
+		QueuedJobs:    pool.Queued(),
+		QueueCapacity: pool.Capacity(),
+		Processed:     pool.Processed(),
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"jatis/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidFilter is returned for payload filters that do not parse.
+var ErrInvalidFilter = errors.New("invalid payload filter")
+
+// Payload filter operators.
+const (
+	FilterEquals    = "="
+	FilterNotEquals = "!="
+)
+
+// filterPathSegment is the allowlist of characters in a payload filter path
+// segment.
+var filterPathSegment = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// PayloadFilter matches messages whose payload has Value, as text, at Path.
+// With FilterNotEquals it matches payloads that have a different value there;
+// payloads without the path match neither.
+type PayloadFilter struct {
+	Path  []string
+	Op    string
+	Value string
+}
+
+// ParsePayloadFilter parses a filter of the form path=value or path!=value,
+// where path is a dot-separated list of object keys, e.g. "meta.env=test".
+// The value is everything after the first operator.
+func ParsePayloadFilter(expr string) (PayloadFilter, error) {
+	var filter PayloadFilter
+	i := strings.Index(expr, FilterEquals)
+	if i < 0 {
+		return filter, fmt.Errorf("%w: %q has no = or != operator", ErrInvalidFilter, expr)
+	}
+	path, value := expr[:i], expr[i+len(FilterEquals):]
+	filter.Op, filter.Value = FilterEquals, value
+	if strings.HasSuffix(path, "!") {
+		path, filter.Op = strings.TrimSuffix(path, "!"), FilterNotEquals
+	}
+
+	filter.Path = strings.Split(path, ".")
+	for _, segment := range filter.Path {
+		if !filterPathSegment.MatchString(segment) {
+			return filter, fmt.Errorf("%w: %q is not a valid path", ErrInvalidFilter, path)
+		}
+	}
+	return filter, nil
+}
+
+// DeleteMessagesByFilter deletes the tenant's messages whose payload matches
+// every filter and returns how many were deleted. At least one filter is
+// required, so a missing filter cannot purge the whole tenant.
+func (ms *MessageService) DeleteMessagesByFilter(tenantID string, filters []PayloadFilter) (*models.PurgeResult, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("%w: at least one filter is required", ErrInvalidFilter)
+	}
+	if err := ms.checkPayloadQuery(ErrInvalidFilter, "payload filters"); err != nil {
+		return nil, err
+	}
+	if err := ms.checkTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	// The tenant_id condition keeps the delete to the tenant's partition
+	var where whereClause
+	where.add("tenant_id = ?", tenantID)
+	if err := where.addPayloadFilters(filters); err != nil {
+		return nil, err
+	}
+
+	result, err := ms.db.Exec(`DELETE FROM messages`+where.String(), where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete messages: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return &models.PurgeResult{TenantID: tenantID, Deleted: deleted}, nil
+}
+
+// addPayloadFilters adds a condition for each filter.
+func (w *whereClause) addPayloadFilters(filters []PayloadFilter) error {
+	for _, filter := range filters {
+		switch filter.Op {
+		case FilterEquals:
+			w.add("payload #>> ?::text[] = ?", pq.Array(filter.Path), filter.Value)
+		case FilterNotEquals:
+			w.add("payload #>> ?::text[] <> ?", pq.Array(filter.Path), filter.Value)
+		default:
+			return fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, filter.Op)
+		}
+	}
+	return nil
+}
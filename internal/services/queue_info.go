@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+
+	"jatis/internal/messaging"
+	"jatis/internal/models"
+)
+
+// QueueInfo returns how the tenant's main queue, or one of its lane queues,
+// is declared on the broker: its arguments, counts and bindings. It reads
+// them from the management API, so it needs rabbitmq.management_url, and
+// returns messaging.ErrQueueNotFound if the broker does not have the queue.
+func (tm *TenantManager) QueueInfo(ctx context.Context, tenantID, lane string) (*models.QueueInfo, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	declared, bindings, err := tm.rabbitmq.InspectQueueDeclaration(ctx, messaging.QueueName(tenantID, lane))
+	if err != nil {
+		return nil, err
+	}
+	info := &models.QueueInfo{
+		TenantID:               tenantID,
+		Lane:                   lane,
+		Name:                   declared.Name,
+		Durable:                declared.Durable,
+		AutoDelete:             declared.AutoDelete,
+		Exclusive:              declared.Exclusive,
+		Arguments:              declared.Arguments,
+		Messages:               declared.Messages,
+		MessagesReady:          declared.MessagesReady,
+		MessagesUnacknowledged: declared.MessagesUnacknowledged,
+		Consumers:              declared.Consumers,
+		Bindings:               make([]models.QueueBinding, len(bindings)),
+	}
+	if info.Arguments == nil {
+		info.Arguments = map[string]interface{}{}
+	}
+	for i, b := range bindings {
+		info.Bindings[i] = models.QueueBinding{Exchange: b.Source, RoutingKey: b.RoutingKey, Arguments: b.Arguments}
+	}
+	return info, nil
+}
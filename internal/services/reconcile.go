@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"jatis/internal/models"
+)
+
+// reconcileWorker reconciles worker pools every interval until Shutdown.
+func (tm *TenantManager) reconcileWorker(interval time.Duration) {
+	defer close(tm.reconcileExited)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := tm.Reconcile(); err != nil {
+				log.Printf("Worker pool reconciliation failed: %v", err)
+			}
+		case <-tm.reconcileDone:
+			return
+		}
+	}
+}
+
+// Reconcile resizes every dedicated worker pool and lane pool on this
+// instance whose live worker count differs from tenant_configs.workers, or
+// the tenant's schedule window in force, or tenant_lanes.workers, e.g. after
+// a scale-down that did not complete. Each
+// correction is logged, and the result is kept for LastReconcile.
+func (tm *TenantManager) Reconcile() (*models.ReconcileResult, error) {
+	tm.reconcileMu.Lock()
+	defer tm.reconcileMu.Unlock()
+
+	result := &models.ReconcileResult{RanAt: time.Now().UTC(), Corrections: []models.PoolCorrection{}}
+	err := tm.reconcile(result)
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	tm.mu.Lock()
+	tm.lastReconcile = result
+	tm.mu.Unlock()
+
+	return result, err
+}
+
+// LastReconcile returns the result of the latest reconciliation, or nil if
+// none has run yet.
+func (tm *TenantManager) LastReconcile() *models.ReconcileResult {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.lastReconcile
+}
+
+func (tm *TenantManager) reconcile(result *models.ReconcileResult) error {
+	stored, err := tm.storedWorkers()
+	if err != nil {
+		return err
+	}
+
+	type drift struct {
+		correction models.PoolCorrection
+		pool       *WorkerPool
+	}
+	var drifted []drift
+	check := func(tenantID, lane string, pool *WorkerPool) {
+		workers, exists := stored[poolKey{tenantID: tenantID, lane: lane}]
+		if !exists {
+			return
+		}
+		result.Checked++
+		if live := pool.Workers(); live != int32(workers) {
+			drifted = append(drifted, drift{
+				correction: models.PoolCorrection{TenantID: tenantID, Lane: lane, From: int(live), To: workers},
+				pool:       pool,
+			})
+		}
+	}
+
+	tm.mu.RLock()
+	for tenantID, pool := range tm.workerPools {
+		check(tenantID, "", pool)
+	}
+	for tenantID, tenantLanes := range tm.lanes {
+		for name, l := range tenantLanes {
+			check(tenantID, name, l.pool)
+		}
+	}
+	tm.mu.RUnlock()
+
+	for _, d := range drifted {
+		c := d.correction
+		if !tm.resizeIfLive(c, d.pool) {
+			continue
+		}
+		if c.Lane == "" {
+			log.Printf("Reconciled worker pool of tenant %s from %d to %d workers", c.TenantID, c.From, c.To)
+		} else {
+			log.Printf("Reconciled lane %s of tenant %s from %d to %d workers", c.Lane, c.TenantID, c.From, c.To)
+		}
+		result.Corrections = append(result.Corrections, c)
+	}
+	return nil
+}
+
+// resizeIfLive applies a correction to pool unless the pool was stopped or
+// replaced since it was checked.
+func (tm *TenantManager) resizeIfLive(c models.PoolCorrection, pool *WorkerPool) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	live := tm.workerPools[c.TenantID]
+	if c.Lane != "" {
+		live = nil
+		if l, exists := tm.lanes[c.TenantID][c.Lane]; exists {
+			live = l.pool
+		}
+	}
+	if live != pool {
+		return false
+	}
+	pool.UpdateWorkers(int32(c.To))
+	return true
+}
+
+// poolKey identifies a tenant's main pool (empty lane) or one of its lanes.
+type poolKey struct {
+	tenantID string
+	lane     string
+}
+
+// storedWorkers loads the configured worker count of every tenant and lane,
+// that of a tenant's schedule window when one is in force.
+func (tm *TenantManager) storedWorkers() (map[poolKey]int, error) {
+	query := `
+		SELECT tenant_id, '', workers, worker_schedule FROM tenant_configs
+		UNION ALL
+		SELECT tenant_id, name, workers, '[]'::jsonb FROM tenant_lanes
+	`
+	rows, err := tm.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load worker config: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[poolKey]int)
+	for rows.Next() {
+		var key poolKey
+		var workers int
+		var schedule []byte
+		if err := rows.Scan(&key.tenantID, &key.lane, &workers, &schedule); err != nil {
+			return nil, fmt.Errorf("failed to scan worker config: %w", err)
+		}
+		stored[key] = tm.workerTarget(workers, decodeWorkerSchedule(schedule))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load worker config: %w", err)
+	}
+	return stored, nil
+}
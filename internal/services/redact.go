@@ -0,0 +1,47 @@
+package services
+
+import "strings"
+
+// RedactedValue replaces redacted payload fields in log output.
+const RedactedValue = "***"
+
+// RedactPayload returns a copy of payload with every field matching one of
+// paths replaced by RedactedValue. Paths are dot-separated object keys, such
+// as "password" or "card.number"; arrays along a path are redacted element by
+// element. The original payload is never modified.
+func RedactPayload(payload interface{}, paths []string) interface{} {
+	result := payload
+	for _, path := range paths {
+		result = redactPath(result, strings.Split(path, "."))
+	}
+	return result
+}
+
+func redactPath(value interface{}, path []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		field, exists := v[path[0]]
+		if !exists {
+			return v
+		}
+
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = item
+		}
+		if len(path) == 1 {
+			redacted[path[0]] = RedactedValue
+		} else {
+			redacted[path[0]] = redactPath(field, path[1:])
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactPath(item, path)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"jatis/internal/logging"
+	"jatis/internal/messaging"
+	"jatis/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrMessagePending is returned when reprocessing a message that is still
+// waiting to be processed.
+var ErrMessagePending = errors.New("message is already pending")
+
+// reprocessBatchSize is how many messages are reset and republished at a
+// time, so large windows are not held in memory at once.
+const reprocessBatchSize = 500
+
+// ReprocessMessages sends the tenant's processed messages created within
+// [from, to) through processing again. Each message keeps its ID and row: it
+// is reset to pending with no attempts and republished, and the new result
+// overwrites the old one. Pending and failed messages are left alone.
+func (ms *MessageService) ReprocessMessages(tenantID string, from, to time.Time) (*models.ReprocessResult, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidRange)
+	}
+	if err := ms.checkTenant(tenantID); err != nil {
+		return nil, err
+	}
+	ingest, err := ms.tenantIngest(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ReprocessResult{TenantID: tenantID, From: from.UTC(), To: to.UTC()}
+
+	// Walk the window in (created_at, id) order. Reset messages may be
+	// processed again before the walk ends, so the keyset rather than the
+	// status keeps them from being picked up twice.
+	var afterCreatedAt time.Time
+	var afterID string
+	for {
+		batch, err := ms.resetForReprocessing(tenantID, from.UTC(), to.UTC(), afterCreatedAt, afterID)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, message := range batch {
+			if message.payloadRef.Valid {
+				if message.payload, err = ms.loadBlob(message.payloadRef.String); err != nil {
+					logging.Printf("Failed to load message %s for reprocessing: %v", message.ID, err)
+					ms.restoreStatus(tenantID, message.ID, models.MessageStatusProcessed)
+					result.Failed++
+					continue
+				}
+			}
+			envelope := messaging.Envelope{MessageID: message.ID, CorrelationID: message.CorrelationID, Lane: message.Lane, Transient: ingest.transient}
+			if err := ms.rabbitmq.Publish(tenantID, message.payload, envelope); err != nil {
+				logging.Printf("Failed to republish message %s for reprocessing: %v", message.ID, err)
+				ms.restoreStatus(tenantID, message.ID, models.MessageStatusProcessed)
+				result.Failed++
+				continue
+			}
+			ms.lifecycle.Event(logging.EventPublished, tenantID, message.ID, message.CorrelationID, "lane", message.Lane, "reprocessed", "true")
+			result.Reprocessed++
+		}
+
+		last := batch[len(batch)-1]
+		afterCreatedAt, afterID = last.CreatedAt, last.ID
+		if len(batch) < reprocessBatchSize {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// reprocessMessage is a message reset for reprocessing, with its payload as
+// stored, or the blob store key of an offloaded one.
+type reprocessMessage struct {
+	models.Message
+	payload    []byte
+	payloadRef sql.NullString
+}
+
+// resetForReprocessing resets the next batch of processed messages in the
+// window after the given keyset position and returns them in order. A zero
+// afterCreatedAt starts at the beginning of the window.
+func (ms *MessageService) resetForReprocessing(tenantID string, from, to, afterCreatedAt time.Time, afterID string) ([]reprocessMessage, error) {
+	var where whereClause
+	where.add("tenant_id = ?", tenantID)
+	where.add("status = ?", models.MessageStatusProcessed)
+	where.add("created_at >= ?", from)
+	where.add("created_at < ?", to)
+	where.add(notExpired)
+	if !afterCreatedAt.IsZero() {
+		where.add("(created_at, id) > (?, ?)", afterCreatedAt, afterID)
+	}
+
+	query := `
+		WITH batch AS (
+			SELECT id FROM messages` + where.String() + `
+			ORDER BY created_at, id
+			LIMIT ` + where.arg(reprocessBatchSize) + `
+			FOR UPDATE
+		)
+		UPDATE messages m SET status = 'pending', attempts = 0
+		FROM batch
+		WHERE m.tenant_id = ` + where.arg(tenantID) + ` AND m.id = batch.id
+		RETURNING m.id, m.payload, m.payload_ref, m.correlation_id, m.lane, m.created_at
+	`
+	rows, err := ms.db.Query(query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset messages for reprocessing: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []reprocessMessage
+	for rows.Next() {
+		var message reprocessMessage
+		var correlationID, lane sql.NullString
+		if err := rows.Scan(&message.ID, &message.payload, &message.payloadRef, &correlationID, &lane, &message.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		message.TenantID = tenantID
+		message.CorrelationID = correlationID.String
+		message.Lane = lane.String
+		batch = append(batch, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to reset messages for reprocessing: %w", err)
+	}
+
+	// RETURNING does not keep the order of the batch
+	sort.Slice(batch, func(i, j int) bool {
+		if !batch[i].CreatedAt.Equal(batch[j].CreatedAt) {
+			return batch[i].CreatedAt.Before(batch[j].CreatedAt)
+		}
+		return batch[i].ID < batch[j].ID
+	})
+	return batch, nil
+}
+
+// ReprocessMessage sends a single processed or failed message through
+// processing again, for debugging one message rather than a window. It keeps
+// its ID and row: it is reset to pending with no attempts and republished to
+// its tenant's queue. The message is returned as reset.
+func (ms *MessageService) ReprocessMessage(messageID string) (*models.Message, error) {
+	// Messages published outside the API have no row to reprocess
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, fmt.Errorf("message not found")
+	}
+
+	var tenantID, status string
+	err := ms.db.QueryRow(`SELECT tenant_id, status FROM messages WHERE id = $1 AND `+notExpired, messageID).Scan(&tenantID, &status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up message: %w", err)
+	}
+
+	query := `
+		UPDATE messages SET status = 'pending', attempts = 0
+		WHERE tenant_id = $1 AND id = $2 AND status <> 'pending'
+		RETURNING id, tenant_id, payload, metadata, correlation_id, lane, status, created_at, expires_at, payload_ref, payload
+	`
+	ingest, err := ms.tenantIngest(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	message, err := ms.scanMessage(payloadRow{ms.db.QueryRow(query, tenantID, messageID), &payload})
+	if err == sql.ErrNoRows {
+		return nil, ErrMessagePending
+	}
+	if err != nil {
+		ms.restoreStatus(tenantID, messageID, status)
+		return nil, fmt.Errorf("failed to reset message for reprocessing: %w", err)
+	}
+	// An offloaded payload is stored as NULL; republish the one read back
+	if payload == nil {
+		if payload, err = json.Marshal(message.Payload); err != nil {
+			ms.restoreStatus(tenantID, messageID, status)
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+	}
+
+	envelope := messaging.Envelope{MessageID: message.ID, CorrelationID: message.CorrelationID, Lane: message.Lane, Transient: ingest.transient}
+	if err := ms.rabbitmq.Publish(tenantID, payload, envelope); err != nil {
+		ms.restoreStatus(tenantID, message.ID, status)
+		return nil, fmt.Errorf("failed to republish message: %w", err)
+	}
+	ms.lifecycle.Event(logging.EventPublished, tenantID, message.ID, message.CorrelationID, "lane", message.Lane, "reprocessed", "true")
+	return message, nil
+}
+
+// payloadRow scans a message row followed by its payload as stored.
+type payloadRow struct {
+	row     rowScanner
+	payload *[]byte
+}
+
+func (r payloadRow) Scan(dest ...interface{}) error {
+	return r.row.Scan(append(dest, r.payload)...)
+}
+
+// restoreStatus puts back the status of a message that could not be
+// republished, so it is not left pending with nothing to process it.
+func (ms *MessageService) restoreStatus(tenantID, messageID, status string) {
+	query := `UPDATE messages SET status = $3 WHERE tenant_id = $1 AND id = $2 AND status = 'pending'`
+	if _, err := ms.db.Exec(query, tenantID, messageID, status); err != nil {
+		log.Printf("Failed to restore status of message %s: %v", messageID, err)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"jatis/internal/logging"
+	"jatis/internal/messaging"
+	"jatis/internal/metrics"
+	"jatis/internal/models"
+)
+
+// RestartConsumer stops the tenant's consumers and dedicated worker pools,
+// lanes included, and starts them again from the tenant's stored config.
+// Messages already being handled are allowed to finish and settle first.
+// Messages still queued in the pools go back to the broker and are redelivered
+// to the new consumers. Tenants on the shared pool keep using it; messages of
+// theirs queued there when the restart happens are redelivered as well.
+// With cluster.enabled, only the instance owning the tenant can restart its
+// consumer; others return ErrNotOwner.
+func (tm *TenantManager) RestartConsumer(tenantID string) (*models.ConsumerStatus, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+	if tm.cfg.Cluster.Enabled && !tm.ownsTenant(tenantID) {
+		return nil, ErrNotOwner
+	}
+
+	if err := tm.StartConsumer(tenantID); err != nil {
+		return nil, fmt.Errorf("failed to restart consumer: %w", err)
+	}
+
+	return tm.GetConsumerStatus(tenantID)
+}
+
+// StartConsumer starts consuming the tenant from its stored config. Consumers
+// and dedicated pools already running for it are stopped first, as
+// RestartConsumer describes, so calling it again never leaves two consumers
+// on one of the tenant's queues.
+func (tm *TenantManager) StartConsumer(tenantID string) error {
+	return tm.startTenantConsumer(tenantID)
+}
+
+// stopTenantConsumer stops the tenant's consumers and dedicated worker pools,
+// lanes included, once the messages being handled have settled. Messages
+// still queued in the pools go back to the broker.
+func (tm *TenantManager) stopTenantConsumer(tenantID string) {
+	unlock := tm.consumerLocks.lock(tenantID)
+	defer unlock()
+	tm.stopTenantConsumerLocked(tenantID)
+}
+
+// stopTenantConsumerLocked is stopTenantConsumer for a caller holding the
+// tenant's consumer lock. The consumers and pools are taken out of the
+// manager in one go, so a start that follows builds its own.
+func (tm *TenantManager) stopTenantConsumerLocked(tenantID string) {
+	tm.mu.Lock()
+	consumers, pools := tm.detachTenantConsumer(tenantID)
+	delete(tm.idle, tenantID)
+	tm.mu.Unlock()
+
+	tm.stopDetached(tenantID, consumers, pools)
+}
+
+// detachTenantConsumer takes the tenant's consumers and pools, lanes
+// included, out of the manager for stopDetached. The caller must hold tm.mu.
+func (tm *TenantManager) detachTenantConsumer(tenantID string) ([]*messaging.Consumer, []*WorkerPool) {
+	consumers := []*messaging.Consumer{}
+	if consumer, exists := tm.consumers[tenantID]; exists {
+		consumers = append(consumers, consumer)
+		delete(tm.consumers, tenantID)
+	}
+	pools := []*WorkerPool{}
+	if pool, exists := tm.workerPools[tenantID]; exists {
+		pools = append(pools, pool)
+		delete(tm.workerPools, tenantID)
+	}
+	for _, l := range tm.lanes[tenantID] {
+		if l.consumer != nil {
+			consumers = append(consumers, l.consumer)
+		}
+		pools = append(pools, l.pool)
+	}
+	delete(tm.lanes, tenantID)
+	if tm.deadLetters != nil {
+		tm.deadLetters.Unsubscribe(tenantID)
+	}
+	return consumers, pools
+}
+
+// stopDetached stops consumers and pools taken out by detachTenantConsumer,
+// handing what they still hold back to the broker.
+func (tm *TenantManager) stopDetached(tenantID string, consumers []*messaging.Consumer, pools []*WorkerPool) {
+	// Stop new deliveries while the channels stay open, so the messages being
+	// handled can still be acked
+	for _, consumer := range consumers {
+		if err := consumer.Cancel(); err != nil {
+			logging.Printf("Warning: failed to cancel consumer of tenant %s: %v", tenantID, err)
+		}
+	}
+
+	var queued []Job
+	for _, pool := range pools {
+		queued = append(queued, pool.Drain()...)
+	}
+
+	// Closing the channels hands every unsettled delivery back to the broker
+	for _, consumer := range consumers {
+		consumer.Stop()
+	}
+	for _, job := range queued {
+		if job.lease != nil {
+			job.lease.Abandon()
+		}
+	}
+}
+
+// consumerRestarts tallies the times a tenant's consumer was recreated after
+// the broker connection was lost.
+type consumerRestarts struct {
+	count int64
+	last  time.Time
+}
+
+// recordConsumerRestart counts a restart of the tenant's consumer after a
+// lost connection. Frequent restarts point at an unstable broker.
+func (tm *TenantManager) recordConsumerRestart(tenantID string) {
+	tm.mu.Lock()
+	restarts, exists := tm.restarts[tenantID]
+	if !exists {
+		restarts = &consumerRestarts{}
+		tm.restarts[tenantID] = restarts
+	}
+	restarts.count++
+	restarts.last = time.Now().UTC()
+	tm.mu.Unlock()
+
+	metrics.RecordConsumerRestart(tenantID)
+}
+
+// tenantLocks holds a mutex per tenant. Entries exist only while the lock is
+// held or waited on, so deleted tenants leave nothing behind.
+type tenantLocks struct {
+	mu    sync.Mutex
+	locks map[string]*tenantLock
+}
+
+type tenantLock struct {
+	sync.Mutex
+	// refs counts the holder and waiters, guarded by tenantLocks.mu
+	refs int
+}
+
+// lock locks the tenant's mutex and returns the function unlocking it.
+func (l *tenantLocks) lock(tenantID string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*tenantLock)
+	}
+	tl, exists := l.locks[tenantID]
+	if !exists {
+		tl = &tenantLock{}
+		l.locks[tenantID] = tl
+	}
+	tl.refs++
+	l.mu.Unlock()
+
+	tl.Lock()
+	return func() {
+		tl.Unlock()
+		l.mu.Lock()
+		if tl.refs--; tl.refs == 0 {
+			delete(l.locks, tenantID)
+		}
+		l.mu.Unlock()
+	}
+}
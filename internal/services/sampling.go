@@ -0,0 +1,81 @@
+package services
+
+import (
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand/v2"
+
+	"jatis/internal/messaging"
+	"jatis/internal/metrics"
+	"jatis/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Sampled reports whether the message with messageID falls within a sample
+// of rate, from 0 to 1. The decision is a hash of the ID, so a redelivered
+// message is sampled the same way every time; messages without an ID are
+// sampled at random.
+func Sampled(messageID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	if messageID == "" {
+		return rand.Float64() < rate
+	}
+	h := fnv.New64a()
+	h.Write([]byte(messageID))
+	return float64(h.Sum64()) < rate*math.MaxUint64
+}
+
+// UpdateSampleRate sets the share of the tenant's messages that are
+// processed. It takes effect for the next delivery.
+func (tm *TenantManager) UpdateSampleRate(tenantID string, rate float64) error {
+	if err := tm.updateTenantConfig(tenantID, "sample_rate", rate); err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	tm.sampleRates[tenantID] = rate
+	tm.mu.Unlock()
+
+	return nil
+}
+
+// sampleRate returns the tenant's sample rate, defaulting to processing
+// every message.
+func (tm *TenantManager) sampleRate(tenantID string) float64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if rate, ok := tm.sampleRates[tenantID]; ok {
+		return rate
+	}
+	return 1
+}
+
+// sampleOut reports whether a delivery falls outside the tenant's sample. If
+// it does, the delivery is acked without being processed, counted under
+// messages_processed_total with status sampled_out, and its message marked
+// sampled_out.
+func (tm *TenantManager) sampleOut(tenantID string, lease *messaging.Lease) bool {
+	delivery := lease.Delivery()
+	if Sampled(delivery.MessageId, tm.sampleRate(tenantID)) {
+		return false
+	}
+
+	// Messages published outside the API have no row to mark
+	if _, err := uuid.Parse(delivery.MessageId); err == nil {
+		query := `UPDATE messages SET status = $3 WHERE tenant_id = $1 AND id = $2`
+		if _, err := tm.db.Exec(query, tenantID, delivery.MessageId, models.MessageStatusSampledOut); err != nil {
+			log.Printf("Failed to mark message %s as sampled out: %v", delivery.MessageId, err)
+		}
+	}
+	lease.Ack()
+	metrics.IncrementMessagesProcessed(tenantID, "sampled_out")
+	return true
+}
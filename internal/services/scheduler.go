@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// strideUnit is the virtual time a weight-1 tenant is charged per job.
+// Heavier tenants are charged proportionally less, so they are picked more
+// often.
+const strideUnit = 1 << 20
+
+// Scheduler shares a fixed number of processing slots between tenants by
+// weighted fair queueing. Worker pools built with a scheduler take a slot for
+// every job they run, so tenants compete for slots by weight instead of by how
+// many workers or queued messages they have.
+type Scheduler struct {
+	mu      sync.Mutex
+	free    int
+	weights map[string]int
+	tags    virtualClock
+	waiting map[string][]chan struct{}
+}
+
+// NewScheduler returns a scheduler with the given number of slots.
+func NewScheduler(slots int) *Scheduler {
+	if slots < 1 {
+		slots = 1
+	}
+	return &Scheduler{
+		free:    slots,
+		weights: make(map[string]int),
+		tags:    newVirtualClock(),
+		waiting: make(map[string][]chan struct{}),
+	}
+}
+
+// SetWeight sets a tenant's share relative to other tenants. Tenants default
+// to a weight of 1.
+func (s *Scheduler) SetWeight(tenantID string, weight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if weight < 1 {
+		delete(s.weights, tenantID)
+		return
+	}
+	s.weights[tenantID] = weight
+}
+
+// Weight returns a tenant's weight.
+func (s *Scheduler) Weight(tenantID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.weight(tenantID)
+}
+
+// Forget drops everything the scheduler knows about a tenant.
+func (s *Scheduler) Forget(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.weights, tenantID)
+	s.tags.forget(tenantID)
+}
+
+func (s *Scheduler) weight(tenantID string) int {
+	if w, ok := s.weights[tenantID]; ok {
+		return w
+	}
+	return 1
+}
+
+// Acquire blocks until the tenant is granted a slot or ctx is done. Every
+// successful Acquire must be paired with a Release.
+func (s *Scheduler) Acquire(ctx context.Context, tenantID string) error {
+	s.mu.Lock()
+	if s.free > 0 {
+		s.free--
+		s.tags.advance(s.tags.next(tenantID, s.weight(tenantID)))
+		s.mu.Unlock()
+		return nil
+	}
+	granted := make(chan struct{})
+	s.waiting[tenantID] = append(s.waiting[tenantID], granted)
+	s.mu.Unlock()
+
+	select {
+	case <-granted:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	waiters := s.waiting[tenantID]
+	for i, ch := range waiters {
+		if ch == granted {
+			s.waiting[tenantID] = append(waiters[:i], waiters[i+1:]...)
+			if len(s.waiting[tenantID]) == 0 {
+				delete(s.waiting, tenantID)
+			}
+			return ctx.Err()
+		}
+	}
+	// The slot was granted just as ctx was cancelled; pass it on
+	s.release()
+	return ctx.Err()
+}
+
+// Release returns a slot, handing it to the waiting tenant that is furthest
+// behind its fair share.
+func (s *Scheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.release()
+}
+
+func (s *Scheduler) release() {
+	var next string
+	var nextStart uint64
+	for tenantID := range s.waiting {
+		start := s.tags.start(tenantID)
+		if next == "" || start < nextStart || (start == nextStart && tenantID < next) {
+			next, nextStart = tenantID, start
+		}
+	}
+	if next == "" {
+		s.free++
+		return
+	}
+
+	waiters := s.waiting[next]
+	granted := waiters[0]
+	if len(waiters) == 1 {
+		delete(s.waiting, next)
+	} else {
+		s.waiting[next] = waiters[1:]
+	}
+	s.tags.advance(s.tags.next(next, s.weight(next)))
+	close(granted)
+}
+
+// virtualClock hands out start-time fair queueing tags. Each tenant's tags
+// advance by strideUnit/weight per job, starting no earlier than the clock,
+// so a tenant that was idle cannot bank credit and a busy tenant cannot
+// run ahead of the others by more than its share.
+type virtualClock struct {
+	now  uint64
+	last map[string]uint64
+}
+
+func newVirtualClock() virtualClock {
+	return virtualClock{last: make(map[string]uint64)}
+}
+
+// start returns the tag the tenant's next job would start at.
+func (c *virtualClock) start(tenantID string) uint64 {
+	if last := c.last[tenantID]; last > c.now {
+		return last
+	}
+	return c.now
+}
+
+// next tags a job for the tenant and returns its start tag.
+func (c *virtualClock) next(tenantID string, weight int) uint64 {
+	start := c.start(tenantID)
+	c.last[tenantID] = start + uint64(strideUnit/weight)
+	return start
+}
+
+// advance moves the clock to tag, the start tag of the job now being served.
+func (c *virtualClock) advance(tag uint64) {
+	if tag > c.now {
+		c.now = tag
+	}
+}
+
+func (c *virtualClock) forget(tenantID string) {
+	delete(c.last, tenantID)
+}
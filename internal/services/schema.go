@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+
+	"jatis/internal/logging"
+	"jatis/internal/messaging"
+	"jatis/internal/metrics"
+	"jatis/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidSchema is returned for a payload schema using a type that
+	// does not exist
+	ErrInvalidSchema = errors.New("invalid payload schema")
+	// ErrSchemaViolation is returned for a payload that does not match its
+	// tenant's schema
+	ErrSchemaViolation = errors.New("payload does not match schema")
+)
+
+var schemaTypes = map[string]bool{
+	"": true, "object": true, "array": true, "string": true,
+	"number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// CheckPayloadSchema returns ErrInvalidSchema unless every type in schema is
+// one ValidatePayload knows.
+func CheckPayloadSchema(schema *models.PayloadSchema) error {
+	return checkSchema(schema, "$")
+}
+
+func checkSchema(schema *models.PayloadSchema, path string) error {
+	if schema == nil {
+		return nil
+	}
+	if !schemaTypes[schema.Type] {
+		return fmt.Errorf("%w: unknown type %q at %s", ErrInvalidSchema, schema.Type, path)
+	}
+	for name, property := range schema.Properties {
+		if err := checkSchema(property, path+"."+name); err != nil {
+			return err
+		}
+	}
+	return checkSchema(schema.Items, path+"[]")
+}
+
+// ValidatePayload returns ErrSchemaViolation, naming the first offending
+// path, unless payload, as decoded from JSON, matches schema. A nil schema
+// matches anything.
+func ValidatePayload(schema *models.PayloadSchema, payload interface{}) error {
+	return validate(schema, payload, "$")
+}
+
+func validate(schema *models.PayloadSchema, value interface{}, path string) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Type != "" && !hasSchemaType(value, schema.Type) {
+		return fmt.Errorf("%w: %s must be of type %s", ErrSchemaViolation, path, schema.Type)
+	}
+	if len(schema.Enum) > 0 {
+		allowed := false
+		for _, option := range schema.Enum {
+			if reflect.DeepEqual(option, value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s must be one of the enum values", ErrSchemaViolation, path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, exists := v[name]; !exists {
+				return fmt.Errorf("%w: %s.%s is required", ErrSchemaViolation, path, name)
+			}
+		}
+		for name, property := range schema.Properties {
+			if field, exists := v[name]; exists {
+				if err := validate(property, field, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasSchemaType(value interface{}, schemaType string) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return schemaType == "object"
+	case []interface{}:
+		return schemaType == "array"
+	case string:
+		return schemaType == "string"
+	case float64:
+		return schemaType == "number" || (schemaType == "integer" && v == math.Trunc(v))
+	case bool:
+		return schemaType == "boolean"
+	case nil:
+		return schemaType == "null"
+	}
+	return false
+}
+
+// UpdatePayloadSchema sets the schema the tenant's payloads are checked
+// against before they are processed, or removes it if schema is nil. It
+// takes effect for the next delivery.
+func (tm *TenantManager) UpdatePayloadSchema(tenantID string, schema *models.PayloadSchema) error {
+	if err := CheckPayloadSchema(schema); err != nil {
+		return err
+	}
+	var value interface{}
+	if schema != nil {
+		schemaBytes, err := json.Marshal(schema)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload schema: %w", err)
+		}
+		value = string(schemaBytes)
+	}
+
+	if err := tm.updateTenantConfig(tenantID, "payload_schema", value); err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	tm.setPayloadSchema(tenantID, schema)
+	tm.mu.Unlock()
+
+	return nil
+}
+
+// setPayloadSchema caches the tenant's schema. tm.mu must be held.
+func (tm *TenantManager) setPayloadSchema(tenantID string, schema *models.PayloadSchema) {
+	if schema == nil {
+		delete(tm.payloadSchemas, tenantID)
+		return
+	}
+	tm.payloadSchemas[tenantID] = schema
+}
+
+// decodePayloadSchema decodes a stored payload schema. One that cannot be
+// decoded is logged and treated as absent.
+func decodePayloadSchema(raw []byte) *models.PayloadSchema {
+	if len(raw) == 0 {
+		return nil
+	}
+	var schema models.PayloadSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		log.Printf("Warning: ignoring undecodable payload schema: %v", err)
+		return nil
+	}
+	return &schema
+}
+
+// invalid reports whether a delivery's payload fails the tenant's payload
+// schema, which catches messages published straight to the queue as well as
+// those created through the API. If it does, it is dead-lettered without
+// being processed, whatever the tenant's failure policy, with the violation
+// as the reason; it is counted under messages_processed_total with status
+// invalid and its message marked failed.
+func (tm *TenantManager) invalid(tenantID string, lease *messaging.Lease) bool {
+	tm.mu.RLock()
+	schema := tm.payloadSchemas[tenantID]
+	tm.mu.RUnlock()
+	if schema == nil {
+		return false
+	}
+
+	delivery := lease.Delivery()
+	var payload interface{}
+	err := json.Unmarshal(delivery.Body, &payload)
+	if err != nil {
+		err = fmt.Errorf("%w: payload is not JSON", ErrSchemaViolation)
+	} else {
+		err = ValidatePayload(schema, payload)
+	}
+	if err == nil {
+		return false
+	}
+
+	reason := err.Error()
+	log.Printf("Warning: dead-lettering message %s for tenant %s: %s", delivery.MessageId, tenantID, reason)
+
+	// Messages published outside the API have no row to mark
+	if _, err := uuid.Parse(delivery.MessageId); err == nil {
+		query := `UPDATE messages SET status = $3 WHERE tenant_id = $1 AND id = $2`
+		if _, err := tm.db.Exec(query, tenantID, delivery.MessageId, models.MessageStatusFailed); err != nil {
+			log.Printf("Failed to mark message %s as failed: %v", delivery.MessageId, err)
+		}
+	}
+	lease.DeadLetter(reason)
+	tm.redeliveries.Forget(tenantID, delivery.MessageId)
+	tm.lifecycle.Event(logging.EventDeadLettered, tenantID, delivery.MessageId, messaging.CorrelationID(delivery), "error", reason)
+	tm.alerts.Record(tenantID, delivery.MessageId, reason)
+	metrics.IncrementMessagesProcessed(tenantID, "invalid")
+	return true
+}
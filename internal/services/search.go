@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"jatis/internal/models"
+)
+
+// ErrInvalidSearch is returned for an empty text search.
+var ErrInvalidSearch = errors.New("invalid search")
+
+// SearchMessages finds the tenant's messages whose payload mentions the words
+// of q, most relevant first by ts_rank and newest first among equals. q uses
+// web search syntax: quoted phrases, "or" and a leading - to exclude a word.
+// Payload keys are searched as well as values.
+func (ms *MessageService) SearchMessages(ctx context.Context, tenantID, q string, limit int) (*models.MessageSearchResult, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if err := ms.checkPayloadQuery(ErrInvalidSearch, "text searches"); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if err := ms.checkTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, tenant_id, payload, metadata, correlation_id, lane, status, created_at, expires_at, payload_ref,
+			ts_rank(search_vector, query) AS rank
+		FROM messages, websearch_to_tsquery('simple', $2) query
+		WHERE tenant_id = $1 AND search_vector @@ query AND ` + notExpired + `
+		ORDER BY rank DESC, created_at DESC, id DESC
+		LIMIT $3
+	`
+	rows, err := ms.db.QueryContext(ctx, query, tenantID, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	result := &models.MessageSearchResult{Query: q, Data: []models.MessageSearchHit{}}
+	for rows.Next() {
+		var hit models.MessageSearchHit
+		message, err := ms.scanMessage(rankedRow{rows, &hit.Rank})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		hit.Message = *message
+		result.Data = append(result.Data, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return result, nil
+}
+
+// rankedRow scans a message row followed by its rank.
+type rankedRow struct {
+	row  rowScanner
+	rank *float64
+}
+
+func (r rankedRow) Scan(dest ...interface{}) error {
+	return r.row.Scan(append(dest, r.rank)...)
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"jatis/internal/database"
+	"jatis/internal/metrics"
+	"jatis/internal/models"
+)
+
+// ErrTenantNotDeleted is returned when restoring a tenant that is not
+// soft-deleted.
+var ErrTenantNotDeleted = errors.New("tenant is not deleted")
+
+// ErrTenantExists is returned when restoring the partition of a tenant that
+// exists again.
+var ErrTenantExists = errors.New("tenant already exists")
+
+// ErrRetiredPartitionNotFound is returned for a retired partition that does
+// not exist, or has already been dropped.
+var ErrRetiredPartitionNotFound = database.ErrRetiredPartitionNotFound
+
+// SoftDeleteTenant stops the tenant's consumers and hides it from the tenant
+// API, but keeps its partition, messages and queues, so RestoreTenant can
+// bring it back until deletion.grace_period has passed and the purge deletes
+// it for good. New messages are rejected in the meantime.
+func (tm *TenantManager) SoftDeleteTenant(tenantID string) error {
+	result, err := tm.db.Exec(
+		`UPDATE tenants SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tenant not found")
+	}
+
+	// Soft-deleted tenants cannot be claimed, so the owner loses its lease on
+	// the next renewal and stops consuming
+	if _, err := tm.db.Exec(`DELETE FROM tenant_owners WHERE tenant_id = $1`, tenantID); err != nil {
+		log.Printf("Warning: failed to release lease of tenant %s: %v", tenantID, err)
+	}
+	tm.mu.Lock()
+	delete(tm.owned, tenantID)
+	tm.mu.Unlock()
+	tm.stopTenantConsumer(tenantID)
+
+	metrics.DecrementActiveTenants()
+	return nil
+}
+
+// RestoreTenant undoes a soft delete and starts consuming the tenant again.
+// In a cluster, the tenant is started here if this instance can claim it.
+func (tm *TenantManager) RestoreTenant(tenantID string) (*models.Tenant, error) {
+	result, err := tm.db.Exec(
+		`UPDATE tenants SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL`, tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore tenant: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		if _, err := tm.GetTenant(tenantID); err != nil {
+			return nil, err
+		}
+		return nil, ErrTenantNotDeleted
+	}
+	metrics.IncrementActiveTenants()
+
+	if err := tm.startClaimedTenant(tenantID); err != nil {
+		return nil, err
+	}
+	return tm.GetTenant(tenantID)
+}
+
+// startClaimedTenant starts consuming a tenant that has just come into
+// existence. In a cluster only its owner consumes it, and an instance
+// without room for it leaves it to another.
+func (tm *TenantManager) startClaimedTenant(tenantID string) error {
+	if tm.cfg.Cluster.Enabled {
+		claimed, err := tm.claimTenants(tenantID)
+		if err != nil {
+			return fmt.Errorf("failed to claim tenant: %w", err)
+		}
+		if len(claimed) == 0 {
+			return nil
+		}
+	}
+	// StartExistingTenants may have started it already
+	if _, err := tm.ensureTenantConsumer(tenantID); err != nil {
+		return fmt.Errorf("failed to start tenant consumer: %w", err)
+	}
+	return nil
+}
+
+// purgeWorker purges soft-deleted tenants every interval until Shutdown.
+func (tm *TenantManager) purgeWorker(interval time.Duration) {
+	defer close(tm.purgeExited)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := tm.PurgeDeletedTenants(); err != nil {
+				log.Printf("Purging deleted tenants failed: %v", err)
+			}
+			if _, err := tm.DropRetiredPartitions(); err != nil {
+				log.Printf("Dropping retired partitions failed: %v", err)
+			}
+		case <-tm.purgeDone:
+			return
+		}
+	}
+}
+
+// PurgeDeletedTenants deletes for good, like DeleteTenant, every tenant
+// soft-deleted longer than deletion.grace_period ago, and returns how many
+// were purged. Having had their grace period, their partitions are dropped
+// straight away.
+func (tm *TenantManager) PurgeDeletedTenants() (int, error) {
+	rows, err := tm.db.Query(
+		`SELECT id FROM tenants WHERE deleted_at < NOW() - $1::interval`,
+		fmt.Sprintf("%d milliseconds", tm.cfg.Deletion.GracePeriod.Milliseconds()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list deleted tenants: %w", err)
+	}
+	var expired []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		expired = append(expired, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list deleted tenants: %w", err)
+	}
+
+	purged := 0
+	for _, tenantID := range expired {
+		if err := tm.deleteTenant(tenantID, true); err != nil {
+			log.Printf("Failed to purge deleted tenant %s: %v", tenantID, err)
+			continue
+		}
+		log.Printf("Purged tenant %s after its deletion grace period", tenantID)
+		purged++
+	}
+	return purged, nil
+}
+
+// DropRetiredPartitions drops the partitions of deleted tenants kept longer
+// than deletion.partition_grace_period, and returns how many were dropped.
+func (tm *TenantManager) DropRetiredPartitions() (int, error) {
+	partitions, err := database.ListRetiredPartitions(tm.db)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, p := range partitions {
+		if time.Since(p.RetiredAt) < tm.cfg.Deletion.PartitionGracePeriod {
+			continue
+		}
+		if err := tm.dropRetiredPartition(p.Name); err != nil {
+			return dropped, err
+		}
+		log.Printf("Dropped partition %s after its grace period", p.Name)
+		dropped++
+	}
+	return dropped, nil
+}
+
+// RetiredPartitions lists the partitions of deleted tenants still kept, and
+// when each is due to be dropped.
+func (tm *TenantManager) RetiredPartitions() ([]models.RetiredPartition, error) {
+	partitions, err := database.ListRetiredPartitions(tm.db)
+	if err != nil {
+		return nil, err
+	}
+	retired := make([]models.RetiredPartition, len(partitions))
+	for i, p := range partitions {
+		retired[i] = models.RetiredPartition{
+			Name:      p.Name,
+			TenantID:  p.TenantID,
+			RetiredAt: p.RetiredAt,
+			DropsAt:   p.RetiredAt.Add(tm.cfg.Deletion.PartitionGracePeriod),
+		}
+	}
+	return retired, nil
+}
+
+// RestoreRetiredPartition undoes a delete whose partition is still kept: it
+// recreates the tenant under its old ID, with the given name and the default
+// config, attaches the partition again and starts consuming the tenant. The
+// tenant's queues were deleted with it, so messages that were still pending
+// stay pending until they are reprocessed.
+func (tm *TenantManager) RestoreRetiredPartition(partition, name string) (*models.Tenant, error) {
+	var tenantID string
+	partitions, err := database.ListRetiredPartitions(tm.db)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range partitions {
+		if p.Name == partition {
+			tenantID = p.TenantID
+		}
+	}
+	if tenantID == "" {
+		return nil, ErrRetiredPartitionNotFound
+	}
+
+	var created bool
+	err = tm.db.QueryRow(
+		`INSERT INTO tenants (id, name) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING RETURNING true`, tenantID, name,
+	).Scan(&created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTenantExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	_, err = tm.db.Exec(`INSERT INTO tenant_configs (tenant_id, workers) VALUES ($1, $2)`, tenantID, tm.defaultWorkers)
+	if err == nil {
+		_, err = database.ReattachRetiredPartition(tm.db, partition)
+	}
+	if err != nil {
+		if _, delErr := tm.db.Exec(`DELETE FROM tenants WHERE id = $1`, tenantID); delErr != nil {
+			log.Printf("Warning: failed to remove tenant %s after a failed restore: %v", tenantID, delErr)
+		}
+		return nil, err
+	}
+	log.Printf("Restored tenant %s from partition %s", tenantID, partition)
+	metrics.IncrementActiveTenants()
+
+	if err := tm.startClaimedTenant(tenantID); err != nil {
+		return nil, err
+	}
+	return tm.GetTenant(tenantID)
+}
+
+// DropRetiredPartition drops the partition of a deleted tenant without
+// waiting for deletion.partition_grace_period to pass.
+func (tm *TenantManager) DropRetiredPartition(partition string) error {
+	if err := tm.dropRetiredPartition(partition); err != nil {
+		return err
+	}
+	log.Printf("Dropped partition %s", partition)
+	return nil
+}
+
+// dropRetiredPartition drops a retired partition and then the payloads its
+// messages had offloaded.
+func (tm *TenantManager) dropRetiredPartition(partition string) error {
+	blobs := tm.partitionBlobs(partition)
+	if err := database.DropRetiredPartition(tm.db, partition); err != nil {
+		return err
+	}
+	deleteBlobs(tm.blobs, blobs)
+	return nil
+}
+
+// partitionBlobs lists the offloaded payloads of the messages in a partition
+// about to be dropped. If they cannot be listed, the failure is logged and
+// the blobs are left behind rather than the drop held up.
+func (tm *TenantManager) partitionBlobs(partition string) []string {
+	if tm.blobs == nil {
+		return nil
+	}
+	blobs, err := database.PartitionPayloadRefs(tm.db, partition)
+	if err != nil {
+		log.Printf("Warning: offloaded payloads of partition %s are left behind: %v", partition, err)
+	}
+	return blobs
+}
@@ -1,80 +1,448 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"jatis/internal/config"
 	"jatis/internal/database"
+	"jatis/internal/logging"
 	"jatis/internal/messaging"
 	"jatis/internal/metrics"
 	"jatis/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTooManyWorkers is returned for worker counts above max_workers.
+	ErrTooManyWorkers = errors.New("worker count exceeds max_workers")
+	// ErrTooManyCreates is returned when a tenant create waited longer than
+	// database.create_wait_timeout for its turn.
+	ErrTooManyCreates = errors.New("too many tenant creations in progress")
 )
 
 type TenantManager struct {
 	db           *sql.DB
 	rabbitmq     *messaging.RabbitMQ
+	cfg          *config.Config
 	consumers    map[string]*messaging.Consumer
 	workerPools  map[string]*WorkerPool
+	sharedPool   *WorkerPool
+	// scheduler shares processing between tenants by weight when
+	// scheduler.enabled is set
+	scheduler    *Scheduler
+	// limits caps each tenant's concurrent handler runs (max_concurrency)
+	limits       *ConcurrencyLimits
+	// throughput caps messages processed per second across every pool when
+	// throughput.max_per_second is set
+	throughput   *ThroughputLimiter
+	// redeliveries counts redeliveries against consumer.max_redeliveries
+	redeliveries *RedeliveryCounts
+	// lifecycle logs message state transitions when logging.lifecycle is set
+	lifecycle    *logging.Lifecycle
+	// lanes holds each tenant's lanes by name
+	lanes        map[string]map[string]*lane
+	// failurePolicies caches each tenant's failure_policy for the workers
+	failurePolicies map[string]string
+	// sampleRates caches each tenant's sample_rate for its consumers
+	sampleRates map[string]float64
+	// payloadSchemas caches the payload_schema of each tenant that has one
+	payloadSchemas map[string]*models.PayloadSchema
+	// queueOptions caches the options each tenant's queues are declared with
+	queueOptions map[string]messaging.QueueOptions
+	// dlqHandler, if set, handles the dead letters of every tenant consumed
+	// here, which deadLetters takes from their queues
+	dlqHandler  DLQHandler
+	deadLetters *messaging.DeadLetterConsumer
+	// batchHandler, if set, processes the jobs of dedicated and lane pools
+	// in batches (consumer.batch.handler)
+	batchHandler BatchHandler
+	// creates bounds concurrent tenant creations
+	// (database.max_concurrent_creates)
+	creates *semaphore
+	// blobs holds the payloads offloaded above
+	// payload_storage.offload_threshold, which go with their partitions and
+	// expired messages; nil when payloads are stored inline
+	blobs BlobStore
+	// partitionJobs feeds the background partition worker when
+	// database.async_partitions is enabled
+	partitionJobs chan string
+	partitionDone chan struct{}
+	// deliveries tallies each tenant's deliveries since startup
+	deliveries map[string]*deliveryCounts
+	// restarts tallies each tenant's consumer restarts after a lost
+	// connection since startup
+	restarts map[string]*consumerRestarts
+	// drains holds a done channel for each tenant being drained
+	drains map[string]chan struct{}
+	// consumerLocks serializes starting, stopping and deleting each tenant's
+	// consumers, so a stop and the start after it run as one step
+	consumerLocks tenantLocks
+	// idle holds the tenants whose consumers were stopped for being idle
+	// (consumer.idle_timeout). idleDone stops the idleness checks, and
+	// idleExited is closed once the last one has returned.
+	idle       map[string]bool
+	idleDone   chan struct{}
+	idleExited chan struct{}
+	// instanceID names this instance in tenant_owners and owned holds the
+	// tenants it has leased, when cluster.enabled is set
+	instanceID string
+	owned      map[string]bool
+	leaseTTL   time.Duration
+	// ownershipDone stops the lease renewal worker, which closes
+	// ownershipExited once it has returned
+	ownershipDone   chan struct{}
+	ownershipExited chan struct{}
+	// health serves the cached dependency checks behind /readyz
+	health *HealthChecker
+	// maintenanceMu serializes scheduled and manual maintenance runs
+	maintenanceMu   sync.Mutex
+	maintenanceDone chan struct{}
+	// reconcileMu serializes reconciliations, the latest of which is kept in
+	// lastReconcile. reconcileDone stops the scheduled runs, and
+	// reconcileExited is closed once the last one has returned.
+	reconcileMu     sync.Mutex
+	reconcileDone   chan struct{}
+	reconcileExited chan struct{}
+	lastReconcile   *models.ReconcileResult
+	// scheduleDone stops the worker schedule checks, and scheduleExited is
+	// closed once the last one has returned
+	scheduleDone   chan struct{}
+	scheduleExited chan struct{}
+	// purgeDone stops the purge of soft-deleted tenants, and purgeExited is
+	// closed once the last run has returned
+	purgeDone   chan struct{}
+	purgeExited chan struct{}
+	// retentionDone stops the deletion of expired messages, and
+	// retentionExited is closed once the last run has returned
+	retentionDone   chan struct{}
+	retentionExited chan struct{}
+	// alerts batches dead-letter alerts, nil without alert targets;
+	// alertsDone stops their flushing, and alertsExited is closed once the
+	// last flush has returned
+	alerts       *DeadLetterAlerts
+	alertsDone   chan struct{}
+	alertsExited chan struct{}
+	// ctx is the parent of every worker pool context and is cancelled on
+	// Shutdown so in-flight handlers can abort
+	ctx          context.Context
+	cancel       context.CancelFunc
 	mu           sync.RWMutex
 	defaultWorkers int
 }
 
 type WorkerPool struct {
-	workers   int32
-	jobQueue  chan []byte
-	quit      chan bool
-	wg        sync.WaitGroup
+	workers    int32
+	jobQueue   *jobQueue
+	handler    JobHandler
+	ctx        context.Context
+	cancel     context.CancelFunc
+	// scheduler, if set, grants the slot each job needs to run
+	scheduler  *Scheduler
+	// limits, if set, bounds each tenant's concurrent handler runs
+	limits     *ConcurrencyLimits
+	// throughput, if set, caps the messages processed per second
+	throughput *ThroughputLimiter
+	quit       chan bool
+	// stopOnce closes quit, so Stop and Drain can be called more than once
+	stopOnce   sync.Once
+	// retiring counts the workers UpdateWorkers asked to exit that have not
+	// done so yet. Workers check it between jobs, so a busy worker leaves
+	// once its job is done and UpdateWorkers never waits on one.
+	retiring   int32
+	// shrink wakes an idle worker to check retiring
+	shrink     chan struct{}
+	wg         sync.WaitGroup
+	processed  int64
+	// batch, if set, hands jobs to a BatchHandler in groups instead of to
+	// handler one at a time
+	batch      *batchMode
+	// busy counts the workers currently handling a job
+	busy       int32
+	// running counts the worker goroutines alive, which trails workers
+	// while surplus workers finish their jobs after a scale-down
+	running    int32
+}
+
+// Job is a single message handed to a worker pool. TenantID identifies the
+// owning tenant, which lets one pool serve several tenants.
+type Job struct {
+	TenantID      string
+	MessageID     string
+	CorrelationID string
+	Priority      uint8
+	Body          []byte
+	// Lane is the tenant lane the job was consumed from, empty for the
+	// tenant's main queue.
+	Lane string
+
+	// lease is the broker delivery behind the job. It is settled once the
+	// handler returns.
+	lease *messaging.Lease
+	// activity is the delivery tally of the job's tenant, marked active
+	// once the job is settled.
+	activity *deliveryCounts
+	// failurePolicy is the tenant's failure policy when the job was
+	// submitted. Jobs built by NewJob alone leave it empty, which settles
+	// like retry_then_dlq.
+	failurePolicy string
+}
+
+// Extend pushes back the job's visibility deadline to d from now. Handlers
+// that run longer than consumer.visibility_timeout call it to keep their
+// message from being redelivered. It returns false once the deadline has
+// already passed.
+func (j Job) Extend(d time.Duration) bool {
+	if j.lease == nil {
+		return true
+	}
+	return j.lease.Extend(d)
 }
 
-func NewTenantManager(db *sql.DB, rabbitmq *messaging.RabbitMQ, defaultWorkers int) *TenantManager {
+// deliveryCounts tallies the deliveries of a tenant's consumers.
+type deliveryCounts struct {
+	delivered   int64
+	redelivered int64
+	// lastActive is when, in Unix nanoseconds, the tenant last had a
+	// delivery or finished a job, or its consumers were started
+	lastActive int64
+	// handling is the number of the tenant's jobs picked up by a worker
+	// and not settled yet
+	handling int64
+}
+
+// JobHandler processes a single job. Returning an error marks the job as
+// failed. ctx is cancelled when the pool stops, so long-running handlers
+// should return once it is done.
+type JobHandler func(ctx context.Context, job Job) error
+
+func NewTenantManager(db *sql.DB, rabbitmq *messaging.RabbitMQ, cfg *config.Config) *TenantManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	tm := &TenantManager{
 		db:             db,
 		rabbitmq:       rabbitmq,
+		cfg:            cfg,
 		consumers:      make(map[string]*messaging.Consumer),
 		workerPools:    make(map[string]*WorkerPool),
-		defaultWorkers: defaultWorkers,
+		lanes:          make(map[string]map[string]*lane),
+		failurePolicies: make(map[string]string),
+		sampleRates:     make(map[string]float64),
+		payloadSchemas:  make(map[string]*models.PayloadSchema),
+		queueOptions:   make(map[string]messaging.QueueOptions),
+		drains:         make(map[string]chan struct{}),
+		idle:           make(map[string]bool),
+		deliveries:     make(map[string]*deliveryCounts),
+		restarts:       make(map[string]*consumerRestarts),
+		owned:          make(map[string]bool),
+		creates:        newSemaphore(int64(max(cfg.Database.MaxConcurrentCreates, 0))),
+		limits:         NewConcurrencyLimits(),
+		redeliveries:   NewRedeliveryCounts(),
+		lifecycle:      logging.NewLifecycle(cfg.Logging.Lifecycle, log.Default()),
+		defaultWorkers: cfg.Workers,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	// New tenants start with the default worker count, so it is held to
+	// max_workers like any other
+	if err := tm.checkWorkers(tm.defaultWorkers); err != nil {
+		log.Printf("Warning: default worker count capped at max_workers: %v", err)
+		tm.defaultWorkers = cfg.MaxWorkers
+	}
+	// A misconfigured store is already reported by NewMessageService
+	tm.blobs, _ = payloadBlobStore(cfg)
+
+	if cfg.Cluster.Enabled {
+		tm.instanceID = cfg.Cluster.InstanceID
+		if tm.instanceID == "" {
+			hostname, _ := os.Hostname()
+			tm.instanceID = hostname + "-" + uuid.New().String()[:8]
+		}
+		tm.leaseTTL = cfg.Cluster.LeaseTTL
+		if tm.leaseTTL <= 0 {
+			tm.leaseTTL = 30 * time.Second
+		}
+	}
+
+	if cfg.Scheduler.Enabled {
+		tm.scheduler = NewScheduler(cfg.Scheduler.Slots)
+	}
+	if cfg.Throughput.MaxPerSecond > 0 {
+		tm.throughput = NewThroughputLimiter(cfg.Throughput.MaxPerSecond, cfg.Throughput.Burst)
+		metrics.SetThroughputUtilizationSource(tm.throughput.Utilization)
+	}
+
+
+	if cfg.SharedPool.Enabled {
+		tm.sharedPool = newWorkerPool(tm.ctx, int32(cfg.SharedPool.Workers), cfg.SharedPool.QueueSize, tm.scheduler, tm.limits, tm.throughput, tm.handleJob)
+	}
+
+	if cfg.Database.AsyncPartitions {
+		tm.partitionJobs = make(chan string, 1000)
+		tm.partitionDone = make(chan struct{})
+		go tm.partitionWorker()
+	}
+
+	if cfg.Maintenance.Interval > 0 {
+		tm.maintenanceDone = make(chan struct{})
+		go tm.maintenanceWorker(cfg.Maintenance.Interval)
+	}
+
+	if cfg.Reconcile.Interval > 0 {
+		tm.reconcileDone = make(chan struct{})
+		tm.reconcileExited = make(chan struct{})
+		go tm.reconcileWorker(cfg.Reconcile.Interval)
+	}
+
+	if cfg.WorkerSchedules.Interval > 0 {
+		tm.scheduleDone = make(chan struct{})
+		tm.scheduleExited = make(chan struct{})
+		go tm.scheduleWorker(cfg.WorkerSchedules.Interval)
+	}
+
+	if cfg.Deletion.PurgeInterval > 0 {
+		tm.purgeDone = make(chan struct{})
+		tm.purgeExited = make(chan struct{})
+		go tm.purgeWorker(cfg.Deletion.PurgeInterval)
+	}
+
+	if cfg.Retention.Interval > 0 {
+		tm.retentionDone = make(chan struct{})
+		tm.retentionExited = make(chan struct{})
+		go tm.retentionWorker(cfg.Retention.Interval)
+	}
+
+	if cfg.Consumer.IdleTimeout > 0 && cfg.Consumer.IdleCheckInterval > 0 {
+		tm.idleDone = make(chan struct{})
+		tm.idleExited = make(chan struct{})
+		go tm.idleWorker(cfg.Consumer.IdleCheckInterval)
+		rabbitmq.OnPublish(tm.wakeIdleTenant)
+	}
+
+	if alerts := cfg.DeadLetters.Alerts; alerts.Interval > 0 {
+		if tm.alerts = newDeadLetterAlerts(alerts); tm.alerts != nil {
+			tm.alertsDone = make(chan struct{})
+			tm.alertsExited = make(chan struct{})
+			go tm.alertWorker(alerts.Interval)
+		}
+	}
+
+	// Set before the consumers start, so each tenant subscribes its dead
+	// letter queue as it starts
+	if cfg.DeadLetters.Handler != "" {
+		if h, err := lookupDLQHandler(cfg.DeadLetters.Handler); err != nil {
+			log.Printf("Warning: dead letters are not consumed: %v", err)
+		} else if err := tm.SetDLQHandler(h); err != nil {
+			log.Printf("Warning: dead letters are not consumed: %v", err)
+		}
+	}
+
+	if cfg.Consumer.Batch.Handler != "" {
+		if h, err := lookupBatchHandler(cfg.Consumer.Batch.Handler); err != nil {
+			log.Printf("Warning: messages are processed one at a time: %v", err)
+		} else {
+			tm.batchHandler = h
+		}
 	}
 
 	// Load existing tenants and start their consumers
-	tm.loadExistingTenants()
+	tm.StartExistingTenants()
+
+	// Started after the consumers, so the startup gate sees their backlog
+	checks := DependencyChecks(db, rabbitmq)
+	if cfg.Health.StartupBacklog > 0 {
+		checks[models.CheckStartupBacklog] = tm.startupBacklogCheck(cfg.Health.StartupBacklog, cfg.Health.MaxWarmup)
+	}
+	tm.health = NewHealthChecker(cfg.Health.CacheTTL, checks)
+
+	if cfg.Cluster.Enabled {
+		tm.ownershipDone = make(chan struct{})
+		tm.ownershipExited = make(chan struct{})
+		go tm.ownershipWorker()
+	}
 
 	return tm
 }
 
 func (tm *TenantManager) CreateTenant(name string) (*models.Tenant, error) {
+	return tm.CreateTenantFromRequest(&models.CreateTenantRequest{Name: name})
+}
+
+// CreateTenantFromRequest creates a tenant with the settings in req that can
+// only be chosen at creation time.
+//
+// Creates beyond database.max_concurrent_creates wait for their turn, and
+// fail with ErrTooManyCreates once database.create_wait_timeout has passed.
+func (tm *TenantManager) CreateTenantFromRequest(req *models.CreateTenantRequest) (*models.Tenant, error) {
+	ctx, cancel := context.WithTimeout(tm.ctx, tm.cfg.Database.CreateWaitTimeout)
+	defer cancel()
+	if err := tm.creates.acquire(ctx, 1); err != nil {
+		return nil, ErrTooManyCreates
+	}
+	defer tm.creates.release(1)
+
 	tenantID := uuid.New().String()
+	name := req.Name
 
 	// Create tenant in database
-	query := `INSERT INTO tenants (id, name) VALUES ($1, $2) RETURNING created_at, updated_at`
+	query := `INSERT INTO tenants (id, name) VALUES ($1, $2) RETURNING status, created_at, updated_at`
 	var tenant models.Tenant
 	tenant.ID = tenantID
 	tenant.Name = name
 
-	err := tm.db.QueryRow(query, tenantID, name).Scan(&tenant.CreatedAt, &tenant.UpdatedAt)
+	err := database.Retry(tm.cfg.Retry.Backoff(), tm.cfg.Database.MaxRetries, func() error {
+		return tm.db.QueryRow(query, tenantID, name).Scan(&tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tenant: %w", err)
 	}
+	normalizeTenantTimes(&tenant)
 
-	// Create partition for tenant
-	if err := database.CreateTenantPartition(tm.db, tenantID); err != nil {
-		return nil, fmt.Errorf("failed to create tenant partition: %w", err)
+	// Create partition for tenant, either inline or in the background. Until a
+	// background job runs, the first insert creates the partition lazily.
+	if tm.partitionJobs != nil {
+		tm.enqueuePartition(tenantID)
+	} else {
+		err = database.Retry(tm.cfg.Retry.Backoff(), tm.cfg.Database.MaxRetries, func() error {
+			return database.CreateTenantPartition(tm.db, tenantID)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tenant partition: %w", err)
+		}
 	}
 
 	// Create tenant config
-	configQuery := `INSERT INTO tenant_configs (tenant_id, workers) VALUES ($1, $2)`
-	if _, err := tm.db.Exec(configQuery, tenantID, tm.defaultWorkers); err != nil {
+	overridden := []string{}
+	if req.SingleActiveConsumer {
+		overridden = append(overridden, "single_active_consumer")
+	}
+	if req.Transient {
+		overridden = append(overridden, "transient")
+	}
+	configQuery := `INSERT INTO tenant_configs (tenant_id, workers, single_active_consumer, transient, overridden) VALUES ($1, $2, $3, $4, $5)`
+	err = database.Retry(tm.cfg.Retry.Backoff(), tm.cfg.Database.MaxRetries, func() error {
+		_, err := tm.db.Exec(configQuery, tenantID, tm.defaultWorkers, req.SingleActiveConsumer, req.Transient, pq.Array(overridden))
+		return err
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create tenant config: %w", err)
 	}
 
 	// Start consumer for tenant
-	if err := tm.startTenantConsumer(tenantID); err != nil {
-		return nil, fmt.Errorf("failed to start tenant consumer: %w", err)
+	if err := tm.startClaimedTenant(tenantID); err != nil {
+		return nil, err
 	}
 
 	// Update metrics
@@ -83,50 +451,171 @@ func (tm *TenantManager) CreateTenant(name string) (*models.Tenant, error) {
 	return &tenant, nil
 }
 
+func (tm *TenantManager) enqueuePartition(tenantID string) {
+	select {
+	case tm.partitionJobs <- tenantID:
+	default:
+		log.Printf("Partition queue is full, partition for tenant %s will be created on first insert", tenantID)
+	}
+}
+
+// partitionWorker creates queued tenant partitions until Shutdown.
+func (tm *TenantManager) partitionWorker() {
+	for {
+		select {
+		case tenantID := <-tm.partitionJobs:
+			err := database.Retry(tm.cfg.Retry.Backoff(), tm.cfg.Database.MaxRetries, func() error {
+				return database.CreateTenantPartition(tm.db, tenantID)
+			})
+			if err != nil {
+				log.Printf("Failed to create partition for tenant %s in background: %v", tenantID, err)
+			}
+		case <-tm.partitionDone:
+			return
+		}
+	}
+}
+
+// maintenanceWorker runs partition maintenance every interval until
+// Shutdown.
+func (tm *TenantManager) maintenanceWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := tm.RunMaintenance(); err != nil {
+				log.Printf("Partition maintenance failed: %v", err)
+			}
+		case <-tm.maintenanceDone:
+			return
+		}
+	}
+}
+
+// RunMaintenance analyzes, and with maintenance.vacuum also vacuums, the
+// messages partitions that have at least maintenance.min_changes changes.
+func (tm *TenantManager) RunMaintenance() (*models.MaintenanceResult, error) {
+	tm.maintenanceMu.Lock()
+	defer tm.maintenanceMu.Unlock()
+
+	cfg := tm.cfg.Maintenance
+	start := time.Now()
+	partitions, err := database.MaintainPartitions(tm.db, cfg.Vacuum, cfg.MinChanges)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MaintenanceResult{
+		Partitions: partitions,
+		Vacuum:     cfg.Vacuum,
+		DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}, nil
+}
+
+// DeleteTenant deletes the tenant, its queues and its configuration. Its
+// partition is kept detached for deletion.partition_grace_period, in case
+// the delete was a mistake, before the purge drops it.
 func (tm *TenantManager) DeleteTenant(tenantID string) error {
+	return tm.deleteTenant(tenantID, tm.cfg.Deletion.PartitionGracePeriod <= 0)
+}
+
+// deleteTenant is DeleteTenant, dropping the partition straight away with
+// dropPartition.
+func (tm *TenantManager) deleteTenant(tenantID string, dropPartition bool) error {
+	unlock := tm.consumerLocks.lock(tenantID)
+	defer unlock()
+
+	// The consumer, pool and lanes are taken out under tm.mu but stopped
+	// after releasing it, as their deliveries take it to reach a pool
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	consumer := tm.consumers[tenantID]
+	delete(tm.consumers, tenantID)
+	// Tenants on the shared pool have no entry here
+	pool := tm.workerPools[tenantID]
+	delete(tm.workerPools, tenantID)
+	lanes := tm.lanes[tenantID]
+	delete(tm.lanes, tenantID)
+
+	delete(tm.failurePolicies, tenantID)
+	delete(tm.sampleRates, tenantID)
+	delete(tm.payloadSchemas, tenantID)
+	delete(tm.queueOptions, tenantID)
+	delete(tm.deliveries, tenantID)
+	delete(tm.restarts, tenantID)
+	delete(tm.idle, tenantID)
+	tm.redeliveries.ForgetTenant(tenantID)
+	delete(tm.owned, tenantID)
+	if tm.scheduler != nil {
+		tm.scheduler.Forget(tenantID)
+	}
+	tm.limits.Forget(tenantID)
+
+	if tm.deadLetters != nil {
+		tm.deadLetters.Unsubscribe(tenantID)
+	}
+	tm.mu.Unlock()
 
 	// Stop consumer
-	if consumer, exists := tm.consumers[tenantID]; exists {
+	if consumer != nil {
 		consumer.Stop()
-		delete(tm.consumers, tenantID)
 	}
 
 	// Stop worker pool
-	if pool, exists := tm.workerPools[tenantID]; exists {
+	if pool != nil {
 		pool.Stop()
-		delete(tm.workerPools, tenantID)
 	}
 
-	// Delete RabbitMQ queue
-	if err := tm.rabbitmq.DeleteTenantQueue(tenantID); err != nil {
+	// Stop lanes
+	laneNames := make([]string, 0, len(lanes))
+	for name, l := range lanes {
+		l.stop()
+		laneNames = append(laneNames, name)
+	}
+
+	// Delete RabbitMQ queues
+	if err := tm.rabbitmq.DeleteTenantQueue(tenantID, laneNames...); err != nil {
 		log.Printf("Warning: failed to delete RabbitMQ queue: %v", err)
 	}
 
-	// Delete from database (cascade will handle configs and messages)
-	query := `DELETE FROM tenants WHERE id = $1`
-	if _, err := tm.db.Exec(query, tenantID); err != nil {
+	// Delete from database (cascade will handle configs and messages). A
+	// soft-deleted tenant was already taken off the active count.
+	query := `DELETE FROM tenants WHERE id = $1 RETURNING deleted_at IS NULL`
+	active := false
+	if err := tm.db.QueryRow(query, tenantID).Scan(&active); err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("failed to delete tenant: %w", err)
 	}
 
-	// Drop partition
-	if err := database.DropTenantPartition(tm.db, tenantID); err != nil {
-		log.Printf("Warning: failed to drop partition: %v", err)
+	// Drop partition, or keep it detached until the purge drops it
+	if dropPartition {
+		blobs := tm.partitionBlobs(database.PartitionName(tenantID))
+		if err := database.DropTenantPartition(tm.db, tenantID); err != nil {
+			log.Printf("Warning: failed to drop partition: %v", err)
+		} else {
+			deleteBlobs(tm.blobs, blobs)
+		}
+	} else if retired, err := database.RetireTenantPartition(tm.db, tenantID); err != nil {
+		log.Printf("Warning: failed to retire partition: %v", err)
+	} else if retired != "" {
+		log.Printf("Kept the partition of deleted tenant %s as %s until deletion.partition_grace_period has passed", tenantID, retired)
 	}
 
 	// Update metrics
-	metrics.DecrementActiveTenants()
+	if active {
+		metrics.DecrementActiveTenants()
+	}
+	metrics.ForgetPayloadSizes(tenantID)
 
 	return nil
 }
 
 func (tm *TenantManager) GetTenant(tenantID string) (*models.Tenant, error) {
-	query := `SELECT id, name, created_at, updated_at FROM tenants WHERE id = $1`
+	query := `SELECT id, name, status, created_at, updated_at FROM tenants WHERE id = $1 AND deleted_at IS NULL`
 	var tenant models.Tenant
 
 	err := tm.db.QueryRow(query, tenantID).Scan(
-		&tenant.ID, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt,
+		&tenant.ID, &tenant.Name, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -134,12 +623,13 @@ func (tm *TenantManager) GetTenant(tenantID string) (*models.Tenant, error) {
 		}
 		return nil, fmt.Errorf("failed to get tenant: %w", err)
 	}
+	normalizeTenantTimes(&tenant)
 
 	return &tenant, nil
 }
 
 func (tm *TenantManager) ListTenants() ([]*models.Tenant, error) {
-	query := `SELECT id, name, created_at, updated_at FROM tenants ORDER BY created_at DESC`
+	query := `SELECT id, name, status, created_at, updated_at FROM tenants WHERE deleted_at IS NULL ORDER BY created_at DESC`
 	rows, err := tm.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tenants: %w", err)
@@ -149,31 +639,40 @@ func (tm *TenantManager) ListTenants() ([]*models.Tenant, error) {
 	var tenants []*models.Tenant
 	for rows.Next() {
 		var tenant models.Tenant
-		err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt)
+		err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan tenant: %w", err)
 		}
+		normalizeTenantTimes(&tenant)
 		tenants = append(tenants, &tenant)
 	}
 
 	return tenants, nil
 }
 
+// normalizeTenantTimes converts a tenant's timestamps to UTC so responses do
+// not depend on the database session's time zone.
+func normalizeTenantTimes(tenant *models.Tenant) {
+	tenant.CreatedAt = tenant.CreatedAt.UTC()
+	tenant.UpdatedAt = tenant.UpdatedAt.UTC()
+}
+
 func (tm *TenantManager) UpdateConcurrency(tenantID string, workers int) error {
+	if err := tm.checkWorkers(workers); err != nil {
+		return err
+	}
+
 	// Update database
-	query := `UPDATE tenant_configs SET workers = $1, updated_at = NOW() WHERE tenant_id = $2`
-	result, err := tm.db.Exec(query, workers, tenantID)
-	if err != nil {
-		return fmt.Errorf("failed to update concurrency: %w", err)
+	if err := tm.updateTenantConfig(tenantID, "workers", workers); err != nil {
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// A schedule window in force keeps its worker count
+	schedule, err := tm.loadWorkerSchedule(tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("tenant not found")
+		return err
 	}
+	workers = tm.workerTarget(workers, schedule)
 
 	// Update worker pool
 	tm.mu.Lock()
@@ -186,47 +685,627 @@ func (tm *TenantManager) UpdateConcurrency(tenantID string, workers int) error {
 	return nil
 }
 
-func (tm *TenantManager) startTenantConsumer(tenantID string) error {
-	consumer, err := tm.rabbitmq.CreateTenantQueue(tenantID)
+// checkWorkers returns ErrTooManyWorkers if workers exceeds max_workers.
+func (tm *TenantManager) checkWorkers(workers int) error {
+	if max := tm.cfg.MaxWorkers; max > 0 && workers > max {
+		return fmt.Errorf("%w: %d is more than %d", ErrTooManyWorkers, workers, max)
+	}
+	return nil
+}
+
+// UpdatePoolMode moves a tenant between the shared worker pool and a
+// dedicated pool of its own. The tenant's consumer keeps running, and jobs
+// still queued in a dedicated pool that is given up move to the shared pool.
+func (tm *TenantManager) UpdatePoolMode(tenantID string, dedicated bool) error {
+	if err := tm.updateTenantConfig(tenantID, "dedicated_pool", dedicated); err != nil {
+		return err
+	}
+
+	// Another instance consumes the tenant and applies the change itself
+	if tm.cfg.Cluster.Enabled && !tm.ownsTenant(tenantID) {
+		return nil
+	}
+
+	var workers int
+	var schedule []byte
+	err := tm.db.QueryRow(`SELECT workers, worker_schedule FROM tenant_configs WHERE tenant_id = $1`, tenantID).Scan(&workers, &schedule)
+	if err != nil {
+		workers = tm.defaultWorkers
+	}
+	workers = tm.workerTarget(workers, decodeWorkerSchedule(schedule))
+
+	tm.applyPoolMode(tenantID, dedicated, workers)
+	return nil
+}
+
+// applyPoolMode moves the tenant's consumer to a dedicated pool of workers
+// or to the shared pool, if it is not in that mode already.
+func (tm *TenantManager) applyPoolMode(tenantID string, dedicated bool, workers int) {
+	tm.mu.Lock()
+	current, hasDedicated := tm.workerPools[tenantID]
+	var retired *WorkerPool
+	if dedicated && !hasDedicated {
+		tm.workerPools[tenantID] = tm.newTenantPool(workers)
+	} else if !dedicated && hasDedicated && tm.sharedPool != nil {
+		delete(tm.workerPools, tenantID)
+		retired = current
+	}
+	tm.mu.Unlock()
+
+	// New deliveries already go to the shared pool; hand it the backlog too
+	if retired != nil {
+		forwardJobs(retired.Drain(), tm.sharedPool)
+	}
+}
+
+// newTenantPool creates a worker pool serving a single tenant, or one of its
+// lanes.
+func (tm *TenantManager) newTenantPool(workers int) *WorkerPool {
+	if tm.batchHandler != nil {
+		return tm.newBatchPool(workers)
+	}
+	return newWorkerPool(tm.ctx, int32(workers), 100, tm.scheduler, tm.limits, tm.throughput, tm.handleJob)
+}
+
+// forwardJobs submits jobs drained from one pool to another. Jobs the target
+// has no room for are requeued with the broker.
+func forwardJobs(jobs []Job, pool *WorkerPool) {
+	for _, job := range jobs {
+		if err := pool.Submit(job); err != nil {
+			job.settle(&jobFailure{err: err, settlement: SettleRequeue})
+		}
+	}
+}
+
+// UpdateWeight sets the tenant's share of processing relative to other
+// tenants. It only has an effect with scheduler.enabled.
+func (tm *TenantManager) UpdateWeight(tenantID string, weight int) error {
+	if err := tm.updateTenantConfig(tenantID, "weight", weight); err != nil {
+		return err
+	}
+
+	if tm.scheduler != nil {
+		tm.scheduler.SetWeight(tenantID, weight)
+	}
+
+	return nil
+}
+
+// UpdateMaxConcurrency caps how many of the tenant's messages are processed
+// at once, across its main pool and lanes, regardless of worker count. 0
+// removes the cap.
+func (tm *TenantManager) UpdateMaxConcurrency(tenantID string, limit int) error {
+	if err := tm.updateTenantConfig(tenantID, "max_concurrency", limit); err != nil {
+		return err
+	}
+
+	tm.limits.SetLimit(tenantID, limit)
+	return nil
+}
+
+// UpdateTransforms sets the transforms applied, in order, to the tenant's
+// payloads before they are stored. With keepRaw the payload as received is
+// stored as well.
+func (tm *TenantManager) UpdateTransforms(tenantID string, transforms []string, keepRaw bool) error {
+	if err := checkTransforms(transforms); err != nil {
+		return err
+	}
+	if transforms == nil {
+		transforms = []string{}
+	}
+	transformsBytes, err := json.Marshal(transforms)
 	if err != nil {
+		return fmt.Errorf("failed to marshal transforms: %w", err)
+	}
+
+	return tm.updateTenantConfigColumns(tenantID, []string{"transforms", "keep_raw_payload"}, string(transformsBytes), keepRaw)
+}
+
+// UpdateEmptyPayload sets whether the tenant accepts messages without a
+// payload, which are then stored with {}. It takes effect for the next
+// message.
+func (tm *TenantManager) UpdateEmptyPayload(tenantID string, allow bool) error {
+	return tm.updateTenantConfig(tenantID, "allow_empty_payload", allow)
+}
+
+// UpdateFailurePolicy changes how the tenant's workers settle messages whose
+// processing fails. It takes effect for the next failure.
+func (tm *TenantManager) UpdateFailurePolicy(tenantID, policy string) error {
+	if err := tm.updateTenantConfig(tenantID, "failure_policy", policy); err != nil {
 		return err
 	}
 
-	// Get worker count for tenant
+	tm.mu.Lock()
+	tm.failurePolicies[tenantID] = policy
+	tm.mu.Unlock()
+
+	return nil
+}
+
+// updateTenantConfig sets a single tenant_configs column and records the
+// resulting configuration in tenant_config_history, in one transaction.
+// column must be a trusted identifier, never user input.
+func (tm *TenantManager) updateTenantConfig(tenantID, column string, value interface{}) error {
+	return tm.updateTenantConfigColumns(tenantID, []string{column}, value)
+}
+
+// updateTenantConfigColumns is updateTenantConfig for several columns at
+// once, recorded as a single change. values are given in column order.
+func (tm *TenantManager) updateTenantConfigColumns(tenantID string, columns []string, values ...interface{}) error {
+	tx, err := tm.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	set := make([]string, len(columns))
+	for i, column := range columns {
+		set[i] = fmt.Sprintf("%s = $%d", column, i+1)
+	}
+	// The columns are recorded as overridden, for EffectiveConfig
+	query := fmt.Sprintf(`
+		UPDATE tenant_configs
+		SET %s, overridden = ARRAY(SELECT DISTINCT unnest(overridden || $%d::text[]) ORDER BY 1), updated_at = NOW()
+		WHERE tenant_id = $%d`,
+		strings.Join(set, ", "), len(columns)+1, len(columns)+2)
+	result, err := tx.Exec(query, append(values, pq.Array(columns), tenantID)...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", strings.Join(columns, ", "), err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tenant not found")
+	}
+
+	historyQuery := `
+		INSERT INTO tenant_config_history (tenant_id, config)
+		SELECT tenant_id, to_jsonb(tc) - 'tenant_id' - 'updated_at' - 'overridden'
+		FROM tenant_configs tc
+		WHERE tenant_id = $1
+	`
+	if _, err := tx.Exec(historyQuery, tenantID); err != nil {
+		return fmt.Errorf("failed to record config history: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetConfigHistory lists a tenant's past configurations, newest first.
+func (tm *TenantManager) GetConfigHistory(tenantID string) ([]*models.TenantConfigHistory, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, tenant_id, config, changed_at
+		FROM tenant_config_history
+		WHERE tenant_id = $1
+		ORDER BY changed_at DESC, id DESC
+	`
+	rows, err := tm.db.Query(query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*models.TenantConfigHistory{}
+	for rows.Next() {
+		var entry models.TenantConfigHistory
+		var configBytes []byte
+		if err := rows.Scan(&entry.ID, &entry.TenantID, &configBytes, &entry.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan config history: %w", err)
+		}
+		entry.ChangedAt = entry.ChangedAt.UTC()
+		if err := json.Unmarshal(configBytes, &entry.Config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal config history: %w", err)
+		}
+		history = append(history, &entry)
+	}
+
+	return history, nil
+}
+
+// UsesSharedPool reports whether the tenant's messages are currently handled
+// by the shared worker pool.
+func (tm *TenantManager) UsesSharedPool(tenantID string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	_, hasConsumer := tm.consumers[tenantID]
+	_, dedicated := tm.workerPools[tenantID]
+	return hasConsumer && !dedicated
+}
+
+// GetConsumerStatus reports the consumers and worker pools serving a tenant,
+// including its lanes.
+func (tm *TenantManager) GetConsumerStatus(tenantID string) (*models.ConsumerStatus, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tm.db.Query(`SELECT name, workers FROM tenant_lanes WHERE tenant_id = $1 ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lanes: %w", err)
+	}
+	defer rows.Close()
+
+	status := &models.ConsumerStatus{TenantID: tenantID, Lanes: []models.LaneStatus{}}
+	for rows.Next() {
+		var ls models.LaneStatus
+		if err := rows.Scan(&ls.Name, &ls.Workers); err != nil {
+			return nil, fmt.Errorf("failed to scan lane: %w", err)
+		}
+		status.Lanes = append(status.Lanes, ls)
+	}
+
+	if tm.cfg.Cluster.Enabled {
+		if status.Owner, err = tm.tenantOwner(tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	_, status.Running = tm.consumers[tenantID]
+	status.Idle = tm.idle[tenantID]
+	status.SingleActiveConsumer = tm.queueOptions[tenantID].SingleActiveConsumer
+	status.Transient = tm.queueOptions[tenantID].Transient
+	if counts := tm.deliveries[tenantID]; counts != nil {
+		status.Delivered = atomic.LoadInt64(&counts.delivered)
+		status.Redelivered = atomic.LoadInt64(&counts.redelivered)
+		if status.Delivered > 0 {
+			status.RedeliveryRate = float64(status.Redelivered) / float64(status.Delivered)
+		}
+	}
+	if restarts := tm.restarts[tenantID]; restarts != nil {
+		status.Restarts = restarts.count
+		lastRestart := restarts.last
+		status.LastRestart = &lastRestart
+	}
+	if tm.scheduler != nil {
+		status.Weight = tm.scheduler.Weight(tenantID)
+	}
+	status.MaxConcurrency = tm.limits.Limit(tenantID)
+	status.SampleRate = 1
+	if rate, ok := tm.sampleRates[tenantID]; ok {
+		status.SampleRate = rate
+	}
+	pool, dedicated := tm.workerPools[tenantID]
+	status.Pool = "dedicated"
+	if !dedicated {
+		pool = tm.sharedPool
+		status.Pool = "shared"
+	}
+	if pool != nil {
+		status.Workers = int(pool.Workers())
+		status.QueuedJobs = pool.Queued()
+		status.Processed = pool.Processed()
+	}
+
+	for i := range status.Lanes {
+		ls := &status.Lanes[i]
+		l, exists := tm.lanes[tenantID][ls.Name]
+		if !exists {
+			continue
+		}
+		ls.Running = l.consumer != nil
+		ls.Workers = int(l.pool.Workers())
+		ls.QueuedJobs = l.pool.Queued()
+		ls.Processed = l.pool.Processed()
+	}
+
+	return status, nil
+}
+
+// startTenantConsumer starts the tenant's consumers and worker pools, lanes
+// included, from its stored config. Any already running for the tenant, e.g.
+// when a reconnect or a regained lease re-runs it, are stopped first, so the
+// tenant never ends up with two consumers on a queue or with leaked pools.
+// The stop and the start hold the tenant's consumer lock throughout, so
+// concurrent starts take turns rather than both replacing the same consumers.
+func (tm *TenantManager) startTenantConsumer(tenantID string) error {
+	unlock := tm.consumerLocks.lock(tenantID)
+	defer unlock()
+	return tm.startTenantConsumerLocked(tenantID)
+}
+
+// ensureTenantConsumer starts the tenant's consumers unless they are running
+// already, and reports whether it started them. It is for callers racing to
+// bring a new tenant up, such as CreateTenant and StartExistingTenants, where
+// the loser must not restart what the winner started.
+func (tm *TenantManager) ensureTenantConsumer(tenantID string) (bool, error) {
+	unlock := tm.consumerLocks.lock(tenantID)
+	defer unlock()
+
+	tm.mu.RLock()
+	_, running := tm.consumers[tenantID]
+	tm.mu.RUnlock()
+	if running {
+		return false, nil
+	}
+	return true, tm.startTenantConsumerLocked(tenantID)
+}
+
+// startTenantConsumerLocked is startTenantConsumer for a caller holding the
+// tenant's consumer lock.
+func (tm *TenantManager) startTenantConsumerLocked(tenantID string) error {
+	tm.stopTenantConsumerLocked(tenantID)
+
+	// Get worker count, pool mode, failure policy and queue options for tenant
 	var workers int
-	query := `SELECT workers FROM tenant_configs WHERE tenant_id = $1`
-	err = tm.db.QueryRow(query, tenantID).Scan(&workers)
+	var dedicated bool
+	var policy string
+	var opts messaging.QueueOptions
+	var maxConcurrency int
+	var schedule, payloadSchema []byte
+	weight := 1
+	sampleRate := 1.0
+	query := `SELECT workers, dedicated_pool, failure_policy, single_active_consumer, transient, weight, max_concurrency, sample_rate, worker_schedule, payload_schema FROM tenant_configs WHERE tenant_id = $1`
+	err := tm.db.QueryRow(query, tenantID).Scan(&workers, &dedicated, &policy, &opts.SingleActiveConsumer, &opts.Transient, &weight, &maxConcurrency, &sampleRate, &schedule, &payloadSchema)
 	if err != nil {
 		workers = tm.defaultWorkers
+		policy = models.FailurePolicyRetryThenDLQ
+		sampleRate = 1
+	}
+	workers = tm.workerTarget(workers, decodeWorkerSchedule(schedule))
+	if tm.scheduler != nil {
+		tm.scheduler.SetWeight(tenantID, weight)
+	}
+	tm.limits.SetLimit(tenantID, maxConcurrency)
+
+	consumer, err := tm.rabbitmq.CreateQueue(tenantID, "", opts)
+	if err != nil {
+		return err
 	}
 
-	// Create worker pool
-	pool := NewWorkerPool(int32(workers))
-	
 	tm.mu.Lock()
+	tm.failurePolicies[tenantID] = policy
+	tm.sampleRates[tenantID] = sampleRate
+	tm.setPayloadSchema(tenantID, decodePayloadSchema(payloadSchema))
+	tm.queueOptions[tenantID] = opts
+	if _, exists := tm.deliveries[tenantID]; !exists {
+		tm.deliveries[tenantID] = &deliveryCounts{}
+	}
+	atomic.StoreInt64(&tm.deliveries[tenantID].lastActive, time.Now().UnixNano())
+	delete(tm.idle, tenantID)
+	if _, exists := tm.workerPools[tenantID]; !exists && (tm.sharedPool == nil || dedicated) {
+		// Create worker pool
+		tm.workerPools[tenantID] = tm.newTenantPool(workers)
+	}
+	tm.mu.Unlock()
+
+	tm.consume(tenantID, consumer)
+	tm.subscribeDeadLetters(tenantID)
+	return tm.startLanes(tenantID)
+}
+
+// consume registers and starts a tenant's consumer. Deliveries go to
+// whichever pool serves the tenant at the time they arrive.
+func (tm *TenantManager) consume(tenantID string, consumer *messaging.Consumer) {
+	tm.mu.Lock()
+	previous := tm.consumers[tenantID]
 	tm.consumers[tenantID] = consumer
-	tm.workerPools[tenantID] = pool
 	tm.mu.Unlock()
 
+	// Never leave two consumers running for the same tenant, e.g. after
+	// concurrent restarts
+	if previous != nil && previous != consumer {
+		previous.Stop()
+	}
+
+	consumer.BatchAcks(tm.cfg.Consumer.AckBatchSize, tm.cfg.Consumer.AckBatchInterval)
 	// Start consumer with message handler
-	consumer.Start(func(body []byte) error {
-		return tm.processMessage(tenantID, body, pool)
+	consumer.Start(tm.cfg.Consumer.VisibilityTimeout, func(lease *messaging.Lease) error {
+		tm.recordDelivery(tenantID, "", lease)
+		if tm.poisoned(tenantID, lease) || tm.invalid(tenantID, lease) || tm.sampleOut(tenantID, lease) {
+			return nil
+		}
+		return tm.processMessage(tenantID, lease)
 	})
+}
 
-	return nil
+// recordDelivery counts a delivery from one of the tenant's consumers, or
+// from one of its lanes. A high share of redeliveries points at messages that
+// keep failing or outliving their deadline.
+func (tm *TenantManager) recordDelivery(tenantID, lane string, lease *messaging.Lease) {
+	delivery := lease.Delivery()
+	redelivered := delivery.Redelivered
+	metrics.RecordDelivery(tenantID, redelivered)
+	tm.lifecycle.Event(logging.EventDelivered, tenantID, delivery.MessageId, messaging.CorrelationID(delivery),
+		"lane", lane, "redelivered", strconv.FormatBool(redelivered))
+
+	tm.mu.RLock()
+	counts := tm.deliveries[tenantID]
+	tm.mu.RUnlock()
+	if counts == nil {
+		return
+	}
+	atomic.AddInt64(&counts.delivered, 1)
+	atomic.StoreInt64(&counts.lastActive, time.Now().UnixNano())
+	if redelivered {
+		atomic.AddInt64(&counts.redelivered, 1)
+	}
 }
 
-func (tm *TenantManager) processMessage(tenantID string, body []byte, pool *WorkerPool) error {
-	// Send message to worker pool for processing
-	select {
-	case pool.jobQueue <- body:
+// touch marks the job's tenant active now, keeping consumer.idle_timeout
+// from stopping its consumers. Workers call it, so it goes through the
+// counts captured when the job was submitted rather than tm.mu.
+func (j Job) touch() {
+	if j.activity != nil {
+		atomic.StoreInt64(&j.activity.lastActive, time.Now().UnixNano())
+	}
+}
+
+// track adds delta to the jobs of the job's tenant being handled.
+func (j Job) track(delta int64) {
+	if j.activity != nil {
+		atomic.AddInt64(&j.activity.handling, delta)
+	}
+}
+
+// handlingJobs returns the number of the tenant's jobs picked up by a worker
+// and not settled yet, whichever pool they are in.
+func (tm *TenantManager) handlingJobs(tenantID string) int64 {
+	tm.mu.RLock()
+	counts := tm.deliveries[tenantID]
+	tm.mu.RUnlock()
+	if counts == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&counts.handling)
+}
+
+// ReconnectRabbitMQ re-dials the broker and recreates every tenant's
+// consumer on the new connection. Worker pools are kept, but the jobs queued
+// in them are discarded: their deliveries belong to the old connection and
+// can no longer be acked, and the broker redelivers them to the new
+// consumers. Tenants whose consumer could not be recreated are listed in the
+// result.
+func (tm *TenantManager) ReconnectRabbitMQ() (*models.ReconnectResult, error) {
+	if err := tm.rabbitmq.Reconnect(); err != nil {
+		return nil, err
+	}
+
+	tm.mu.Lock()
+	tenantIDs := make([]string, 0, len(tm.consumers))
+	for tenantID, consumer := range tm.consumers {
+		consumer.Stop()
+		delete(tm.consumers, tenantID)
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	pools := make([]*WorkerPool, 0, len(tm.workerPools)+1)
+	for _, pool := range tm.workerPools {
+		pools = append(pools, pool)
+	}
+	if tm.sharedPool != nil {
+		pools = append(pools, tm.sharedPool)
+	}
+	type laneRef struct {
+		tenantID, name string
+		lane           *lane
+	}
+	var lanes []laneRef
+	for tenantID, tenantLanes := range tm.lanes {
+		for name, l := range tenantLanes {
+			if l.consumer != nil {
+				l.consumer.Stop()
+				l.consumer = nil
+			}
+			pools = append(pools, l.pool)
+			lanes = append(lanes, laneRef{tenantID: tenantID, name: name, lane: l})
+		}
+	}
+	tm.mu.Unlock()
+
+	result := &models.ReconnectResult{Failures: map[string]string{}}
+	for _, pool := range pools {
+		for _, job := range pool.jobQueue.drain() {
+			if job.lease != nil {
+				job.lease.Abandon()
+			}
+			result.DiscardedJobs++
+		}
+	}
+
+	for _, tenantID := range tenantIDs {
+		consumer, err := tm.rabbitmq.CreateQueue(tenantID, "", tm.tenantQueueOptions(tenantID))
+		if err != nil {
+			logging.Printf("Failed to recreate consumer for tenant %s: %v", tenantID, err)
+			result.Failures[tenantID] = err.Error()
+			continue
+		}
+		tm.consume(tenantID, consumer)
+		tm.recordConsumerRestart(tenantID)
+		result.ConsumersRestarted++
+	}
+
+	for _, ref := range lanes {
+		consumer, err := tm.rabbitmq.CreateQueue(ref.tenantID, ref.name, tm.tenantQueueOptions(ref.tenantID))
+		if err != nil {
+			logging.Printf("Failed to recreate consumer for lane %s of tenant %s: %v", ref.name, ref.tenantID, err)
+			result.Failures[ref.tenantID+"/"+ref.name] = err.Error()
+			continue
+		}
+		tm.consumeLane(ref.tenantID, ref.name, ref.lane, consumer)
+		result.ConsumersRestarted++
+	}
+
+	// The dead letter consumer's channel went with the old connection
+	tm.mu.Lock()
+	deadLetters := tm.deadLetters
+	tm.deadLetters = nil
+	tm.mu.Unlock()
+	if deadLetters != nil {
+		deadLetters.Close()
+		if err := tm.consumeDeadLetters(); err != nil {
+			logging.Printf("Failed to recreate dead letter consumer: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (tm *TenantManager) processMessage(tenantID string, lease *messaging.Lease) error {
+	// Holding the read lock keeps the pool from being retired between the
+	// lookup and the submit
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	pool, exists := tm.workerPools[tenantID]
+	if !exists {
+		pool = tm.sharedPool
+	}
+	if pool == nil {
+		// The tenant is being stopped and its consumer is about to be
+		// cancelled; hand the delivery back rather than dead-letter it
+		lease.Abandon()
 		return nil
-	default:
-		return fmt.Errorf("worker pool queue is full")
 	}
+
+	// Send message to worker pool for processing. The worker settles the
+	// lease when it is done with the job.
+	return pool.Submit(tm.tenantJob(tenantID, lease))
+}
+
+// tenantJob is NewJob with the tenant state its worker needs captured, so
+// workers never read it under tm.mu. The caller must hold tm.mu.
+func (tm *TenantManager) tenantJob(tenantID string, lease *messaging.Lease) Job {
+	job := NewJob(tenantID, lease)
+	job.activity = tm.deliveries[tenantID]
+	job.failurePolicy = tm.failurePolicy(tenantID)
+	return job
 }
 
-func (tm *TenantManager) loadExistingTenants() {
+// NewJob builds the job for a leased delivery. The pool the job is submitted
+// to settles the lease once the job has been handled.
+func NewJob(tenantID string, lease *messaging.Lease) Job {
+	delivery := lease.Delivery()
+	return Job{
+		TenantID:      tenantID,
+		MessageID:     delivery.MessageId,
+		CorrelationID: messaging.CorrelationID(delivery),
+		Priority:      delivery.Priority,
+		Body:          delivery.Body,
+		lease:         lease,
+	}
+}
+
+// StartExistingTenants starts consuming every stored tenant that is not
+// being consumed yet, and finishes drains interrupted by a restart.
+// NewTenantManager runs it; tenants created meanwhile, or started by a
+// concurrent run, are left as they are rather than started twice.
+func (tm *TenantManager) StartExistingTenants() {
+	// In a cluster, only the tenants this instance can claim
+	if tm.cfg.Cluster.Enabled {
+		tm.rebalance()
+		return
+	}
+
 	tenants, err := tm.ListTenants()
 	if err != nil {
 		log.Printf("Failed to load existing tenants: %v", err)
@@ -234,35 +1313,152 @@ func (tm *TenantManager) loadExistingTenants() {
 	}
 
 	for _, tenant := range tenants {
-		if err := tm.startTenantConsumer(tenant.ID); err != nil {
+		started, err := tm.ensureTenantConsumer(tenant.ID)
+		if err != nil {
 			log.Printf("Failed to start consumer for tenant %s: %v", tenant.ID, err)
+			continue
+		}
+		// Finish drains interrupted by a restart
+		if started && tenant.Status == models.TenantStatusDraining {
+			tm.startDrain(tenant.ID, DefaultDrainTimeout)
 		}
 	}
 }
 
 func (tm *TenantManager) Shutdown() {
+	if tm.ownershipDone != nil {
+		close(tm.ownershipDone)
+		<-tm.ownershipExited
+	}
+	// Nor must a run stopping or restarting idle tenants
+	if tm.idleDone != nil {
+		close(tm.idleDone)
+		<-tm.idleExited
+	}
+	// A run resizing pools must not overlap with them being stopped
+	if tm.reconcileDone != nil {
+		close(tm.reconcileDone)
+		<-tm.reconcileExited
+	}
+	if tm.scheduleDone != nil {
+		close(tm.scheduleDone)
+		<-tm.scheduleExited
+	}
+	// A purge deleting tenants must not overlap with their consumers being
+	// stopped
+	if tm.purgeDone != nil {
+		close(tm.purgeDone)
+		<-tm.purgeExited
+	}
+	if tm.retentionDone != nil {
+		close(tm.retentionDone)
+		<-tm.retentionExited
+	}
+
+	// Collect the consumers and pools under tm.mu, but stop them after
+	// releasing it, as their deliveries take it to reach a pool
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	var consumers []*messaging.Consumer
+	var pools []*WorkerPool
+	for _, consumer := range tm.consumers {
+		consumers = append(consumers, consumer)
+	}
+	for _, pool := range tm.workerPools {
+		pools = append(pools, pool)
+	}
+	for _, tenantLanes := range tm.lanes {
+		for _, l := range tenantLanes {
+			if l.consumer != nil {
+				consumers = append(consumers, l.consumer)
+			}
+			pools = append(pools, l.pool)
+		}
+	}
+	if tm.sharedPool != nil {
+		pools = append(pools, tm.sharedPool)
+	}
+	tm.mu.Unlock()
 
 	// Stop all consumers
-	for _, consumer := range tm.consumers {
+	for _, consumer := range consumers {
 		consumer.Stop()
 	}
 
-	// Stop all worker pools
-	for _, pool := range tm.workerPools {
+	if tm.deadLetters != nil {
+		tm.deadLetters.Close()
+	}
+
+	// Abort in-flight handlers, then stop all worker pools
+	tm.cancel()
+	for _, pool := range pools {
 		pool.Stop()
 	}
 
+	if tm.partitionDone != nil {
+		close(tm.partitionDone)
+	}
+	if tm.maintenanceDone != nil {
+		close(tm.maintenanceDone)
+	}
+	tm.health.Stop()
+
+	// Let other instances take over now rather than once the leases expire
+	if tm.cfg.Cluster.Enabled {
+		tm.releaseLeases()
+	}
+
+	// Flushed last, to alert on whatever the stopping pools dead-lettered
+	if tm.alertsDone != nil {
+		close(tm.alertsDone)
+		<-tm.alertsExited
+	}
+
 	log.Println("All tenant consumers and worker pools stopped")
 }
 
 // WorkerPool implementation
-func NewWorkerPool(workers int32) *WorkerPool {
+//
+// NewWorkerPool starts a pool whose handlers receive a context derived from
+// ctx. The context is cancelled when ctx is or when the pool is stopped.
+func NewWorkerPool(ctx context.Context, workers int32, handler JobHandler) *WorkerPool {
+	return newWorkerPool(ctx, workers, 100, nil, nil, nil, handler)
+}
+
+// NewLimitedWorkerPool starts a pool whose handlers only run while the job's
+// tenant is within its limit in limits, however many workers are free.
+func NewLimitedWorkerPool(ctx context.Context, workers int32, limits *ConcurrencyLimits, handler JobHandler) *WorkerPool {
+	return newWorkerPool(ctx, workers, 100, nil, limits, nil, handler)
+}
+
+// NewScheduledWorkerPool starts a pool whose jobs each wait for a slot from
+// scheduler before running. Its queue interleaves tenants by their scheduler
+// weight, so one tenant's backlog cannot hold up the others.
+func NewScheduledWorkerPool(ctx context.Context, workers int32, queueSize int, scheduler *Scheduler, handler JobHandler) *WorkerPool {
+	return newWorkerPool(ctx, workers, queueSize, scheduler, nil, nil, handler)
+}
+
+// NewThrottledWorkerPool starts a pool whose jobs each wait for throughput
+// to let them through before running.
+func NewThrottledWorkerPool(ctx context.Context, workers int32, throughput *ThroughputLimiter, handler JobHandler) *WorkerPool {
+	return newWorkerPool(ctx, workers, 100, nil, nil, throughput, handler)
+}
+
+func newWorkerPool(ctx context.Context, workers int32, queueSize int, scheduler *Scheduler, limits *ConcurrencyLimits, throughput *ThroughputLimiter, handler JobHandler) *WorkerPool {
+	ctx, cancel := context.WithCancel(ctx)
 	pool := &WorkerPool{
-		workers:  workers,
-		jobQueue: make(chan []byte, 100), // Buffered channel
-		quit:     make(chan bool),
+		workers:    workers,
+		jobQueue:   newJobQueue(queueSize), // Bounded priority queue
+		handler:    handler,
+		ctx:        ctx,
+		cancel:     cancel,
+		scheduler:  scheduler,
+		limits:     limits,
+		throughput: throughput,
+		quit:       make(chan bool),
+		shrink:     make(chan struct{}, 1),
+	}
+	if scheduler != nil {
+		pool.jobQueue = newFairJobQueue(queueSize, scheduler.Weight)
 	}
 
 	pool.start()
@@ -278,49 +1474,370 @@ func (wp *WorkerPool) start() {
 
 func (wp *WorkerPool) worker() {
 	defer wp.wg.Done()
+	atomic.AddInt32(&wp.running, 1)
+	defer atomic.AddInt32(&wp.running, -1)
+	if wp.batch != nil {
+		wp.batchWorker()
+		return
+	}
 	
 	for {
 		select {
-		case job := <-wp.jobQueue:
-			wp.processJob(job)
+		case <-wp.jobQueue.ready:
+			if job, ok := wp.jobQueue.pop(); ok {
+				wp.processJob(job)
+			}
+		case <-wp.shrink:
 		case <-wp.quit:
 			return
 		}
+		if wp.retire() {
+			return
+		}
+	}
+}
+
+// retire reports whether the calling worker should exit to bring the pool
+// down to its worker count, and if so counts it as gone.
+func (wp *WorkerPool) retire() bool {
+	if !takeOne(&wp.retiring) {
+		return false
+	}
+	// Pass the wake-up on to another idle worker if more must go
+	if atomic.LoadInt32(&wp.retiring) > 0 {
+		wp.signalShrink()
+	}
+	return true
+}
+
+// signalShrink wakes one idle worker, unless one is already being woken.
+func (wp *WorkerPool) signalShrink() {
+	select {
+	case wp.shrink <- struct{}{}:
+	default:
+	}
+}
+
+// takeOne decrements n if it is positive, and reports whether it did.
+func takeOne(n *int32) bool {
+	for {
+		current := atomic.LoadInt32(n)
+		if current <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(n, current, current-1) {
+			return true
+		}
+	}
+}
+
+// Submit queues a job without blocking. Higher priority jobs are handed to
+// workers first. ErrQueueFull is returned when the queue is at capacity.
+func (wp *WorkerPool) Submit(job Job) error {
+	return wp.jobQueue.push(job)
+}
+
+func (wp *WorkerPool) processJob(job Job) {
+	atomic.AddInt32(&wp.busy, 1)
+	defer atomic.AddInt32(&wp.busy, -1)
+	job.track(1)
+	defer job.track(-1)
+
+	// Wait for the tenant's own limit before taking a scheduler slot, so a
+	// throttled tenant does not hold slots other tenants could use
+	if wp.limits != nil {
+		release, err := wp.limits.Acquire(wp.ctx, job.TenantID)
+		if err != nil {
+			// Stopped while waiting for the tenant to get under its limit
+			job.settle(&jobFailure{err: err, settlement: SettleRequeue})
+			return
+		}
+		defer release()
+	}
+	// Then for the global budget, also before taking a scheduler slot
+	if wp.throughput != nil {
+		if err := wp.throughput.Wait(wp.ctx); err != nil {
+			// Stopped while waiting for the budget
+			job.settle(&jobFailure{err: err, settlement: SettleRequeue})
+			return
+		}
+	}
+	if wp.scheduler != nil {
+		if err := wp.scheduler.Acquire(wp.ctx, job.TenantID); err != nil {
+			// Stopped while waiting for a slot
+			job.settle(&jobFailure{err: err, settlement: SettleRequeue})
+			return
+		}
+		defer wp.scheduler.Release()
+	}
+
+	err := wp.handler(wp.ctx, job)
+	atomic.AddInt64(&wp.processed, 1)
+	wp.finish(job, err)
+}
+
+// finish settles a handled job according to the handler's err and counts the
+// outcome.
+func (wp *WorkerPool) finish(job Job, err error) {
+	if err != nil && wp.ctx.Err() != nil {
+		// The pool is stopping and the handler gave up; hand the message back
+		// to the broker rather than counting it as a failure.
+		if job.settle(&jobFailure{err: err, settlement: SettleRequeue}) {
+			log.Printf("Message %s for tenant %s interrupted by shutdown, requeued", job.MessageID, job.TenantID)
+		}
+		return
+	}
+	if !job.settle(err) {
+		// The deadline passed first and the message was requeued, so this
+		// result is discarded in favour of the redelivery.
+		log.Printf("Message %s for tenant %s finished after its visibility deadline, result discarded",
+			job.MessageID, job.TenantID)
+		metrics.IncrementMessagesProcessed(job.TenantID, "expired")
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to process message %s (correlation_id=%s) for tenant %s: %v",
+			job.MessageID, job.CorrelationID, job.TenantID, err)
+		metrics.IncrementMessagesProcessed(job.TenantID, "failed")
+		return
 	}
+
+	metrics.IncrementMessagesProcessed(job.TenantID, "success")
 }
 
-func (wp *WorkerPool) processJob(body []byte) {
+// settle acks the job's delivery, or sends it to the DLQ if err is set.
+// Failures carrying a Settlement are settled accordingly. It returns false if
+// the delivery had already been requeued by its deadline.
+func (j Job) settle(err error) bool {
+	if j.lease == nil {
+		return true
+	}
+	if err == nil {
+		return j.lease.Ack()
+	}
+
+	var failure *jobFailure
+	if !errors.As(err, &failure) {
+		return j.lease.DeadLetter(err.Error())
+	}
+	switch failure.settlement {
+	case SettleAck:
+		return j.lease.Ack()
+	case SettleRequeue:
+		return j.lease.Nack(true)
+	default:
+		return j.lease.DeadLetter(err.Error())
+	}
+}
+
+// handleJob is the JobHandler used for tenant worker pools. Each attempt is
+// recorded against the stored message, except attempts cut short by
+// shutdown. consumer.job_timeout bounds each attempt.
+func (tm *TenantManager) handleJob(ctx context.Context, job Job) error {
+	if tm.cfg.Consumer.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tm.cfg.Consumer.JobTimeout)
+		defer cancel()
+	}
+
+	tm.lifecycle.Event(logging.EventProcessing, job.TenantID, job.MessageID, job.CorrelationID)
+	return tm.settleAttempt(ctx, job, tm.processPayload(ctx, job))
+}
+
+// settleAttempt records the attempt that ended with err against the job's
+// message, and returns the error the job is settled with.
+func (tm *TenantManager) settleAttempt(ctx context.Context, job Job, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		tm.lifecycle.Event(logging.EventRequeued, job.TenantID, job.MessageID, job.CorrelationID, "reason", "shutdown")
+		return err
+	}
+	err = tm.recordAttempt(job, err)
+	var failure *jobFailure
+	if !errors.As(err, &failure) || failure.settlement != SettleRequeue {
+		// Settled for good, so the message will not be redelivered
+		tm.redeliveries.Forget(job.TenantID, job.MessageID)
+	}
+	if err != nil && (failure == nil || failure.settlement == SettleDeadLetter) {
+		tm.alerts.Record(job.TenantID, job.MessageID, err.Error())
+	}
+	tm.logOutcome(job, err)
+	job.touch()
+	return err
+}
+
+// logOutcome logs how a processed job is about to be settled.
+func (tm *TenantManager) logOutcome(job Job, err error) {
+	if !tm.lifecycle.Enabled() {
+		return
+	}
+	if err == nil {
+		tm.lifecycle.Event(logging.EventProcessed, job.TenantID, job.MessageID, job.CorrelationID)
+		return
+	}
+
+	event := logging.EventDeadLettered
+	var failure *jobFailure
+	if errors.As(err, &failure) {
+		switch failure.settlement {
+		case SettleRequeue:
+			event = logging.EventRequeued
+		case SettleAck:
+			event = logging.EventDropped
+		}
+	}
+	tm.lifecycle.Event(event, job.TenantID, job.MessageID, job.CorrelationID, "error", err.Error())
+}
+
+// processPayload handles a job's message body. Payload fields listed in
+// logging.redact_fields are masked before anything is logged.
+func (tm *TenantManager) processPayload(ctx context.Context, job Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Process the message (placeholder implementation)
 	var message map[string]interface{}
-	if err := json.Unmarshal(body, &message); err != nil {
-		log.Printf("Failed to unmarshal message: %v", err)
-		return
+	if err := json.Unmarshal(job.Body, &message); err != nil {
+		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
-	log.Printf("Processing message: %v", message)
+	log.Printf("Processing message %s (correlation_id=%s) for tenant %s: %v",
+		job.MessageID, job.CorrelationID, job.TenantID, RedactPayload(message, tm.cfg.Logging.RedactFields))
 	// Add actual message processing logic here
+	return nil
 }
 
+// recordAttempt counts a processing attempt against the job's message and
+// updates its status. A failure is settled according to the tenant's failure
+// policy; messages that will not be retried are marked failed.
+func (tm *TenantManager) recordAttempt(job Job, procErr error) error {
+	maxAttempts := tm.cfg.Consumer.MaxAttempts
+
+	// Messages published outside the API have no row to track, so they are
+	// treated as having used up their attempts
+	attempts := maxAttempts
+	tracked := false
+	if _, err := uuid.Parse(job.MessageID); err == nil {
+		query := `
+			UPDATE messages
+			SET attempts = attempts + 1,
+				status = CASE WHEN $3 THEN 'processed' ELSE status END
+			WHERE tenant_id = $1 AND id = $2
+			RETURNING attempts
+		`
+		err := tm.db.QueryRow(query, job.TenantID, job.MessageID, procErr == nil).Scan(&attempts)
+		switch {
+		case err == nil:
+			tracked = true
+		case err != sql.ErrNoRows:
+			log.Printf("Failed to record attempt for message %s: %v", job.MessageID, err)
+		}
+	}
+
+	if procErr == nil {
+		return nil
+	}
+
+	settlement := FailureSettlement(job.failurePolicy, attempts, maxAttempts)
+	if tracked && settlement != SettleRequeue {
+		query := `UPDATE messages SET status = $3 WHERE tenant_id = $1 AND id = $2`
+		if _, err := tm.db.Exec(query, job.TenantID, job.MessageID, models.MessageStatusFailed); err != nil {
+			log.Printf("Failed to mark message %s as failed: %v", job.MessageID, err)
+		}
+	}
+
+	return &jobFailure{err: procErr, settlement: settlement}
+}
+
+// tenantQueueOptions returns the options the tenant's queues are declared
+// with.
+func (tm *TenantManager) tenantQueueOptions(tenantID string) messaging.QueueOptions {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.queueOptions[tenantID]
+}
+
+// failurePolicy returns the tenant's failure policy, defaulting to
+// retry_then_dlq. The caller must hold tm.mu.
+func (tm *TenantManager) failurePolicy(tenantID string) string {
+	if policy, ok := tm.failurePolicies[tenantID]; ok {
+		return policy
+	}
+	return models.FailurePolicyRetryThenDLQ
+}
+
+// UpdateWorkers resizes the pool to newWorkers without blocking. New
+// workers start straight away; workers beyond the new count exit as soon as
+// they are idle, after finishing the job they are handling, if any.
 func (wp *WorkerPool) UpdateWorkers(newWorkers int32) {
 	currentWorkers := atomic.LoadInt32(&wp.workers)
 	
 	if newWorkers > currentWorkers {
-		// Add workers
+		// Add workers, first keeping any still due to retire
 		for i := currentWorkers; i < newWorkers; i++ {
+			if takeOne(&wp.retiring) {
+				continue
+			}
 			wp.wg.Add(1)
 			go wp.worker()
 		}
 	} else if newWorkers < currentWorkers {
-		// Remove workers by sending quit signals
-		for i := newWorkers; i < currentWorkers; i++ {
-			wp.quit <- true
-		}
+		// Remove workers by asking that many to retire
+		atomic.AddInt32(&wp.retiring, currentWorkers-newWorkers)
+		wp.signalShrink()
 	}
 
 	atomic.StoreInt32(&wp.workers, newWorkers)
 }
 
+// Workers returns the pool's current number of workers.
+func (wp *WorkerPool) Workers() int32 {
+	return atomic.LoadInt32(&wp.workers)
+}
+
+// Running returns the number of worker goroutines alive. It differs from
+// Workers while a resize is taking effect.
+func (wp *WorkerPool) Running() int32 {
+	return atomic.LoadInt32(&wp.running)
+}
+
+// Capacity returns the most jobs that can wait in the pool's queue.
+func (wp *WorkerPool) Capacity() int {
+	return wp.jobQueue.capacity
+}
+
+// Queued returns the number of jobs waiting for a worker.
+func (wp *WorkerPool) Queued() int {
+	return wp.jobQueue.len()
+}
+
+// Busy returns the number of workers currently handling a job.
+func (wp *WorkerPool) Busy() int32 {
+	return atomic.LoadInt32(&wp.busy)
+}
+
+// Processed returns the number of jobs the pool's handler has run,
+// whatever their outcome.
+func (wp *WorkerPool) Processed() int64 {
+	return atomic.LoadInt64(&wp.processed)
+}
+
+// Stop cancels the context of running handlers and waits for the workers to
+// exit. Stopping a stopped pool just waits for its workers.
 func (wp *WorkerPool) Stop() {
-	close(wp.quit)
+	wp.cancel()
+	wp.stopOnce.Do(func() { close(wp.quit) })
+	wp.wg.Wait()
+}
+
+// Drain stops the pool's workers and returns the jobs still waiting in its
+// queue, highest priority first, so another pool can take them over. Jobs
+// already being handled are allowed to finish. Once one call has taken the
+// queued jobs, later calls return none.
+func (wp *WorkerPool) Drain() []Job {
+	wp.stopOnce.Do(func() { close(wp.quit) })
 	wp.wg.Wait()
+	wp.cancel()
+	return wp.jobQueue.drain()
 }
\ No newline at end of file
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ThroughputLimiter caps how many messages are processed per second across
+// every tenant. Worker pools built with one wait for their turn before
+// running each job, so once the budget is spent the workers stop taking jobs,
+// the consumers stop acking and the broker holds the rest back; nothing is
+// dropped.
+type ThroughputLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	// next is when the next message is due at the steady rate; up to burst
+	// messages may run ahead of it
+	next time.Time
+	// window counts the messages let through in the current second, and
+	// last those of the second before, for Utilization
+	windowStart time.Time
+	window      int
+	last        int
+	perSecond   float64
+}
+
+// NewThroughputLimiter lets perSecond messages through per second, and up
+// to burst at once after a quiet period. A burst below 1 is taken as 1.
+func NewThroughputLimiter(perSecond float64, burst int) *ThroughputLimiter {
+	return &ThroughputLimiter{
+		interval:  time.Duration(float64(time.Second) / perSecond),
+		burst:     max(burst, 1),
+		perSecond: perSecond,
+	}
+}
+
+// Wait blocks until the next message may be processed or ctx is done.
+func (l *ThroughputLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve lets a message through and returns 0, or returns how long to wait
+// before trying again.
+func (l *ThroughputLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	due := l.next
+	if due.Before(now) {
+		due = now
+	}
+	if wait := due.Add(-time.Duration(l.burst-1) * l.interval).Sub(now); wait > 0 {
+		return wait
+	}
+
+	l.next = due.Add(l.interval)
+	l.roll(now)
+	l.window++
+	return 0
+}
+
+// Utilization returns the share of the limit used over the last full
+// second, from 0 to 1.
+func (l *ThroughputLimiter) Utilization() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.roll(time.Now())
+	return min(float64(l.last)/l.perSecond, 1)
+}
+
+// roll moves the counting window forward to the second holding now.
+func (l *ThroughputLimiter) roll(now time.Time) {
+	elapsed := now.Sub(l.windowStart)
+	if elapsed < time.Second {
+		return
+	}
+	if elapsed < 2*time.Second {
+		l.last = l.window
+	} else {
+		l.last = 0
+	}
+	l.window = 0
+	l.windowStart = now.Truncate(time.Second)
+}
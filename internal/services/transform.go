@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownTransform = errors.New("unknown transform")
+	ErrTransformFailed  = errors.New("payload transform failed")
+)
+
+// Transformer rewrites a message payload before it is stored and published.
+// Implementations must not modify payload in place; it may still be stored
+// as the raw payload.
+type Transformer interface {
+	Transform(payload interface{}) (interface{}, error)
+}
+
+// TransformerFunc adapts a function to the Transformer interface.
+type TransformerFunc func(payload interface{}) (interface{}, error)
+
+func (f TransformerFunc) Transform(payload interface{}) (interface{}, error) {
+	return f(payload)
+}
+
+// Built-in transforms.
+const (
+	// TransformLowercaseKeys lowercases every object key, at any depth
+	TransformLowercaseKeys = "lowercase_keys"
+	// TransformAddReceivedAt sets a top-level "received_at" field to the
+	// time the message was accepted, overwriting any value sent by the client
+	TransformAddReceivedAt = "add_received_at"
+)
+
+var (
+	transformersMu sync.RWMutex
+	transformers   = map[string]Transformer{
+		TransformLowercaseKeys: TransformerFunc(lowercaseKeys),
+		TransformAddReceivedAt: TransformerFunc(addReceivedAt),
+	}
+)
+
+// RegisterTransformer makes a transform available to tenants under name,
+// replacing any transform registered under the same name. Register custom
+// transforms before the service starts accepting messages.
+func RegisterTransformer(name string, t Transformer) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	transformers[name] = t
+}
+
+// Transformers lists the names of the registered transforms.
+func Transformers() []string {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+
+	names := make([]string, 0, len(transformers))
+	for name := range transformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyTransforms runs the named transforms over payload in order and returns
+// the result. payload itself is left untouched.
+func ApplyTransforms(payload interface{}, names []string) (interface{}, error) {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+
+	result := payload
+	for _, name := range names {
+		t, exists := transformers[name]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTransform, name)
+		}
+		var err error
+		if result, err = t.Transform(result); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrTransformFailed, name, err)
+		}
+	}
+	return result, nil
+}
+
+// checkTransforms returns ErrUnknownTransform unless every name is registered.
+func checkTransforms(names []string) error {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+
+	for _, name := range names {
+		if _, exists := transformers[name]; !exists {
+			return fmt.Errorf("%w: %s", ErrUnknownTransform, name)
+		}
+	}
+	return nil
+}
+
+func lowercaseKeys(payload interface{}) (interface{}, error) {
+	switch v := payload.(type) {
+	case map[string]interface{}:
+		lowered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item, _ = lowercaseKeys(item)
+			lowered[strings.ToLower(key)] = item
+		}
+		return lowered, nil
+	case []interface{}:
+		lowered := make([]interface{}, len(v))
+		for i, item := range v {
+			lowered[i], _ = lowercaseKeys(item)
+		}
+		return lowered, nil
+	default:
+		return payload, nil
+	}
+}
+
+// addReceivedAt stamps object payloads; other payloads are left as they are.
+func addReceivedAt(payload interface{}) (interface{}, error) {
+	object, ok := payload.(map[string]interface{})
+	if !ok {
+		return payload, nil
+	}
+
+	stamped := make(map[string]interface{}, len(object)+1)
+	for key, item := range object {
+		stamped[key] = item
+	}
+	stamped["received_at"] = time.Now().UTC().Format(time.RFC3339Nano)
+	return stamped, nil
+}
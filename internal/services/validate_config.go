@@ -0,0 +1,47 @@
+package services
+
+import (
+	"sort"
+
+	"jatis/internal/models"
+)
+
+// ValidateConfig checks a tenant config change the way the update methods
+// do, without applying any of it, and lists each field that would be
+// rejected. Fields left out are not checked. The request bounds the API
+// enforces while binding, such as weight being 1 to 100, are the caller's to
+// check.
+func (tm *TenantManager) ValidateConfig(tenantID string, req models.ValidateConfigRequest) ([]models.ConfigError, error) {
+	if _, err := tm.GetTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	errs := []models.ConfigError{}
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, models.ConfigError{Field: field, Error: err.Error()})
+		}
+	}
+
+	if req.Workers != nil {
+		check("workers", tm.checkWorkers(*req.Workers))
+	}
+	if req.WorkerSchedule != nil {
+		check("worker_schedule", tm.checkWorkerSchedule(req.WorkerSchedule))
+	}
+	if req.Transforms != nil {
+		check("transforms", checkTransforms(req.Transforms))
+	}
+	check("payload_schema", CheckPayloadSchema(req.PayloadSchema))
+
+	names := make([]string, 0, len(req.Lanes))
+	for name := range req.Lanes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check("lanes."+name, tm.checkLane(name, req.Lanes[name]))
+	}
+
+	return errs, nil
+}
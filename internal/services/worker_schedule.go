@@ -0,0 +1,245 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"jatis/internal/models"
+)
+
+// ErrInvalidWorkerSchedule is returned for a worker schedule window that
+// cannot be parsed.
+var ErrInvalidWorkerSchedule = errors.New("invalid worker schedule")
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ScheduledWorkers returns the worker count of the first window of schedule
+// that holds now, or false if none does.
+func ScheduledWorkers(schedule []models.WorkerWindow, now time.Time) (int, bool) {
+	now = now.UTC()
+	clock := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second + time.Duration(now.Nanosecond())
+
+	for _, window := range schedule {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			continue
+		}
+
+		day := now.Weekday()
+		var holds bool
+		switch {
+		case start < end:
+			holds = clock >= start && clock < end
+		case clock >= start:
+			holds = true
+		case clock < end:
+			// The window started the day before
+			holds = true
+			day = (day + 6) % 7
+		}
+		if holds && onScheduleDay(window.Days, day) {
+			return window.Workers, true
+		}
+	}
+	return 0, false
+}
+
+func onScheduleDay(days []string, day time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if scheduleDays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses "HH:MM" into the time since midnight. "24:00" is
+// accepted as the end of the day.
+func parseClock(s string) (time.Duration, error) {
+	hours, minutes, found := strings.Cut(s, ":")
+	h, err := strconv.Atoi(hours)
+	if !found || len(hours) != 2 || err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	m, err := strconv.Atoi(minutes)
+	if len(minutes) != 2 || err != nil || m > 59 || h > 24 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// checkWorkerSchedule returns ErrInvalidWorkerSchedule for a window that
+// cannot be parsed, and ErrTooManyWorkers for one above max_workers.
+func (tm *TenantManager) checkWorkerSchedule(schedule []models.WorkerWindow) error {
+	for i, window := range schedule {
+		if _, err := parseClock(window.Start); err != nil {
+			return fmt.Errorf("%w: window %d start: %v", ErrInvalidWorkerSchedule, i, err)
+		}
+		if _, err := parseClock(window.End); err != nil {
+			return fmt.Errorf("%w: window %d end: %v", ErrInvalidWorkerSchedule, i, err)
+		}
+		for _, day := range window.Days {
+			if _, valid := scheduleDays[strings.ToLower(day)]; !valid {
+				return fmt.Errorf("%w: window %d: unknown day %q", ErrInvalidWorkerSchedule, i, day)
+			}
+		}
+		if window.Workers < 1 {
+			return fmt.Errorf("%w: window %d: workers must be at least 1", ErrInvalidWorkerSchedule, i)
+		}
+		if err := tm.checkWorkers(window.Workers); err != nil {
+			return fmt.Errorf("window %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// UpdateWorkerSchedule replaces the tenant's worker schedule and resizes its
+// dedicated pool to match straight away. Outside the schedule's windows the
+// tenant runs tenant_configs.workers.
+func (tm *TenantManager) UpdateWorkerSchedule(tenantID string, schedule []models.WorkerWindow) error {
+	if err := tm.checkWorkerSchedule(schedule); err != nil {
+		return err
+	}
+	if schedule == nil {
+		schedule = []models.WorkerWindow{}
+	}
+	scheduleBytes, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal worker schedule: %w", err)
+	}
+
+	if err := tm.updateTenantConfig(tenantID, "worker_schedule", string(scheduleBytes)); err != nil {
+		return err
+	}
+
+	var workers int
+	if err := tm.db.QueryRow(`SELECT workers FROM tenant_configs WHERE tenant_id = $1`, tenantID).Scan(&workers); err != nil {
+		return fmt.Errorf("failed to get worker count: %w", err)
+	}
+	tm.applyWorkerSchedule(tenantID, workers, schedule)
+	return nil
+}
+
+// scheduleWorker applies worker schedules every interval until Shutdown.
+func (tm *TenantManager) scheduleWorker(interval time.Duration) {
+	defer close(tm.scheduleExited)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := tm.ApplyWorkerSchedules(); err != nil {
+				log.Printf("Applying worker schedules failed: %v", err)
+			}
+		case <-tm.scheduleDone:
+			return
+		}
+	}
+}
+
+// ApplyWorkerSchedules resizes the dedicated pool of every tenant on this
+// instance that has a worker schedule, to the worker count of the window in
+// force or back to tenant_configs.workers, and lists the pools resized.
+func (tm *TenantManager) ApplyWorkerSchedules() ([]models.PoolCorrection, error) {
+	rows, err := tm.db.Query(`SELECT tenant_id, workers, worker_schedule FROM tenant_configs WHERE worker_schedule <> '[]'::jsonb`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load worker schedules: %w", err)
+	}
+	defer rows.Close()
+
+	corrections := []models.PoolCorrection{}
+	for rows.Next() {
+		var tenantID string
+		var workers int
+		var schedule []byte
+		if err := rows.Scan(&tenantID, &workers, &schedule); err != nil {
+			return corrections, fmt.Errorf("failed to scan worker schedule: %w", err)
+		}
+		if c, resized := tm.applyWorkerSchedule(tenantID, workers, decodeWorkerSchedule(schedule)); resized {
+			corrections = append(corrections, c)
+		}
+	}
+	return corrections, rows.Err()
+}
+
+// applyWorkerSchedule resizes the tenant's dedicated pool, if it has one
+// here, to the worker count its schedule sets for now.
+func (tm *TenantManager) applyWorkerSchedule(tenantID string, workers int, schedule []models.WorkerWindow) (models.PoolCorrection, bool) {
+	target := tm.workerTarget(workers, schedule)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	pool, exists := tm.workerPools[tenantID]
+	if !exists || pool.Workers() == int32(target) {
+		return models.PoolCorrection{}, false
+	}
+	c := models.PoolCorrection{TenantID: tenantID, From: int(pool.Workers()), To: target}
+	pool.UpdateWorkers(int32(target))
+	log.Printf("Worker schedule resized the pool of tenant %s from %d to %d workers", tenantID, c.From, c.To)
+	return c, true
+}
+
+// workerTarget returns the worker count of the schedule window in force,
+// held to max_workers, or workers if none is.
+func (tm *TenantManager) workerTarget(workers int, schedule []models.WorkerWindow) int {
+	scheduled, inWindow := ScheduledWorkers(schedule, time.Now())
+	if !inWindow {
+		return workers
+	}
+	if max := tm.cfg.MaxWorkers; max > 0 && scheduled > max {
+		return max
+	}
+	return scheduled
+}
+
+// loadWorkerSchedule returns the tenant's worker schedule.
+func (tm *TenantManager) loadWorkerSchedule(tenantID string) ([]models.WorkerWindow, error) {
+	var schedule []byte
+	err := tm.db.QueryRow(`SELECT worker_schedule FROM tenant_configs WHERE tenant_id = $1`, tenantID).Scan(&schedule)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worker schedule: %w", err)
+	}
+	return decodeWorkerSchedule(schedule), nil
+}
+
+// decodeWorkerSchedule decodes a stored worker schedule. One that cannot be
+// decoded is logged and treated as empty.
+func decodeWorkerSchedule(raw []byte) []models.WorkerWindow {
+	if len(raw) == 0 {
+		return nil
+	}
+	var schedule []models.WorkerWindow
+	if err := json.Unmarshal(raw, &schedule); err != nil {
+		log.Printf("Warning: ignoring undecodable worker schedule: %v", err)
+		return nil
+	}
+	return schedule
+}
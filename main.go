@@ -13,6 +13,7 @@ import (
 	"jatis/internal/config"
 	"jatis/internal/database"
 	"jatis/internal/messaging"
+	"jatis/internal/metrics"
 	"jatis/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +24,9 @@ import (
 // @description A multi-tenant messaging system with RabbitMQ and PostgreSQL
 // @host localhost:8080
 // @BasePath /api/v1
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -37,25 +41,38 @@ func main() {
 	}
 	defer db.Close()
 
-	// Run migrations
-	if err := database.RunMigrations(db); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	metrics.ConfigureTenantLabels(cfg.Metrics.TenantLabels, cfg.Metrics.TenantAllowlist)
+
+	// Export connection pool stats
+	stopDBStats := metrics.StartDBStatsCollector(db, cfg.Database.StatsInterval)
+	defer stopDBStats()
+
+	// Run migrations, or make sure they have been run
+	if cfg.Database.AutoMigrate {
+		if err := database.RunMigrations(db, cfg.Retry.Backoff(), cfg.Database.MaxRetries); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+	if err := database.CheckSchemaVersion(db); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
 	}
 
 	// Initialize RabbitMQ
-	rabbitmq, err := messaging.NewRabbitMQ(cfg.RabbitMQ.URL)
+	rabbitmq, err := messaging.NewRabbitMQ(cfg.RabbitMQ.URL, cfg.RabbitMQ.ConnectionName)
 	if err != nil {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
 	defer rabbitmq.Close()
+	rabbitmq.SetManagementURL(cfg.RabbitMQ.ManagementURL)
+	rabbitmq.SetConsumerPriority(cfg.RabbitMQ.ConsumerPriority)
 
 	// Initialize services
-	tenantManager := services.NewTenantManager(db, rabbitmq, cfg.Workers)
-	messageService := services.NewMessageService(db)
+	tenantManager := services.NewTenantManager(db, rabbitmq, cfg)
+	messageService := services.NewMessageService(db, rabbitmq, cfg)
 
 	// Initialize HTTP server
 	router := gin.Default()
-	api.SetupRoutes(router, tenantManager, messageService)
+	api.SetupRoutes(router, cfg, tenantManager, messageService)
 
 	server := &http.Server{
 		Addr:    ":8080",
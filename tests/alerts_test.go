@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNotifier struct {
+	mu     sync.Mutex
+	alerts []services.DeadLetterAlert
+	err    error
+}
+
+func (n *mockNotifier) Notify(_ context.Context, alert services.DeadLetterAlert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return n.err
+}
+
+func TestDeadLetterAlertsAreBatchedPerTenant(t *testing.T) {
+	notifier := &mockNotifier{}
+	alerts := services.NewDeadLetterAlerts([]services.Notifier{notifier}, nil)
+
+	for i := 0; i < 100; i++ {
+		alerts.Record("tenant-a", fmt.Sprintf("msg-%d", i), "handler failed")
+	}
+	alerts.Record("tenant-b", "msg-b", "invalid payload")
+	alerts.Flush(context.Background())
+
+	require.Len(t, notifier.alerts, 2)
+	a := notifier.alerts[0]
+	assert.Equal(t, "tenant-a", a.TenantID)
+	assert.Equal(t, 100, a.Count)
+	assert.Len(t, a.Samples, 5)
+	assert.Equal(t, services.DeadLetterSample{MessageID: "msg-0", Reason: "handler failed"}, a.Samples[0])
+	assert.Contains(t, a.Text(), "and 95 more")
+	assert.Equal(t, "tenant-b", notifier.alerts[1].TenantID)
+	assert.Equal(t, 1, notifier.alerts[1].Count)
+
+	// Nothing new, nothing sent
+	alerts.Flush(context.Background())
+	assert.Len(t, notifier.alerts, 2)
+}
+
+func TestDeadLetterAlertsRouteByTenant(t *testing.T) {
+	global, own := &mockNotifier{}, &mockNotifier{}
+	alerts := services.NewDeadLetterAlerts([]services.Notifier{global}, map[string][]services.Notifier{
+		"tenant-a": {own},
+		"tenant-b": nil, // silenced
+	})
+
+	alerts.Record("tenant-a", "msg-a", "failed")
+	alerts.Record("tenant-b", "msg-b", "failed")
+	alerts.Record("tenant-c", "msg-c", "failed")
+	alerts.Flush(context.Background())
+
+	require.Len(t, own.alerts, 1)
+	assert.Equal(t, "tenant-a", own.alerts[0].TenantID)
+	require.Len(t, global.alerts, 1)
+	assert.Equal(t, "tenant-c", global.alerts[0].TenantID)
+}
+
+func TestDeadLetterAlertsDropFailedAlerts(t *testing.T) {
+	notifier := &mockNotifier{err: errors.New("webhook down")}
+	alerts := services.NewDeadLetterAlerts([]services.Notifier{notifier}, nil)
+
+	alerts.Record("tenant-a", "msg-1", "failed")
+	alerts.Flush(context.Background())
+	alerts.Record("tenant-a", "msg-2", "failed")
+	alerts.Flush(context.Background())
+
+	require.Len(t, notifier.alerts, 2)
+	assert.Equal(t, 1, notifier.alerts[1].Count)
+
+	// A nil DeadLetterAlerts is disabled alerting
+	var disabled *services.DeadLetterAlerts
+	disabled.Record("tenant-a", "msg-3", "failed")
+	disabled.Flush(context.Background())
+}
+
+func TestSlackNotifierPostsText(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer server.Close()
+
+	alert := services.DeadLetterAlert{
+		TenantID: "tenant-a",
+		Count:    1,
+		Samples:  []services.DeadLetterSample{{MessageID: "msg-1", Reason: "handler failed"}},
+	}
+	require.NoError(t, services.SlackNotifier{WebhookURL: server.URL}.Notify(context.Background(), alert))
+	assert.Contains(t, body["text"], "tenant tenant-a")
+	assert.Contains(t, body["text"], "msg-1: handler failed")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer failing.Close()
+	assert.Error(t, services.SlackNotifier{WebhookURL: failing.URL}.Notify(context.Background(), alert))
+}
+
+// silentSMTPServer accepts connections and never answers them, as a hung
+// mail server would.
+func silentSMTPServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, c := range conns {
+					c.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// fakeSMTPServer accepts one message and sends its DATA to received.
+func fakeSMTPServer(t *testing.T, received chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO":
+				text.PrintfLine("250 localhost")
+			case "DATA":
+				text.PrintfLine("354 go ahead")
+				data, _ := text.ReadDotBytes()
+				received <- string(data)
+				text.PrintfLine("250 queued")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				return
+			default:
+				text.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestSMTPNotifierSendsAlert(t *testing.T) {
+	received := make(chan string, 1)
+	n := services.SMTPNotifier{Addr: fakeSMTPServer(t, received), From: "jatis@example.com", To: []string{"ops@example.com"}}
+	require.NoError(t, n.Notify(context.Background(), services.DeadLetterAlert{TenantID: "tenant-a", Count: 1}))
+
+	msg := <-received
+	assert.Contains(t, msg, "Subject: Dead letters for tenant tenant-a")
+	assert.Contains(t, msg, "To: ops@example.com")
+}
+
+func TestSMTPNotifierGivesUpOnHungServer(t *testing.T) {
+	addr := silentSMTPServer(t)
+	alert := services.DeadLetterAlert{TenantID: "tenant-a", Count: 1}
+
+	// Its own timeout
+	n := services.SMTPNotifier{Addr: addr, From: "jatis@example.com", To: []string{"ops@example.com"}, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	assert.Error(t, n.Notify(context.Background(), alert))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// The caller's context, when it ends first
+	n.Timeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	assert.Error(t, n.Notify(ctx, alert))
+	assert.Less(t, time.Since(start), time.Second)
+}
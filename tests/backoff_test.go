@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"jatis/internal/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelaysAreBoundedAndJittered(t *testing.T) {
+	b := backoff.Backoff{Base: 10 * time.Millisecond, Cap: 80 * time.Millisecond}
+
+	for attempt, ceiling := range []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		80 * time.Millisecond,
+		80 * time.Millisecond,
+		80 * time.Millisecond,
+	} {
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 200; i++ {
+			delay := b.Delay(attempt)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, ceiling, "attempt %d", attempt)
+			seen[delay] = true
+		}
+		assert.Greater(t, len(seen), 1, "attempt %d should not always wait the same time", attempt)
+	}
+}
+
+func TestBackoffDoesNotOverflowOnLateAttempts(t *testing.T) {
+	b := backoff.Backoff{Base: time.Second, Cap: time.Minute}
+
+	for _, attempt := range []int{62, 63, 64, 1000} {
+		delay := b.Delay(attempt)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, time.Minute)
+	}
+}
+
+func TestBackoffWithoutBaseDoesNotWait(t *testing.T) {
+	assert.Equal(t, time.Duration(0), backoff.Backoff{}.Delay(3))
+}
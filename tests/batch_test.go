@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"jatis/internal/messaging"
+	"jatis/internal/services"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder is a BatchHandler that records the bodies of each batch.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (r *batchRecorder) handle(ctx context.Context, jobs []services.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var bodies []string
+	for _, job := range jobs {
+		bodies = append(bodies, string(job.Body))
+	}
+	r.batches = append(r.batches, bodies)
+	return r.err
+}
+
+func (r *batchRecorder) recorded() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string{}, r.batches...)
+}
+
+func TestBatchWorkerPoolGroupsJobs(t *testing.T) {
+	recorder := &batchRecorder{}
+	pool := services.NewBatchWorkerPool(context.Background(), 0, 3, 50*time.Millisecond, recorder.handle)
+	defer pool.Stop()
+
+	for _, body := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		require.NoError(t, pool.Submit(services.Job{Body: []byte(body)}))
+	}
+	pool.UpdateWorkers(1)
+
+	assert.Eventually(t, func() bool { return len(recorder.recorded()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d", "e", "f"}, {"g"}}, recorder.recorded())
+	assert.Equal(t, int64(7), pool.Processed())
+}
+
+func TestBatchWorkerPoolFlushesPartialBatchAfterWait(t *testing.T) {
+	const wait = 100 * time.Millisecond
+	recorder := &batchRecorder{}
+	pool := services.NewBatchWorkerPool(context.Background(), 1, 10, wait, recorder.handle)
+	defer pool.Stop()
+
+	submitted := time.Now()
+	require.NoError(t, pool.Submit(services.Job{Body: []byte("a")}))
+	require.NoError(t, pool.Submit(services.Job{Body: []byte("b")}))
+
+	assert.Eventually(t, func() bool { return len(recorder.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(submitted), wait)
+	assert.Equal(t, [][]string{{"a", "b"}}, recorder.recorded())
+}
+
+func TestBatchWorkerPoolSettlesWholeBatch(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		err      error
+		acks     int
+		nacks    int
+		requeued bool
+	}{
+		{"success acks every job", nil, 1, 0, false},
+		{"failure nacks every job", errors.New("bulk write failed"), 0, 1, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &batchRecorder{err: tt.err}
+			pool := services.NewBatchWorkerPool(context.Background(), 0, 3, time.Second, recorder.handle)
+			defer pool.Stop()
+
+			acknowledgers := make([]*recordingAcknowledger, 3)
+			for i := range acknowledgers {
+				acknowledgers[i] = &recordingAcknowledger{}
+				lease := messaging.NewLease(amqp.Delivery{Acknowledger: acknowledgers[i], DeliveryTag: uint64(i + 1), Body: []byte("{}")}, 0)
+				require.NoError(t, pool.Submit(services.NewJob("tenant", lease)))
+			}
+			pool.UpdateWorkers(1)
+
+			// Jobs are settled after the handler returns
+			assert.Eventually(t, func() bool {
+				acks, nacks, _ := acknowledgers[2].counts()
+				return acks+nacks == 1
+			}, time.Second, 5*time.Millisecond)
+			assert.Len(t, recorder.recorded(), 1)
+			for _, ack := range acknowledgers {
+				acks, nacks, requeue := ack.counts()
+				assert.Equal(t, tt.acks, acks)
+				assert.Equal(t, tt.nacks, nacks)
+				assert.Equal(t, tt.requeued, requeue)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBlobStoreRoundTrip(t *testing.T) {
+	store := services.FileBlobStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "tenant-a/blob-1", []byte(`{"big": true}`)))
+	data, err := store.Get(ctx, "tenant-a/blob-1")
+	require.NoError(t, err)
+	assert.Equal(t, `{"big": true}`, string(data))
+
+	require.NoError(t, store.Delete(ctx, "tenant-a/blob-1"))
+	_, err = store.Get(ctx, "tenant-a/blob-1")
+	assert.ErrorIs(t, err, services.ErrBlobNotFound)
+	assert.NoError(t, store.Delete(ctx, "tenant-a/blob-1"))
+
+	assert.Error(t, store.Put(ctx, "../outside", []byte("x")))
+}
+
+func TestS3BlobStoreSignsRequests(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+			r.Header.Get("X-Amz-Content-Sha256") == "" || r.Header.Get("X-Amz-Date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, exists := objects[r.URL.Path]
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := services.S3BlobStore{Endpoint: server.URL, Region: "eu-west-1", Bucket: "payloads", AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "tenant-a/blob-1", []byte("payload")))
+	assert.Contains(t, objects, "/payloads/tenant-a/blob-1")
+	data, err := store.Get(ctx, "tenant-a/blob-1")
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(data))
+
+	require.NoError(t, store.Delete(ctx, "tenant-a/blob-1"))
+	_, err = store.Get(ctx, "tenant-a/blob-1")
+	assert.ErrorIs(t, err, services.ErrBlobNotFound)
+
+	store.SecretAccessKey, store.AccessKeyID = "", "other"
+	assert.Error(t, store.Put(ctx, "tenant-a/blob-2", []byte("payload")))
+}
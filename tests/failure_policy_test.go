@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"jatis/internal/models"
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureSettlement(t *testing.T) {
+	const maxAttempts = 3
+
+	tests := []struct {
+		policy   string
+		attempts int
+		want     services.Settlement
+	}{
+		{models.FailurePolicyDLQ, 1, services.SettleDeadLetter},
+		{models.FailurePolicyRetry, 1, services.SettleRequeue},
+		{models.FailurePolicyRetry, 50, services.SettleRequeue},
+		{models.FailurePolicyDrop, 1, services.SettleAck},
+		{models.FailurePolicyRetryThenDLQ, 1, services.SettleRequeue},
+		{models.FailurePolicyRetryThenDLQ, 2, services.SettleRequeue},
+		{models.FailurePolicyRetryThenDLQ, 3, services.SettleDeadLetter},
+		{"", 1, services.SettleRequeue},
+		{"", 3, services.SettleDeadLetter},
+	}
+
+	for _, tt := range tests {
+		got := services.FailureSettlement(tt.policy, tt.attempts, maxAttempts)
+		assert.Equal(t, tt.want, got, "policy %q after %d attempts", tt.policy, tt.attempts)
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jatis/internal/models"
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckerCachesRapidProbes(t *testing.T) {
+	var pings int32
+	checker := services.NewHealthChecker(time.Hour, map[string]services.HealthCheck{
+		models.DependencyPostgres: func(ctx context.Context) error {
+			atomic.AddInt32(&pings, 1)
+			return nil
+		},
+	})
+	defer checker.Stop()
+
+	for i := 0; i < 100; i++ {
+		report := checker.Report()
+		assert.True(t, report.Ready)
+		assert.Equal(t, models.DependencyOK, report.Dependencies[models.DependencyPostgres])
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pings))
+}
+
+func TestHealthCheckerReflectsOutageWithinTTL(t *testing.T) {
+	var down atomic.Bool
+	ttl := 50 * time.Millisecond
+	checker := services.NewHealthChecker(ttl, map[string]services.HealthCheck{
+		models.DependencyRabbitMQ: func(ctx context.Context) error {
+			if down.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	})
+	defer checker.Stop()
+	assert.True(t, checker.Report().Ready)
+
+	down.Store(true)
+	outage := time.Now()
+	assert.Eventually(t, func() bool { return !checker.Report().Ready }, time.Second, 5*time.Millisecond)
+	assert.Less(t, time.Since(outage), 3*ttl)
+	assert.Equal(t, "connection refused", checker.Report().Dependencies[models.DependencyRabbitMQ])
+
+	down.Store(false)
+	assert.Eventually(t, func() bool { return checker.Report().Ready }, time.Second, 5*time.Millisecond)
+}
+
+func TestHealthCheckerWithoutCacheChecksEveryProbe(t *testing.T) {
+	var pings int32
+	checker := services.NewHealthChecker(0, map[string]services.HealthCheck{
+		models.DependencyPostgres: func(ctx context.Context) error {
+			atomic.AddInt32(&pings, 1)
+			return nil
+		},
+	})
+	defer checker.Stop()
+
+	for i := 0; i < 5; i++ {
+		checker.Report()
+	}
+	assert.Equal(t, int32(5), atomic.LoadInt32(&pings))
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"jatis/internal/config"
+	"jatis/internal/models"
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyLag(t *testing.T) {
+	thresholds := config.LagConfig{
+		DegradedQueueDepth:    100,
+		OverloadedQueueDepth:  1000,
+		DegradedPendingAge:    time.Minute,
+		OverloadedPendingAge:  10 * time.Minute,
+		DegradedUtilization:   0.8,
+		OverloadedUtilization: 1,
+	}
+
+	tests := []struct {
+		name    string
+		lag     models.TenantLag
+		status  string
+		reasons int
+	}{
+		{"idle", models.TenantLag{}, models.LagHealthy, 0},
+		{"busy but keeping up", models.TenantLag{QueueDepth: 99, OldestPendingAgeSeconds: 59, WorkerUtilization: 0.79}, models.LagHealthy, 0},
+		{"deep queue", models.TenantLag{QueueDepth: 100}, models.LagDegraded, 1},
+		{"old pending message", models.TenantLag{OldestPendingAgeSeconds: 90}, models.LagDegraded, 1},
+		{"saturated workers", models.TenantLag{WorkerUtilization: 0.9}, models.LagDegraded, 1},
+		{"several degraded indicators", models.TenantLag{QueueDepth: 500, OldestPendingAgeSeconds: 120, WorkerUtilization: 0.85}, models.LagDegraded, 3},
+		{"queue over the overloaded threshold", models.TenantLag{QueueDepth: 5000}, models.LagOverloaded, 1},
+		{"overloaded wins over degraded", models.TenantLag{QueueDepth: 200, OldestPendingAgeSeconds: 900, WorkerUtilization: 1}, models.LagOverloaded, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lag := tt.lag
+			services.ClassifyLag(&lag, thresholds)
+			assert.Equal(t, tt.status, lag.Status)
+			assert.Len(t, lag.Reasons, tt.reasons)
+		})
+	}
+}
+
+func TestClassifyLagSkipsZeroThresholds(t *testing.T) {
+	lag := models.TenantLag{QueueDepth: 1 << 20, OldestPendingAgeSeconds: 86400, WorkerUtilization: 1}
+	services.ClassifyLag(&lag, config.LagConfig{DegradedQueueDepth: 10})
+
+	assert.Equal(t, models.LagDegraded, lag.Status)
+	assert.Equal(t, []string{"queue depth 1048576 reached the degraded threshold of 10"}, lag.Reasons)
+}
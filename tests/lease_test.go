@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"jatis/internal/messaging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAcknowledger records how deliveries were settled.
+type recordingAcknowledger struct {
+	mu      sync.Mutex
+	acks    int
+	nacks   int
+	requeue bool
+}
+
+func (a *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acks++
+	return nil
+}
+
+func (a *recordingAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacks++
+	a.requeue = requeue
+	return nil
+}
+
+func (a *recordingAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *recordingAcknowledger) counts() (acks, nacks int, requeue bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.acks, a.nacks, a.requeue
+}
+
+func TestLeaseRequeuesAfterDeadline(t *testing.T) {
+	ack := &recordingAcknowledger{}
+	lease := messaging.NewLease(amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}, 50*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, nacks, _ := ack.counts()
+		return nacks == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// A worker finishing late must not settle the delivery a second time
+	assert.False(t, lease.Ack())
+	assert.False(t, lease.Extend(time.Second))
+
+	acks, nacks, requeue := ack.counts()
+	assert.Equal(t, 0, acks)
+	assert.Equal(t, 1, nacks)
+	assert.True(t, requeue)
+}
+
+func TestLeaseExtendPostponesDeadline(t *testing.T) {
+	ack := &recordingAcknowledger{}
+	lease := messaging.NewLease(amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}, 50*time.Millisecond)
+
+	require.True(t, lease.Extend(500*time.Millisecond))
+	time.Sleep(150 * time.Millisecond)
+
+	assert.True(t, lease.Ack())
+	acks, nacks, _ := ack.counts()
+	assert.Equal(t, 1, acks)
+	assert.Equal(t, 0, nacks)
+}
+
+func TestLeaseWithoutDeadlineNeverExpires(t *testing.T) {
+	ack := &recordingAcknowledger{}
+	lease := messaging.NewLease(amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}, 0)
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.True(t, lease.Nack(false))
+	acks, nacks, requeue := ack.counts()
+	assert.Equal(t, 0, acks)
+	assert.Equal(t, 1, nacks)
+	assert.False(t, requeue)
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"jatis/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func logLines(out *syncBuffer) []string {
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestLimiterBoundsRepeatedErrors(t *testing.T) {
+	var out syncBuffer
+	limiter := logging.NewLimiter(100*time.Millisecond, log.New(&out, "", 0))
+
+	errClosed := errors.New("channel/connection is not open")
+	for i := 0; i < 1000; i++ {
+		limiter.Printf("Warning: failed to settle message %s: %v", fmt.Sprintf("msg-%d", i), errClosed)
+	}
+
+	assert.Equal(t, []string{"Warning: failed to settle message msg-0: channel/connection is not open"}, logLines(&out))
+
+	// One summary carries the count of everything suppressed
+	assert.Eventually(t, func() bool { return len(logLines(&out)) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Warning: failed to settle message msg-999: channel/connection is not open (repeated 999 times in the last 100ms)", logLines(&out)[1])
+
+	// Once the flood is over the message is logged straight away again
+	time.Sleep(250 * time.Millisecond)
+	limiter.Printf("Warning: failed to settle message %s: %v", "msg-1000", errClosed)
+	assert.Len(t, logLines(&out), 3)
+}
+
+func TestLimiterKeepsDistinctErrors(t *testing.T) {
+	var out syncBuffer
+	limiter := logging.NewLimiter(time.Minute, log.New(&out, "", 0))
+
+	for i := 0; i < 100; i++ {
+		limiter.Printf("Failed to process message: %v", errors.New("queue is full"))
+		limiter.Printf("Failed to process message: %v", errors.New("connection closed"))
+	}
+
+	assert.Equal(t, []string{
+		"Failed to process message: queue is full",
+		"Failed to process message: connection closed",
+	}, logLines(&out))
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	var out syncBuffer
+	lifecycle := logging.NewLifecycle(true, log.New(&out, "", 0))
+
+	lifecycle.Event(logging.EventDelivered, "tenant-1", "msg-1", "trace-1", "lane", "", "redelivered", "false")
+	lifecycle.Event(logging.EventRequeued, "tenant-1", "msg-1", "trace-1", "error", "handler failed: timeout")
+
+	assert.Equal(t, []string{
+		"lifecycle event=delivered message_id=msg-1 correlation_id=trace-1 tenant_id=tenant-1 redelivered=false",
+		`lifecycle event=requeued message_id=msg-1 correlation_id=trace-1 tenant_id=tenant-1 error="handler failed: timeout"`,
+	}, logLines(&out))
+
+	// Disabled lifecycles log nothing
+	disabled := logging.NewLifecycle(false, log.New(&out, "", 0))
+	assert.False(t, disabled.Enabled())
+	disabled.Event(logging.EventCreated, "tenant-1", "msg-2", "trace-2")
+	assert.Len(t, logLines(&out), 2)
+}
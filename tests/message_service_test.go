@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"jatis/internal/config"
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDriver is a database/sql driver that records every statement and
+// answers queries with a single row holding the driver's tenantID.
+type recordingDriver struct {
+	mu       sync.Mutex
+	queries  []string
+	tenantID string
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d}, nil }
+
+func (d *recordingDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+}
+
+func (d *recordingDriver) recorded() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *recordingConn) Commit() error             { return nil }
+func (c *recordingConn) Rollback() error           { return nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.record(s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.record(s.query)
+	return &singleRow{value: s.d.tenantID}, nil
+}
+
+type singleRow struct {
+	value string
+	done  bool
+}
+
+func (r *singleRow) Columns() []string { return []string{"tenant_id"} }
+func (r *singleRow) Close() error      { return nil }
+func (r *singleRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+// recordingDrivers numbers the registered drivers; database/sql does not
+// allow a name to be registered twice.
+var recordingDrivers int32
+
+func newRecordingDB(t *testing.T, tenantID string) (*sql.DB, *recordingDriver) {
+	d := &recordingDriver{tenantID: tenantID}
+	name := fmt.Sprintf("recording-%d", atomic.AddInt32(&recordingDrivers, 1))
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestDeleteMessageTargetsTenantPartition(t *testing.T) {
+	const tenantID = "7c1b6a9e-3f55-4c57-9d3f-3c0f8f6c2b11"
+	const messageID = "0f7e4c7a-2d7c-4b8e-9a61-5f0f6e2b9c44"
+
+	t.Run("with tenant", func(t *testing.T) {
+		db, d := newRecordingDB(t, tenantID)
+		ms := services.NewMessageService(db, nil, config.Default())
+
+		require.NoError(t, ms.DeleteMessage(tenantID, messageID))
+
+		queries := d.recorded()
+		require.Len(t, queries, 1)
+		assert.True(t, strings.HasPrefix(queries[0], "DELETE"))
+		assert.Contains(t, queries[0], "tenant_id = $1")
+	})
+
+	t.Run("tenant looked up", func(t *testing.T) {
+		db, d := newRecordingDB(t, tenantID)
+		ms := services.NewMessageService(db, nil, config.Default())
+
+		require.NoError(t, ms.DeleteMessage("", messageID))
+
+		queries := d.recorded()
+		require.Len(t, queries, 2)
+		assert.True(t, strings.HasPrefix(queries[0], "SELECT tenant_id"))
+		assert.True(t, strings.HasPrefix(queries[1], "DELETE"))
+		assert.Contains(t, queries[1], "tenant_id = $1")
+	})
+}
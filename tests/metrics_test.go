@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jatis/internal/config"
+	"jatis/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricLabels returns the label names of every sample of the metric called
+// name in the default Prometheus registry.
+func metricLabels(t *testing.T, name string) [][]string {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	var labels [][]string
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var names []string
+			for _, label := range metric.GetLabel() {
+				names = append(names, label.GetName())
+			}
+			labels = append(labels, names)
+		}
+	}
+	return labels
+}
+
+func TestMetricsWithoutTenantLabels(t *testing.T) {
+	metrics.ConfigureTenantLabels(false, nil)
+	t.Cleanup(func() { metrics.ConfigureTenantLabels(true, nil) })
+
+	metrics.IncrementMessagesProcessed("tenant-a", "success")
+	metrics.IncrementMessagesProcessed("tenant-b", "success")
+	metrics.RecordDelivery("tenant-a", true)
+	metrics.ObservePayloadSize("tenant-a", 100)
+
+	for _, name := range []string{"messages_processed_total", "messages_delivered_total", "messages_redelivered_total", "message_payload_bytes"} {
+		samples := metricLabels(t, name)
+		require.NotEmpty(t, samples, name)
+		for _, labels := range samples {
+			assert.NotContains(t, labels, "tenant_id", name)
+		}
+	}
+	// Tenants are aggregated
+	assert.Equal(t, float64(2), metricValue("messages_processed_total", map[string]string{"status": "success"}))
+}
+
+func TestMetricsTenantAllowlist(t *testing.T) {
+	metrics.ConfigureTenantLabels(true, []string{"watched"})
+	t.Cleanup(func() { metrics.ConfigureTenantLabels(true, nil) })
+
+	metrics.IncrementMessagesProcessed("watched", "success")
+	metrics.IncrementMessagesProcessed("tenant-a", "success")
+	metrics.IncrementMessagesProcessed("tenant-b", "success")
+
+	assert.Equal(t, float64(1), messagesProcessed("watched", "success"))
+	assert.Equal(t, float64(2), messagesProcessed(metrics.OtherTenants, "success"))
+	assert.Zero(t, messagesProcessed("tenant-a", "success"))
+}
+
+func TestDBStatsIntervalConfig(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	load := func(interval string) (*config.Config, error) {
+		yaml := "database:\n  stats_interval: " + interval + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(yaml), 0o644))
+		return config.Load()
+	}
+
+	// Zero turns the collector off rather than panicking in NewTicker
+	cfg, err := load("0s")
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Database.StatsInterval)
+	stop := metrics.StartDBStatsCollector(nil, cfg.Database.StatsInterval)
+	stop()
+
+	_, err = load("-5s")
+	assert.ErrorContains(t, err, "stats_interval")
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"jatis/internal/config"
+	"jatis/internal/database"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// migrationDB is a database/sql driver that records the statements run
+// against it and fails them, or the connection, as told.
+type migrationDB struct {
+	mu         sync.Mutex
+	statements []string
+	// failures fails each statement once with its error
+	failures map[string]error
+	// connectFailures fails that many connection attempts
+	connectFailures int
+}
+
+func (m *migrationDB) record(statement string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statements = append(m.statements, statement)
+	if err, exists := m.failures[statement]; exists {
+		delete(m.failures, statement)
+		return err
+	}
+	return nil
+}
+
+func (m *migrationDB) Connect(context.Context) (driver.Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connectFailures > 0 {
+		m.connectFailures--
+		return nil, &pq.Error{Code: "57P03"} // cannot_connect_now
+	}
+	return migrationConn{m}, nil
+}
+
+func (m *migrationDB) Driver() driver.Driver { return nil }
+
+type migrationConn struct{ db *migrationDB }
+
+func (c migrationConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c migrationConn) Close() error { return nil }
+func (c migrationConn) Begin() (driver.Tx, error) {
+	return migrationConn(c), c.db.record("BEGIN")
+}
+func (c migrationConn) Commit() error   { return c.db.record("COMMIT") }
+func (c migrationConn) Rollback() error { return c.db.record("ROLLBACK") }
+func (c migrationConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.record(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func migrate(m *migrationDB, steps ...string) error {
+	db := sql.OpenDB(m)
+	defer db.Close()
+	return database.Migrate(db, steps, testBackoff, 3)
+}
+
+func TestMigrateRetriesTransientStepFromSavepoint(t *testing.T) {
+	m := &migrationDB{failures: map[string]error{
+		"step 2": &pq.Error{Code: "40P01"}, // deadlock_detected
+	}}
+	require.NoError(t, migrate(m, "step 1", "step 2", "step 3"))
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SELECT pg_advisory_xact_lock(hashtext($1))",
+		"SAVEPOINT migration_step", "step 1", "RELEASE SAVEPOINT migration_step",
+		"SAVEPOINT migration_step", "step 2", "ROLLBACK TO SAVEPOINT migration_step",
+		"step 2", "RELEASE SAVEPOINT migration_step",
+		"SAVEPOINT migration_step", "step 3", "RELEASE SAVEPOINT migration_step",
+		"COMMIT",
+	}, m.statements)
+}
+
+func TestMigrateRollsBackOnFailedStep(t *testing.T) {
+	m := &migrationDB{failures: map[string]error{
+		"step 2": &pq.Error{Code: "42601"}, // syntax_error
+	}}
+	err := migrate(m, "step 1", "step 2", "step 3")
+
+	var pqErr *pq.Error
+	require.True(t, errors.As(err, &pqErr))
+	assert.Contains(t, err.Error(), "migration 1")
+	assert.NotContains(t, m.statements, "step 3")
+	assert.NotContains(t, m.statements, "COMMIT")
+	assert.Equal(t, "ROLLBACK", m.statements[len(m.statements)-1])
+}
+
+func TestMigrateWaitsForStartingDatabase(t *testing.T) {
+	m := &migrationDB{connectFailures: 2}
+	require.NoError(t, migrate(m, "step 1"))
+	assert.Equal(t, "COMMIT", m.statements[len(m.statements)-1])
+
+	m = &migrationDB{connectFailures: 10}
+	assert.Error(t, migrate(m, "step 1"))
+	assert.Empty(t, m.statements)
+}
+
+func TestAutoMigrateEnvOverride(t *testing.T) {
+	assert.True(t, config.Default().Database.AutoMigrate)
+
+	t.Setenv("DATABASE_AUTO_MIGRATE", "false")
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.Database.AutoMigrate)
+
+	t.Setenv("DATABASE_AUTO_MIGRATE", "sometimes")
+	_, err = config.Load()
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"testing"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedeliveryCountsCountRedeliveriesPerMessage(t *testing.T) {
+	counts := services.NewRedeliveryCounts()
+
+	assert.Equal(t, 0, counts.Observe("tenant", "m1", false))
+	assert.Equal(t, 1, counts.Observe("tenant", "m1", true))
+	assert.Equal(t, 2, counts.Observe("tenant", "m1", true))
+	assert.Equal(t, 1, counts.Observe("tenant", "m2", true))
+	assert.Equal(t, 1, counts.Observe("other", "m1", true), "counts are kept per tenant")
+
+	// A first delivery starts the count over, e.g. after a reprocess
+	assert.Equal(t, 0, counts.Observe("tenant", "m1", false))
+	assert.Equal(t, 1, counts.Observe("tenant", "m1", true))
+
+	counts.Forget("tenant", "m2")
+	assert.Equal(t, 1, counts.Observe("tenant", "m2", true))
+
+	counts.ForgetTenant("tenant")
+	assert.Equal(t, 1, counts.Observe("tenant", "m1", true))
+	assert.Equal(t, 2, counts.Observe("other", "m1", true))
+}
+
+func TestRedeliveryCountsIgnoreMessagesWithoutID(t *testing.T) {
+	counts := services.NewRedeliveryCounts()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 0, counts.Observe("tenant", "", true))
+	}
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePayloadFilter(t *testing.T) {
+	filter, err := services.ParsePayloadFilter("meta.env=test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"meta", "env"}, filter.Path)
+	assert.Equal(t, services.FilterEquals, filter.Op)
+	assert.Equal(t, "test", filter.Value)
+
+	filter, err = services.ParsePayloadFilter("env!=prod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"env"}, filter.Path)
+	assert.Equal(t, services.FilterNotEquals, filter.Op)
+	assert.Equal(t, "prod", filter.Value)
+
+	// Everything after the operator is the value
+	filter, err = services.ParsePayloadFilter("query=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, "a=b", filter.Value)
+
+	filter, err = services.ParsePayloadFilter("env=")
+	require.NoError(t, err)
+	assert.Equal(t, "", filter.Value)
+}
+
+func TestParsePayloadFilterRejectsInvalidPaths(t *testing.T) {
+	for _, expr := range []string{
+		"env",
+		"=test",
+		"meta..env=test",
+		"env'; DROP TABLE messages; --=x",
+		"payload->>'env'=test",
+		"env test=x",
+		"{env}=x",
+	} {
+		_, err := services.ParsePayloadFilter(expr)
+		assert.ErrorIs(t, err, services.ErrInvalidFilter, expr)
+	}
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPayload(t *testing.T) {
+	payload := map[string]interface{}{
+		"user":     "alice",
+		"password": "hunter2",
+		"card": map[string]interface{}{
+			"number": "4111111111111111",
+			"brand":  "visa",
+		},
+		"people": []interface{}{
+			map[string]interface{}{"name": "bob", "ssn": "123-45-6789"},
+		},
+	}
+
+	redacted := services.RedactPayload(payload, []string{"password", "card.number", "people.ssn", "missing.field"})
+
+	assert.Equal(t, map[string]interface{}{
+		"user":     "alice",
+		"password": services.RedactedValue,
+		"card": map[string]interface{}{
+			"number": services.RedactedValue,
+			"brand":  "visa",
+		},
+		"people": []interface{}{
+			map[string]interface{}{"name": "bob", "ssn": services.RedactedValue},
+		},
+	}, redacted)
+
+	// The original payload is left untouched
+	assert.Equal(t, "hunter2", payload["password"])
+	assert.Equal(t, "4111111111111111", payload["card"].(map[string]interface{})["number"])
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"jatis/internal/backoff"
+	"jatis/internal/database"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBackoff = backoff.Backoff{Base: time.Millisecond, Cap: 10 * time.Millisecond}
+
+func TestRetryRecoversFromTransientError(t *testing.T) {
+	calls := 0
+	err := database.Retry(testBackoff, 3, func() error {
+		calls++
+		if calls == 1 {
+			return &pq.Error{Code: "40001"} // serialization_failure
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryFailsFastOnConstraintViolation(t *testing.T) {
+	calls := 0
+	err := database.Retry(testBackoff, 3, func() error {
+		calls++
+		return &pq.Error{Code: "23505"} // unique_violation
+	})
+
+	var pqErr *pq.Error
+	assert.True(t, errors.As(err, &pqErr))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryGivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	err := database.Retry(testBackoff, 2, func() error {
+		calls++
+		return &pq.Error{Code: "08006"} // connection_failure
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+}
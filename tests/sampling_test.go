@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"testing"
+
+	"jatis/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampledKeepsRoughlyTheRate(t *testing.T) {
+	const total = 10000
+	sampled := 0
+	for i := 0; i < total; i++ {
+		if services.Sampled(uuid.NewString(), 0.5) {
+			sampled++
+		}
+	}
+
+	assert.InDelta(t, total/2, sampled, total*0.05)
+}
+
+func TestSampledIsStableForAMessage(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		id := uuid.NewString()
+		first := services.Sampled(id, 0.5)
+		for j := 0; j < 5; j++ {
+			assert.Equal(t, first, services.Sampled(id, 0.5), "a redelivery must be sampled the same way")
+		}
+	}
+}
+
+func TestSampledBounds(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		id := uuid.NewString()
+		assert.True(t, services.Sampled(id, 1))
+		assert.False(t, services.Sampled(id, 0))
+	}
+	assert.True(t, services.Sampled("", 1))
+	assert.False(t, services.Sampled("", 0))
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFairPoolDoesNotStarveQuietTenant(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	scheduler := services.NewScheduler(1)
+	pool := services.NewScheduledWorkerPool(context.Background(), 0, 200, scheduler, func(ctx context.Context, job services.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, job.TenantID)
+		return nil
+	})
+	defer pool.Stop()
+
+	// The noisy tenant's backlog is queued before the quiet tenant's messages
+	for i := 0; i < 50; i++ {
+		require.NoError(t, pool.Submit(services.Job{TenantID: "noisy", Body: []byte("{}")}))
+	}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, pool.Submit(services.Job{TenantID: "quiet", Body: []byte("{}")}))
+	}
+
+	pool.UpdateWorkers(1)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 55
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	quiet := 0
+	for _, tenantID := range order[:10] {
+		if tenantID == "quiet" {
+			quiet++
+		}
+	}
+	assert.Equal(t, 5, quiet, "quiet tenant should be interleaved with the backlog, got %v", order[:10])
+}
+
+func TestFairPoolHonoursWeights(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	scheduler := services.NewScheduler(1)
+	scheduler.SetWeight("heavy", 3)
+	pool := services.NewScheduledWorkerPool(context.Background(), 0, 200, scheduler, func(ctx context.Context, job services.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, job.TenantID)
+		return nil
+	})
+	defer pool.Stop()
+
+	for i := 0; i < 40; i++ {
+		require.NoError(t, pool.Submit(services.Job{TenantID: "light", Body: []byte("{}")}))
+		require.NoError(t, pool.Submit(services.Job{TenantID: "heavy", Body: []byte("{}")}))
+	}
+
+	pool.UpdateWorkers(1)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 80
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	heavy := 0
+	for _, tenantID := range order[:20] {
+		if tenantID == "heavy" {
+			heavy++
+		}
+	}
+	assert.InDelta(t, 15, heavy, 1)
+}
+
+func TestSchedulerSharesSlotsAcrossPools(t *testing.T) {
+	scheduler := services.NewScheduler(1)
+	var noisyDone, quietDone int32
+	work := func(counter *int32) services.JobHandler {
+		return func(ctx context.Context, job services.Job) error {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(counter, 1)
+			return nil
+		}
+	}
+
+	// The noisy tenant has more workers and far more messages
+	noisy := services.NewScheduledWorkerPool(context.Background(), 4, 200, scheduler, work(&noisyDone))
+	defer noisy.Stop()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, noisy.Submit(services.Job{TenantID: "noisy", Body: []byte("{}")}))
+	}
+
+	quiet := services.NewScheduledWorkerPool(context.Background(), 1, 200, scheduler, work(&quietDone))
+	defer quiet.Stop()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, quiet.Submit(services.Job{TenantID: "quiet", Body: []byte("{}")}))
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&quietDone) == 5
+	}, 5*time.Second, time.Millisecond)
+	assert.Less(t, atomic.LoadInt32(&noisyDone), int32(30))
+}
+
+func TestSchedulerAcquireHonoursCancellation(t *testing.T) {
+	scheduler := services.NewScheduler(1)
+	require.NoError(t, scheduler.Acquire(context.Background(), "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, scheduler.Acquire(ctx, "b"), context.DeadlineExceeded)
+
+	// The abandoned wait must not swallow the slot
+	scheduler.Release()
+	require.NoError(t, scheduler.Acquire(context.Background(), "b"))
+	scheduler.Release()
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"jatis/internal/models"
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePayloadAgainstSchema(t *testing.T) {
+	var schema models.PayloadSchema
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["order_id", "items"],
+		"properties": {
+			"order_id": {"type": "integer"},
+			"channel": {"type": "string", "enum": ["web", "store"]},
+			"items": {"type": "array", "items": {"type": "object", "required": ["sku"]}}
+		}
+	}`), &schema))
+	require.NoError(t, services.CheckPayloadSchema(&schema))
+
+	cases := []struct {
+		payload string
+		err     string
+	}{
+		{`{"order_id": 7, "channel": "web", "items": [{"sku": "a"}]}`, ""},
+		{`{"order_id": 7, "items": []}`, ""},
+		{`[1, 2]`, "$ must be of type object"},
+		{`{"items": []}`, "$.order_id is required"},
+		{`{"order_id": 7.5, "items": []}`, "$.order_id must be of type integer"},
+		{`{"order_id": 7, "channel": "fax", "items": []}`, "$.channel must be one of the enum values"},
+		{`{"order_id": 7, "items": [{"sku": "a"}, {}]}`, "$.items[1].sku is required"},
+	}
+	for _, tc := range cases {
+		var payload interface{}
+		require.NoError(t, json.Unmarshal([]byte(tc.payload), &payload))
+		err := services.ValidatePayload(&schema, payload)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.payload)
+			continue
+		}
+		assert.ErrorIs(t, err, services.ErrSchemaViolation, tc.payload)
+		assert.ErrorContains(t, err, tc.err, tc.payload)
+	}
+
+	// No schema accepts anything
+	assert.NoError(t, services.ValidatePayload(nil, "anything"))
+
+	unknown := models.PayloadSchema{Type: "object", Properties: map[string]*models.PayloadSchema{"n": {Type: "int"}}}
+	assert.ErrorIs(t, services.CheckPayloadSchema(&unknown), services.ErrInvalidSchema)
+}
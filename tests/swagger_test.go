@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jatis/internal/api"
+	"jatis/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func swaggerStatus(enabled bool) int {
+	cfg := config.Default()
+	cfg.API.Swagger = enabled
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, cfg, nil, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/swagger/index.html", nil)
+	// The Swagger handler matches on the raw request URI, which only the
+	// server sets
+	req.RequestURI = req.URL.RequestURI()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestSwaggerRouteEnabledByDefault(t *testing.T) {
+	assert.True(t, config.Default().API.Swagger)
+	assert.Equal(t, http.StatusOK, swaggerStatus(true))
+}
+
+func TestSwaggerRouteAbsentWhenDisabled(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, swaggerStatus(false))
+}
+
+func TestSwaggerEnabledEnvOverride(t *testing.T) {
+	t.Setenv("SWAGGER_ENABLED", "false")
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.API.Swagger)
+
+	t.Setenv("SWAGGER_ENABLED", "maybe")
+	_, err = config.Load()
+	assert.Error(t, err)
+}
+
+func TestSwaggerDisabledInReleaseMode(t *testing.T) {
+	t.Setenv("GIN_MODE", "release")
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.API.Swagger)
+
+	// The config can still turn it back on
+	t.Setenv("SWAGGER_ENABLED", "true")
+	cfg, err = config.Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.API.Swagger)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledWorkerPoolStaysUnderCeiling(t *testing.T) {
+	const perSecond = 100
+	throughput := services.NewThroughputLimiter(perSecond, 5)
+
+	var done int32
+	pool := services.NewThrottledWorkerPool(context.Background(), 20, throughput, func(ctx context.Context, job services.Job) error {
+		atomic.AddInt32(&done, 1)
+		return nil
+	})
+	defer pool.Stop()
+
+	const total = 90
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		require.NoError(t, pool.Submit(services.Job{TenantID: "tenant", Body: []byte("{}")}))
+	}
+
+	// Midway the pool has kept to the rate plus the burst
+	time.Sleep(300 * time.Millisecond)
+	assert.LessOrEqual(t, atomic.LoadInt32(&done), int32(float64(perSecond)*time.Since(start).Seconds())+5)
+
+	// Throttled jobs wait rather than being dropped
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&done) == total
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), time.Duration(total-5)*time.Second/perSecond)
+}
+
+func TestThroughputLimiterUtilization(t *testing.T) {
+	throughput := services.NewThroughputLimiter(1000, 1000)
+	assert.Zero(t, throughput.Utilization())
+
+	// Seconds are counted on the clock; start at the top of one
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	for i := 0; i < 500; i++ {
+		require.NoError(t, throughput.Wait(context.Background()))
+	}
+	// The current second is reported once it is over
+	require.Eventually(t, func() bool {
+		return throughput.Utilization() > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.InDelta(t, 0.5, throughput.Utilization(), 0.01)
+}
+
+func TestThroughputLimiterWaitStopsWithContext(t *testing.T) {
+	throughput := services.NewThroughputLimiter(1, 1)
+	require.NoError(t, throughput.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, throughput.Wait(ctx), context.DeadlineExceeded)
+}
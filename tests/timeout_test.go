@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jatis/internal/api"
+	"jatis/internal/config"
+	"jatis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutRouter serves the API with a /slow route that waits for delay or
+// for its request to be cancelled, recording why it stopped.
+func timeoutRouter(cfg *config.Config, delay time.Duration, stopped *error) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, cfg, nil, nil)
+	router.GET("/slow", func(c *gin.Context) {
+		select {
+		case <-time.After(delay):
+			c.JSON(http.StatusOK, gin.H{"status": "done"})
+		case <-c.Request.Context().Done():
+			*stopped = c.Request.Context().Err()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed", Message: (*stopped).Error()})
+		}
+	})
+	return router
+}
+
+func TestRequestTimeoutRespondsServiceUnavailable(t *testing.T) {
+	cfg := config.Default()
+	cfg.API.RouteTimeouts = map[string]time.Duration{"/slow": 50 * time.Millisecond}
+
+	var stopped error
+	router := timeoutRouter(cfg, time.Minute, &stopped)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	start := time.Now()
+	router.ServeHTTP(w, req)
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, stopped, context.DeadlineExceeded)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Request timed out", body.Error)
+	assert.Contains(t, body.Message, "50ms")
+
+	// Requests finishing in time are untouched
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/health", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRequestTimeoutLeavesOtherRoutesUntimed(t *testing.T) {
+	cfg := config.Default()
+	cfg.API.RequestTimeout = 20 * time.Millisecond
+
+	var stopped error
+	router := timeoutRouter(cfg, 100*time.Millisecond, &stopped)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, stopped)
+}
+
+func TestRouteTimeoutOverrides(t *testing.T) {
+	cfg := config.Default()
+	cfg.API.RequestTimeout = 20 * time.Millisecond
+	cfg.API.RouteTimeouts = map[string]time.Duration{"/slow": 0}
+
+	var stopped error
+	router := timeoutRouter(cfg, 100*time.Millisecond, &stopped)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, stopped)
+
+	cfg.API.RouteTimeouts = map[string]time.Duration{"/slow": 10 * time.Millisecond}
+	router = timeoutRouter(cfg, time.Minute, &stopped)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/slow", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "10ms")
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddReceivedAtStampsServerTime(t *testing.T) {
+	payload := map[string]interface{}{"order": 42, "received_at": "spoofed"}
+
+	before := time.Now().UTC()
+	transformed, err := services.ApplyTransforms(payload, []string{services.TransformAddReceivedAt})
+	require.NoError(t, err)
+	after := time.Now().UTC()
+
+	stamped := transformed.(map[string]interface{})
+	assert.Equal(t, 42, stamped["order"])
+	receivedAt, err := time.Parse(time.RFC3339Nano, stamped["received_at"].(string))
+	require.NoError(t, err)
+	assert.False(t, receivedAt.Before(before) || receivedAt.After(after), "received_at %s is not the server time", receivedAt)
+
+	// The original payload is left untouched
+	assert.Equal(t, "spoofed", payload["received_at"])
+}
+
+func TestApplyTransformsInOrder(t *testing.T) {
+	payload := map[string]interface{}{
+		"Customer": map[string]interface{}{"EMail": "a@example.com"},
+		"Items":    []interface{}{map[string]interface{}{"SKU": "x"}},
+	}
+
+	transformed, err := services.ApplyTransforms(payload, []string{services.TransformLowercaseKeys, services.TransformAddReceivedAt})
+	require.NoError(t, err)
+
+	result := transformed.(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"email": "a@example.com"}, result["customer"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"sku": "x"}}, result["items"])
+	assert.Contains(t, result, "received_at")
+	assert.NotContains(t, result, "Customer")
+}
+
+func TestCustomTransformer(t *testing.T) {
+	services.RegisterTransformer("test_uppercase_strings", services.TransformerFunc(func(payload interface{}) (interface{}, error) {
+		if s, ok := payload.(string); ok {
+			return strings.ToUpper(s), nil
+		}
+		return payload, nil
+	}))
+	assert.Contains(t, services.Transformers(), "test_uppercase_strings")
+
+	transformed, err := services.ApplyTransforms("hello", []string{"test_uppercase_strings"})
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", transformed)
+
+	_, err = services.ApplyTransforms("hello", []string{"no_such_transform"})
+	assert.ErrorIs(t, err, services.ErrUnknownTransform)
+}
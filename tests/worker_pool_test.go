@@ -0,0 +1,332 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPoolDispatchesHighPriorityFirst(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	pool := services.NewWorkerPool(context.Background(), 0, func(ctx context.Context, job services.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, string(job.Body))
+		return nil
+	})
+	defer pool.Stop()
+
+	// Queue everything before any worker is running
+	jobs := []services.Job{
+		{Priority: 1, Body: []byte("low-1")},
+		{Priority: 9, Body: []byte("high-1")},
+		{Priority: 5, Body: []byte("mid")},
+		{Priority: 1, Body: []byte("low-2")},
+		{Priority: 9, Body: []byte("high-2")},
+		{Priority: 0, Body: []byte("none")},
+	}
+	for _, job := range jobs {
+		require.NoError(t, pool.Submit(job))
+	}
+
+	pool.UpdateWorkers(1)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == len(jobs)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"high-1", "high-2", "mid", "low-1", "low-2", "none"}, order)
+}
+
+func TestWorkerPoolRejectsWhenFull(t *testing.T) {
+	pool := services.NewWorkerPool(context.Background(), 0, func(ctx context.Context, job services.Job) error { return nil })
+	defer pool.Stop()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+	}
+	assert.ErrorIs(t, pool.Submit(services.Job{Body: []byte("{}")}), services.ErrQueueFull)
+}
+
+func TestWorkerPoolScalingKeepsBufferedJobs(t *testing.T) {
+	var processed int32
+	release := make(chan struct{})
+	pool := services.NewWorkerPool(context.Background(), 2, func(ctx context.Context, job services.Job) error {
+		<-release
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+	defer pool.Stop()
+
+	// Two jobs occupy the workers, the rest wait in the queue
+	for i := 0; i < 10; i++ {
+		require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+	}
+
+	pool.UpdateWorkers(5)
+	close(release)
+	pool.UpdateWorkers(1)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&processed) == 10
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWorkerPoolDownscaleDoesNotWaitForBusyWorkers(t *testing.T) {
+	var processed int32
+	proceed := make(chan struct{}, 10)
+	pool := services.NewWorkerPool(context.Background(), 3, func(ctx context.Context, job services.Job) error {
+		<-proceed
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+	defer pool.Stop()
+	defer close(proceed)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+	}
+	require.Eventually(t, func() bool { return pool.Busy() == 3 }, time.Second, 5*time.Millisecond)
+
+	// Every worker is busy, yet the downscale returns straight away
+	resized := make(chan struct{})
+	go func() {
+		pool.UpdateWorkers(1)
+		close(resized)
+	}()
+	select {
+	case <-resized:
+	case <-time.After(time.Second):
+		t.Fatal("UpdateWorkers blocked on busy workers")
+	}
+	assert.Equal(t, int32(1), pool.Workers())
+
+	// The surplus workers exit once their jobs are done
+	for i := 0; i < 3; i++ {
+		proceed <- struct{}{}
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 3 }, time.Second, 5*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+	}
+	require.Eventually(t, func() bool { return pool.Busy() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), pool.Busy())
+}
+
+func TestWorkerPoolReportsRunningWorkers(t *testing.T) {
+	pool := services.NewWorkerPool(context.Background(), 2, func(ctx context.Context, job services.Job) error { return nil })
+	defer pool.Stop()
+
+	assert.Equal(t, 100, pool.Capacity())
+	require.Eventually(t, func() bool { return pool.Running() == 2 }, time.Second, 5*time.Millisecond)
+	pool.UpdateWorkers(4)
+	require.Eventually(t, func() bool { return pool.Running() == 4 }, time.Second, 5*time.Millisecond)
+	pool.UpdateWorkers(1)
+	require.Eventually(t, func() bool { return pool.Running() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), pool.Workers())
+}
+
+func TestWorkerPoolDrainHandsOverQueuedJobs(t *testing.T) {
+	retired := services.NewWorkerPool(context.Background(), 0, func(ctx context.Context, job services.Job) error { return nil })
+	for _, priority := range []uint8{1, 9, 5} {
+		require.NoError(t, retired.Submit(services.Job{Priority: priority, Body: []byte("{}")}))
+	}
+
+	jobs := retired.Drain()
+	require.Len(t, jobs, 3)
+	assert.Equal(t, uint8(9), jobs[0].Priority)
+	assert.Equal(t, uint8(1), jobs[2].Priority)
+
+	var processed int32
+	successor := services.NewWorkerPool(context.Background(), 1, func(ctx context.Context, job services.Job) error {
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+	defer successor.Stop()
+	for _, job := range jobs {
+		require.NoError(t, successor.Submit(job))
+	}
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&processed) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWorkerPoolConcurrentStopsAndDrains(t *testing.T) {
+	pool := services.NewWorkerPool(context.Background(), 0, func(ctx context.Context, job services.Job) error { return nil })
+	for i := 0; i < 5; i++ {
+		require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+	}
+
+	// No call panics, and no queued job is handed out twice
+	var drained int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			atomic.AddInt32(&drained, int32(len(pool.Drain())))
+		}()
+		go func() {
+			defer wg.Done()
+			pool.Stop()
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, atomic.LoadInt32(&drained), int32(5))
+	assert.Empty(t, pool.Drain())
+}
+
+func TestWorkerPoolStopCancelsRunningHandlers(t *testing.T) {
+	started := make(chan struct{})
+	var cancelled int32
+	pool := services.NewWorkerPool(context.Background(), 1, func(ctx context.Context, job services.Job) error {
+		close(started)
+		<-ctx.Done()
+		atomic.StoreInt32(&cancelled, 1)
+		return ctx.Err()
+	})
+	require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("pool did not stop while a handler was running")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+}
+
+func TestWorkerPoolHandlersSeeParentCancellation(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	pool := services.NewWorkerPool(parent, 1, func(ctx context.Context, job services.Job) error {
+		<-ctx.Done()
+		done <- ctx.Err()
+		return ctx.Err()
+	})
+	defer pool.Stop()
+	require.NoError(t, pool.Submit(services.Job{Body: []byte("{}")}))
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("handler did not observe the cancelled parent context")
+	}
+}
+
+func TestWorkerPoolRespectsTenantConcurrencyLimit(t *testing.T) {
+	limits := services.NewConcurrencyLimits()
+	limits.SetLimit("limited", 5)
+
+	var running, peak, done int32
+	pool := services.NewLimitedWorkerPool(context.Background(), 20, limits, func(ctx context.Context, job services.Job) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&done, 1)
+		return nil
+	})
+	defer pool.Stop()
+
+	for i := 0; i < 60; i++ {
+		require.NoError(t, pool.Submit(services.Job{TenantID: "limited", Body: []byte("{}")}))
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&done) == 60
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&peak))
+}
+
+func TestConcurrencyLimitCanBeRaisedWhileWaiting(t *testing.T) {
+	limits := services.NewConcurrencyLimits()
+	limits.SetLimit("tenant", 1)
+
+	release, err := limits.Acquire(context.Background(), "tenant")
+	require.NoError(t, err)
+	defer release()
+
+	acquired := make(chan struct{})
+	go func() {
+		second, err := limits.Acquire(context.Background(), "tenant")
+		if err == nil {
+			second()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second handler ran while the tenant was at its limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	limits.SetLimit("tenant", 2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("raising the limit did not let the waiting handler run")
+	}
+}
+
+func TestTenantSemaphoresThrottleEachTenant(t *testing.T) {
+	sems := services.NewTenantSemaphores(2)
+
+	first, err := sems.Acquire(context.Background(), "tenant")
+	require.NoError(t, err)
+	second, err := sems.Acquire(context.Background(), "tenant")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = sems.Acquire(ctx, "tenant")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "a third create should be throttled")
+
+	other, err := sems.Acquire(context.Background(), "other")
+	require.NoError(t, err, "another tenant should not be throttled")
+	other()
+
+	acquired := make(chan struct{})
+	go func() {
+		third, err := sems.Acquire(context.Background(), "tenant")
+		if err == nil {
+			third()
+		}
+		close(acquired)
+	}()
+	first()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("releasing a permit did not let the waiting create run")
+	}
+	second()
+}
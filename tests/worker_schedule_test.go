@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"jatis/internal/models"
+	"jatis/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledWorkersWindows(t *testing.T) {
+	schedule := []models.WorkerWindow{
+		{Start: "08:00", End: "20:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Workers: 10},
+		{Start: "22:00", End: "06:00", Days: []string{"fri"}, Workers: 1},
+		{Start: "08:00", End: "20:00", Workers: 4},
+	}
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name    string
+		now     time.Time
+		workers int
+		inForce bool
+	}{
+		{"weekday daytime", at(16, 12, 0), 10, true},
+		{"end is exclusive", at(16, 20, 0), 0, false},
+		{"weekend daytime falls through to the every-day window", at(17, 8, 0), 4, true},
+		{"overnight window before midnight", at(16, 23, 30), 1, true},
+		{"overnight window after midnight belongs to the day it started", at(17, 5, 59), 1, true},
+		{"overnight window on a day it does not start", at(16, 3, 0), 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			workers, inForce := services.ScheduledWorkers(schedule, tc.now)
+			assert.Equal(t, tc.inForce, inForce)
+			assert.Equal(t, tc.workers, workers)
+		})
+	}
+
+	// Windows that cannot be parsed are skipped
+	_, inForce := services.ScheduledWorkers([]models.WorkerWindow{{Start: "8am", End: "20:00", Workers: 2}}, at(16, 12, 0))
+	assert.False(t, inForce)
+}